@@ -0,0 +1,31 @@
+package chain
+
+import (
+	"XianfengChain04/wallet"
+)
+
+/**
+ *为地址设置标签，标签为空时清除标签
+ */
+func (chain *BlockChain) SetLabel(address string, label string) error {
+	return chain.Wallet.SetLabel(address, label)
+}
+
+/**
+ *获取使用指定标签的所有地址
+ */
+func (chain *BlockChain) GetAddressesByLabel(label string) []string {
+	return chain.Wallet.GetAddressesByLabel(label)
+}
+
+/**
+ *获取地址的详细信息以及地址当前的余额
+ */
+func (chain *BlockChain) GetAddressInfo(address string) (wallet.AddressInfo, float64, error) {
+	info, err := chain.Wallet.GetAddressInfo(address)
+	if err != nil {
+		return info, 0, err
+	}
+	balance, err := chain.GetBalance(address)
+	return info, balance, err
+}
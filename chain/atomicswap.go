@@ -0,0 +1,262 @@
+package chain
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/transaction"
+	"XianfengChain04/utils"
+	"XianfengChain04/wallet"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const SWAPINITIATELOCKTIME = 48 * 60 * 60    //发起方合约默认的退款等待时间（秒）
+const SWAPPARTICIPATELOCKTIME = 24 * 60 * 60 //参与方合约默认的退款等待时间（秒），需要短于发起方合约
+
+/**
+ *原子交换合约，由发起方或参与方在各自的链上创建
+ */
+type SwapContract struct {
+	Contract   []byte //哈希时间锁合约的赎回脚本
+	Address    string //合约的P2SH地址
+	Secret     []byte //发起方生成的秘密值，参与方创建合约时为空
+	SecretHash []byte //秘密值的sha256哈希
+	LockTime   int64  //合约的退款锁定时间
+}
+
+/**
+ *原子交换合约的审计信息
+ */
+type SwapAudit struct {
+	Address          string  //合约的P2SH地址
+	RecipientAddress string  //凭秘密值领取资金的地址
+	RefundAddress    string  //锁定时间到期后取回资金的地址
+	SecretHash       []byte  //秘密值的sha256哈希
+	LockTime         int64   //合约的退款锁定时间
+	Balance          float64 //合约地址中尚未被花费的资金
+	Secret           []byte  //合约已被领取时，接收方在解锁脚本中公开的秘密值
+}
+
+/**
+ *在当前链上创建原子交换合约并将amount转入合约地址：to凭秘密值领取资金，锁定时间到期后from可以取回资金
+ *secretHash为空时作为发起方生成新的秘密值，否则作为参与方使用对方合约中的秘密值哈希
+ *lockTime为0时使用默认的退款等待时间，参与方合约的锁定时间需要早于发起方合约
+ */
+func (chain *BlockChain) InitiateSwap(from string, to string, amount float64, secretHash []byte, lockTime int64) (*SwapContract, error) {
+	if !chain.Wallet.CheckAddress(from) || !chain.Wallet.CheckAddress(to) {
+		return nil, errors.New("地址不符合规范，请检查后重试")
+	}
+	refundHash, err := swapPubkHash(from)
+	if err != nil {
+		return nil, err
+	}
+	recipientHash, err := swapPubkHash(to)
+	if err != nil {
+		return nil, err
+	}
+	if chain.Wallet.GetKeyPairByAddress(from) == nil {
+		return nil, errors.New("当前钱包未找到" + from + "的秘钥对")
+	}
+	if amount <= 0 {
+		return nil, errors.New("合约金额必须大于0")
+	}
+
+	swap := &SwapContract{SecretHash: secretHash, LockTime: lockTime}
+	if len(secretHash) == 0 {
+		swap.Secret = make([]byte, script.SECRETLEN)
+		if _, err = rand.Read(swap.Secret); err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(swap.Secret)
+		swap.SecretHash = hash[:]
+	}
+	if swap.LockTime == 0 {
+		swap.LockTime = time.Now().Unix() + SWAPPARTICIPATELOCKTIME
+		if swap.Secret != nil {
+			swap.LockTime = time.Now().Unix() + SWAPINITIATELOCKTIME
+		}
+	}
+	swap.Contract, err = script.HTLCScript(script.HTLCContract{
+		SecretHash:    swap.SecretHash,
+		RecipientHash: recipientHash,
+		RefundHash:    refundHash,
+		LockTime:      swap.LockTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	//保存合约的赎回脚本，便于之后查询合约地址的余额
+	swap.Address, err = chain.Wallet.AddRedeemScript(swap.Contract)
+	if err != nil {
+		return nil, err
+	}
+	err = chain.SendTransaction([]string{from}, []string{swap.Address}, []float64{amount}, 0, nil, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	return swap, nil
+}
+
+/**
+ *接收方使用秘密值领取合约中的全部资金，to为空时转入合约中的接收方地址，返回领取交易的哈希
+ */
+func (chain *BlockChain) RedeemSwap(contractHex string, secretHex string, to string) ([32]byte, error) {
+	contractScript, contract, err := parseSwapContract(contractHex)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	secret, err := hex.DecodeString(secretHex)
+	if err != nil || len(secret) != script.SECRETLEN {
+		return [32]byte{}, errors.New("秘密值格式不正确，请检查后重试")
+	}
+	hash := sha256.Sum256(secret)
+	if !bytes.Equal(hash[:], contract.SecretHash) {
+		return [32]byte{}, errors.New("秘密值与合约中的秘密值哈希不一致")
+	}
+	recipient := chain.Wallet.GetAddressByPubkHash(append([]byte{wallet.VERSION}, contract.RecipientHash...))
+	keyPair := chain.Wallet.GetKeyPairByAddress(recipient)
+	if keyPair == nil {
+		return [32]byte{}, errors.New("当前钱包未找到合约接收方" + recipient + "的秘钥对")
+	}
+	if len(to) == 0 {
+		to = recipient
+	}
+	return chain.spendSwap(contractScript, keyPair, to, secret, 0)
+}
+
+/**
+ *合约的锁定时间到期后，退款方取回合约中的全部资金，to为空时转入合约中的退款地址，返回退款交易的哈希
+ */
+func (chain *BlockChain) RefundSwap(contractHex string, to string) ([32]byte, error) {
+	contractScript, contract, err := parseSwapContract(contractHex)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	refund := chain.Wallet.GetAddressByPubkHash(append([]byte{wallet.VERSION}, contract.RefundHash...))
+	keyPair := chain.Wallet.GetKeyPairByAddress(refund)
+	if keyPair == nil {
+		return [32]byte{}, errors.New("当前钱包未找到合约退款方" + refund + "的秘钥对")
+	}
+	if len(to) == 0 {
+		to = refund
+	}
+	return chain.spendSwap(contractScript, keyPair, to, nil, contract.LockTime)
+}
+
+/**
+ *审计原子交换合约：解析合约参数，查询合约地址的余额，并在链上查找接收方领取资金时公开的秘密值
+ */
+func (chain *BlockChain) AuditSwap(contractHex string) (*SwapAudit, error) {
+	contractScript, contract, err := parseSwapContract(contractHex)
+	if err != nil {
+		return nil, err
+	}
+	audit := &SwapAudit{
+		Address:          chain.Wallet.GetAddressByRedeemScript(contractScript),
+		RecipientAddress: chain.Wallet.GetAddressByPubkHash(append([]byte{wallet.VERSION}, contract.RecipientHash...)),
+		RefundAddress:    chain.Wallet.GetAddressByPubkHash(append([]byte{wallet.VERSION}, contract.RefundHash...)),
+		SecretHash:       contract.SecretHash,
+		LockTime:         contract.LockTime,
+	}
+	_, audit.Balance = chain.GetUTXOsWithBalance(audit.Address, nil)
+
+	blocks, err := chain.GetAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			for _, input := range tx.Inputs {
+				if secret := script.ExtractHTLCSecret(input.ScriptSig, contractScript); secret != nil {
+					audit.Secret = secret
+					return audit, nil
+				}
+			}
+		}
+	}
+	return audit, nil
+}
+
+/**
+ *构建并打包花费合约地址全部资金的交易，手续费从转给to的资金中扣除，
+ *secret不为空时走领取分支，否则以lockTime作为交易的锁定时间走退款分支
+ */
+func (chain *BlockChain) spendSwap(contract []byte, signer wallet.Signer, to string, secret []byte, lockTime int64) ([32]byte, error) {
+	if !chain.Wallet.CheckAddress(to) {
+		return [32]byte{}, errors.New("地址不符合规范，请检查后重试")
+	}
+	address := chain.Wallet.GetAddressByRedeemScript(contract)
+	utxos, totalBalance := chain.GetUTXOsWithBalance(address, chain.memPoolTxs())
+	if len(utxos) == 0 {
+		return [32]byte{}, errors.New("合约地址" + address + "中没有可花费的资金")
+	}
+	feeRate, err := chain.resolveFeeRate(0)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	//合约地址的全部资金转给to，交易没有找零输出，不需要预留找零地址
+	newTx, err := fundFixedInputs(feeRate, func(fee float64) (*transaction.Transaction, error) {
+		if totalBalance-fee < transaction.AMOUNTPRECISION {
+			return nil, errors.New("合约地址中的资金不足以支付手续费")
+		}
+		newTx, err := transaction.CreateNewTransactionWithFee(utxos, address, nil, to, totalBalance-fee, fee, address)
+		if err != nil {
+			return nil, err
+		}
+		if lockTime > 0 {
+			if err = newTx.SetLockTime(lockTime); err != nil {
+				return nil, err
+			}
+		}
+		if err = newTx.SignalReplacement(); err != nil {
+			return nil, err
+		}
+		for index := range newTx.Inputs {
+			if err = newTx.SetRedeemScript(index, contract); err != nil {
+				return nil, err
+			}
+			err = newTx.SignHTLCInput(index, signer, utxos, secret, transaction.SIGHASH_ALL)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return newTx, nil
+	})
+	if err != nil {
+		return [32]byte{}, err
+	}
+	err = chain.submitWalletTransactions([]transaction.Transaction{*newTx}, false)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return newTx.TxHash, nil
+}
+
+/**
+ *解析十六进制编码的原子交换合约
+ */
+func parseSwapContract(contractHex string) ([]byte, script.HTLCContract, error) {
+	contractScript, err := hex.DecodeString(contractHex)
+	if err != nil {
+		return nil, script.HTLCContract{}, errors.New("合约不是合法的十六进制编码")
+	}
+	contract, ok := script.ExtractHTLC(contractScript)
+	if !ok {
+		return nil, script.HTLCContract{}, errors.New("不是有效的原子交换合约")
+	}
+	return contractScript, contract, nil
+}
+
+/**
+ *获取普通地址的公钥哈希，原子交换合约的双方只支持普通地址
+ */
+func swapPubkHash(address string) ([]byte, error) {
+	reAddr := utils.Decode(address)
+	if len(reAddr) != 1+script.PUBKHASHLEN+4 || reAddr[0] != wallet.VERSION {
+		return nil, errors.New("原子交换只支持普通地址：" + address)
+	}
+	return reAddr[1 : 1+script.PUBKHASHLEN], nil
+}
@@ -3,12 +3,13 @@ package chain
 import (
 	"XianfengChain04/consensus"
 	"XianfengChain04/transaction"
+	"XianfengChain04/utils"
 	"bytes"
-	"encoding/gob"
+	"errors"
 	"time"
 )
 
-const VERSION = 0x00
+const VERSION = 0x01 //区块序列化格式的版本号
 
 /**
  *区块的结构体定义
@@ -62,14 +63,30 @@ func (block Block) GetTransaction() []transaction.Transaction {
 //}
 
 /**
- *区块的序列化方法
+ *区块的序列化方法，格式如下（整数均为大端序）：
+ *version(8) + height(8) + prevhash(32) + hash(32) + timestamp(8) + nonce(8)
+ * + 交易个数(4) + 每笔交易（带4字节长度前缀的交易序列化数据）
  */
 func (block *Block) Serialize() ([]byte, error) {
-	//缓冲区
+	if block.Version != VERSION {
+		return nil, errors.New("不支持的区块版本")
+	}
 	buff := new(bytes.Buffer)
-	encoder := gob.NewEncoder(buff)
-	err := encoder.Encode(&block)
-	return buff.Bytes(), err
+	utils.WriteInt64(buff, block.Version)
+	utils.WriteInt64(buff, block.Height)
+	buff.Write(block.PrevHash[:])
+	buff.Write(block.Hash[:])
+	utils.WriteInt64(buff, block.TimeStamp)
+	utils.WriteInt64(buff, block.Nonce)
+	utils.WriteUint32(buff, uint32(len(block.Transactions)))
+	for _, tx := range block.Transactions {
+		txBytes, err := tx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		utils.WriteVarBytes(buff, txBytes)
+	}
+	return buff.Bytes(), nil
 }
 
 /**
@@ -77,9 +94,49 @@ func (block *Block) Serialize() ([]byte, error) {
  */
 func Deserialize(data []byte) (Block, error) {
 	var block Block
-	decoder := gob.NewDecoder(bytes.NewReader(data))
-	err := decoder.Decode(&block)
-	return block, err
+	var err error
+	reader := bytes.NewReader(data)
+	if block.Version, err = utils.ReadInt64(reader); err != nil {
+		return block, err
+	}
+	if block.Version != VERSION {
+		return block, errors.New("不支持的区块版本")
+	}
+	if block.Height, err = utils.ReadInt64(reader); err != nil {
+		return block, err
+	}
+	if block.PrevHash, err = utils.ReadHash(reader); err != nil {
+		return block, err
+	}
+	if block.Hash, err = utils.ReadHash(reader); err != nil {
+		return block, err
+	}
+	if block.TimeStamp, err = utils.ReadInt64(reader); err != nil {
+		return block, err
+	}
+	if block.Nonce, err = utils.ReadInt64(reader); err != nil {
+		return block, err
+	}
+	txNum, err := utils.ReadCount(reader, 4)
+	if err != nil {
+		return block, err
+	}
+	block.Transactions = make([]transaction.Transaction, 0, txNum)
+	for i := 0; i < txNum; i++ {
+		txBytes, err := utils.ReadVarBytes(reader)
+		if err != nil {
+			return block, err
+		}
+		tx, err := transaction.DeserializeTransaction(txBytes)
+		if err != nil {
+			return block, err
+		}
+		block.Transactions = append(block.Transactions, *tx)
+	}
+	if reader.Len() != 0 {
+		return block, errors.New("反序列化失败，区块数据末尾存在多余的字节")
+	}
+	return block, nil
 }
 
 /**
@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"XianfengChain04/transaction"
+	"reflect"
+	"testing"
+)
+
+const testAddress = "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"
+
+/**
+ *构建一个包含coinbase交易和普通交易的测试区块
+ */
+func newTestBlock(t *testing.T) Block {
+	coinbase, err := transaction.CreateCoinBase(testAddress, 3, []byte("extra"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := transaction.NewTxInput([32]byte{1}, 0, []byte{0x02, 0x01})
+	input.Sig = []byte{0x30, 0x01}
+	tx, err := transaction.NewRawTransaction([]transaction.TxInput{input},
+		[]transaction.TxOutPut{transaction.LockMoney2PubkHash(2, testAddress)}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Block{
+		Height:       3,
+		Version:      VERSION,
+		PrevHash:     [32]byte{7},
+		Hash:         [32]byte{8},
+		TimeStamp:    1700000000,
+		Nonce:        42,
+		Transactions: []transaction.Transaction{*coinbase, *tx},
+	}
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	block := newTestBlock(t)
+	data, err := block.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(block, decoded) {
+		t.Fatalf("区块反序列化结果不一致\n%+v\n%+v", block, decoded)
+	}
+}
+
+func TestDeserializeBlockRejects(t *testing.T) {
+	block := newTestBlock(t)
+	data, err := block.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for length := 0; length < len(data); length++ {
+		if _, err := Deserialize(data[:length]); err == nil {
+			t.Fatalf("截断到%d字节的区块数据应当被拒绝", length)
+		}
+	}
+	if _, err := Deserialize(append(data, 0x00)); err == nil {
+		t.Fatal("末尾存在多余字节的区块数据应当被拒绝")
+	}
+
+	unknown := append([]byte{}, data...)
+	unknown[7] = VERSION + 1
+	if _, err := Deserialize(unknown); err == nil {
+		t.Fatal("未知版本的区块数据应当被拒绝")
+	}
+	block.Version = VERSION + 1
+	if _, err := block.Serialize(); err == nil {
+		t.Fatal("未知版本的区块不应当被序列化")
+	}
+}
@@ -1,15 +1,20 @@
 package chain
 
 import (
+	"XianfengChain04/mempool"
+	"XianfengChain04/script"
 	"XianfengChain04/transaction"
+	"XianfengChain04/utils"
 	"XianfengChain04/utxoset"
 	"XianfengChain04/wallet"
+	"XianfengChain04/wallettx"
 	"bytes"
-	"crypto/ecdsa"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
 	"math/big"
+	"time"
 )
 
 const BLOCKS = "blocks"//桶名
@@ -25,28 +30,44 @@ type BlockChain struct {
 	IteratorBlockHash  [32]byte//表示当前迭代到了哪个区块，该变量用于记录迭代到的区块哈希
     Wallet             wallet.Wallet//引入wallet字段作为 blockchain的属性
     UTXOSet            utxoset.UTXOSet//utxoset是用来关于utxo集合的操作
+    MemPool            mempool.MemPool//交易池，保存尚未被打包的交易
+    WalletTxs          wallettx.TxStore//钱包交易记录，保存与钱包地址相关的交易
 }
 
 func CreateChain(db *bolt.DB) (*BlockChain, error) {
+	//创建或者加载wallet结构体对象
+	wallet, err := wallet.LoadAddrAndKeyPairsFromDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	//旧版本使用gob编码保存的区块先迁移到新的序列化格式
+	if err = migrateLegacyBlocks(db, wallet); err != nil {
+		return nil, err
+	}
+
 	var lastBlock Block
-	db.Update(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BLOCKS))
 		if bucket == nil {
-			bucket, _ = tx.CreateBucket([]byte(BLOCKS))
+			bucket, err = tx.CreateBucket([]byte(BLOCKS))
+			if err != nil {
+				return err
+			}
 		}
 		lastHash := bucket.Get([]byte(LASTHASH))
 		if len(lastHash) <=  0 {
 			return nil
 		}
 		lastBlockBytes := bucket.Get(lastHash)
-		lastBlock, _ = Deserialize(lastBlockBytes)
+		lastBlock, err = Deserialize(lastBlockBytes)
+		if err != nil {
+			return errors.New("读取最新区块时遇到错误：" + err.Error())
+		}
 		return nil
 	})
-
-	//创建或者加载wallet结构体对象
-    wallet, err := wallet.LoadAddrAndKeyPairsFromDB(db)
-    if err != nil {
-    	return nil, err
+	if err != nil {
+		return nil, err
 	}
 
 	//创建或者加载utxoset结构体对象
@@ -58,6 +79,8 @@ func CreateChain(db *bolt.DB) (*BlockChain, error) {
 		IteratorBlockHash: lastBlock.Hash,
 		Wallet:            *wallet,
 		UTXOSet:           set,
+		MemPool:           mempool.NewMemPool(db),
+		WalletTxs:         wallettx.NewTxStore(db, wallet.Name),
 	}
 	return &blockChain, nil
 }
@@ -71,8 +94,12 @@ func (chain *BlockChain) CreateCoinBase(addr string) error {
     if !isAddrValid{
     	return errors.New("抱歉，地址不符合规范，请检查后重试")
 	}
-	//2，创建一笔coinbase交易
-	coinbase, err := transaction.CreateCoinBase(addr)
+	//2，创建一笔coinbase交易，创世区块的高度为0，使用当前纳秒时间作为extra-nonce
+	extraNonce, err := utils.Int2Byte(time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	coinbase, err := transaction.CreateCoinBase(addr, 0, extraNonce)
 	if err != nil {
 		return err
 	}
@@ -89,6 +116,8 @@ func (chain *BlockChain) CreateCoinBase(addr string) error {
 	utxos := make([]transaction.UTXO, 0)
 	for index, output := range coinbase.Outputs {
 		utxo := transaction.NewUTXO(coinbase.TxHash, index, output)
+		utxo.Height = chain.LastBlock.Height
+		utxo.Time = chain.LastBlock.TimeStamp
 		utxos = append(utxos, utxo)
 	}
 
@@ -96,7 +125,8 @@ func (chain *BlockChain) CreateCoinBase(addr string) error {
 	if !success {
 		return err
 	}
-	return err
+	//6，记录钱包在创世区块中获得的奖励
+	return chain.recordWalletTxs([]transaction.Transaction{*coinbase}, [][]transaction.UTXO{nil})
 }
 
 /**
@@ -344,11 +374,9 @@ func (chain BlockChain) GetUTXOsWithBalance(addr string, txs []transaction.Trans
 	memSpends := make([]transaction.TxInput, 0)
 	memInComes := make([]transaction.UTXO, 0)
 	for _, tx := range txs {
-		//a，遍历交易输入，把花的钱记录下来
-		for _, input := range tx.Inputs {
-			if input.VertifyInputWithAddress(addr) {
-				memSpends = append(memSpends, input)
-			}
+		//a，遍历交易输入，把花的钱记录下来，交易输入通过txid和vout唯一确定所花费的utxo
+		if !tx.IsCoinbase() {
+			memSpends = append(memSpends, tx.Inputs...)
 		}
 		//b，遍历交易输出，把收入的钱记录下来
 		for outIndex, output := range tx.Outputs {
@@ -359,9 +387,10 @@ func (chain BlockChain) GetUTXOsWithBalance(addr string, txs []transaction.Trans
 		}
 	}
 
+	//把内存中的收入也加入到，内存中的收入也可能已经被后面的交易花掉
 	utxos := make([]transaction.UTXO, 0)
 	var isUTXOSpend bool
-	for _, utxo := range dbUtxos {
+	for _, utxo := range append(dbUtxos, memInComes...) {
 		isUTXOSpend = false
 		for _, spend := range memSpends {
 			if utxo.IsUTXOSpend(spend) {
@@ -372,12 +401,9 @@ func (chain BlockChain) GetUTXOsWithBalance(addr string, txs []transaction.Trans
 			utxos = append(utxos, utxo)
 		}
 	}
-	//把内存中的收入也加入到
-	utxos = append(utxos, memInComes...)
 
 	var totaBalance float64
 	for _, utxo := range utxos{
-		fmt.Println(utxo)
 		totaBalance += utxo.Value
 	}
 
@@ -385,10 +411,11 @@ func (chain BlockChain) GetUTXOsWithBalance(addr string, txs []transaction.Trans
 }
 
 /**
- *定义区块链的发送交易的功能
+ *定义区块链的发送交易的功能，lockTime为交易的锁定时间，0表示不锁定
+ *selector为选币策略，为空时使用默认策略，feeRate为手续费率（每字节），为0时使用估算的手续费率
+ *交易声明允许替换并提交到交易池，memPoolOnly为true时交易留在交易池中，否则随后打包新区块
  */
-func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts []float64) (error) {
-	var err error
+func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts []float64, lockTime int64, selector transaction.CoinSelector, feeRate float64, memPoolOnly bool) (error) {
 	//对所有的from和to进行合法性检查
 	for i := 0; i < len(froms); i ++ {
 		isFromValid := chain.Wallet.CheckAddress(froms[i])
@@ -398,59 +425,183 @@ func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts [
 		}
 	}
 
+	if selector == nil {
+		selector = transaction.DefaultCoinSelector()
+	}
+	feeRate, err := chain.resolveFeeRate(feeRate)
+	if err != nil {
+		return err
+	}
+
 	newTxs := make([]transaction.Transaction, 0)
+	//交易池中未确认的交易已经花费的utxo不能再次使用
+	memTxs := chain.memPoolTxs()
 	//遍历
 	for from_index, from := range froms {
-		utxos, totaBalance := chain.GetUTXOsWithBalance(from,newTxs)
+		utxos, totaBalance := chain.GetUTXOsWithBalance(from, append(memTxs, newTxs...))
 		if totaBalance < amounts[from_index] {
 			return errors.New(from + "余额不足，赶紧去搬砖挣钱")
 		}
-		totaBalance = 0
-		var utxoNum int
-		for index, utxo := range utxos {
-			totaBalance += utxo.Value
-			if totaBalance > amounts[from_index] {
-				utxoNum = index
-				break
-			}
-		}
-
-		//获取from的原始公钥
-		keyPair := chain.Wallet.GetKeyPairByAddress(from)
-		if keyPair == nil {
-			err = errors.New("交易失败，请重试")
-			break
+		pubk, err := chain.getInputPubKey(from)
+		if err != nil {
+			return err
 		}
-		if len(keyPair.Pub) == 0 {
-			err = errors.New("构建交易出现错误，请重试")
-			break
+		//每笔交易的找零转入一个新的找零地址，避免付款地址被重复使用
+		change, changeKey, err := chain.reserveChangeAddress(from)
+		if err != nil {
+			return err
 		}
-		//可花费的钱总额比要花费的钱数额大，才构建交易
-		newTx, err := transaction.CreateNewTransaction(
-			utxos[:utxoNum +1],
-			from,
-			keyPair.Pub,
-		    tos[from_index],
-			amounts[from_index])
+		//使用选币策略选出本次交易要花费的utxo，并从找零中扣除手续费
+		newTx, _, err := chain.fundTransaction(utxos, amounts[from_index], feeRate, selector,
+			func(selected []transaction.UTXO, fee float64) (*transaction.Transaction, error) {
+				newTx, err := transaction.CreateNewTransactionWithFee(
+					selected,
+					from,
+					pubk,
+					tos[from_index],
+					amounts[from_index],
+					fee,
+					change)
+				if err != nil {
+					return nil, err
+				}
+				return newTx, chain.signTransaction(newTx, from, selected, lockTime)
+			})
 		if err != nil {
 			return err
 		}
-		//对构建的交易newTx进行签名
-        err = newTx.SignTx(keyPair.Priv, utxos[:utxoNum + 1])
-        if err != nil {
-        	return err
+		if err = chain.keepChangeAddress(newTx, change, changeKey); err != nil {
+			return err
 		}
         //把经过签名以后的交易对象存入到内存中交易的切片中
 		newTxs = append(newTxs, *newTx)
 	}
+	return chain.submitWalletTransactions(newTxs, memPoolOnly)
+}
+
+/**
+ *为付款地址预留一个新的找零地址，找零地址与付款地址使用相同的曲线；
+ *MuSig、多重签名和P2SH付款地址没有对应的单一秘钥，找零转回付款地址本身，返回的秘钥为nil
+ */
+func (chain *BlockChain) reserveChangeAddress(from string) (string, *wallet.KeyPair, error) {
+	keyPair := chain.Wallet.GetKeyPairByAddress(from)
+	if keyPair == nil {
+		return from, nil, nil
+	}
+	curve, err := wallet.GetCurve(keyPair.KeyType)
+	if err != nil {
+		return "", nil, err
+	}
+	return chain.Wallet.ReserveChangeAddress(curve)
+}
+
+/**
+ *交易中存在转入预留找零地址的输出时，把该找零地址保存到钱包中，找零转回付款地址（秘钥为nil）时无需保存
+ */
+func (chain *BlockChain) keepChangeAddress(tx *transaction.Transaction, change string, changeKey *wallet.KeyPair) error {
+	if changeKey == nil {
+		return nil
+	}
+	for _, output := range tx.Outputs {
+		if len(output.PubkHash) != 0 && chain.Wallet.GetAddressByPubkHash(output.PubkHash) == change {
+			return chain.Wallet.KeepChangeAddress(change, changeKey)
+		}
+	}
+	return nil
+}
+
+/**
+ *获取花费from的资金时交易输入所携带的公钥，多重签名和P2SH的交易输入不携带公钥，公钥已包含在锁定脚本或赎回脚本中
+ */
+func (chain *BlockChain) getInputPubKey(from string) ([]byte, error) {
+	if _, isP2SH := chain.Wallet.GetRedeemScript(from); isP2SH || wallet.IsMultiSigAddress(from) {
+		return nil, nil
+	}
+	signers, err := chain.Wallet.GetSignersByAddress(from)
+	if err != nil {
+		return nil, err
+	}
+	pubk := signers[0].PublicKey()
+	if len(pubk) == 0 {
+		return nil, errors.New("构建交易出现错误，请重试")
+	}
+	return pubk, nil
+}
 
+/**
+ *使用本地钱包对花费from资金的交易进行签名，lockTime为交易的锁定时间，0表示不锁定
+ */
+func (chain *BlockChain) signTransaction(newTx *transaction.Transaction, from string, utxos []transaction.UTXO, lockTime int64) error {
+	//加密钱包被锁定时无法使用私钥签名
+	if err := chain.Wallet.CheckUnlocked(); err != nil {
+		return err
+	}
+	//获取from对应的签名者，普通地址为其秘钥对，MuSig聚合地址为所有参与方的秘钥对，
+	//多重签名地址和P2SH地址为本地持有的所有参与方秘钥对
+	signers, err := chain.Wallet.GetSignersByAddress(from)
+	if err != nil {
+		return err
+	}
+	if lockTime > 0 {
+		if err = newTx.SetLockTime(lockTime); err != nil {
+			return err
+		}
+	}
+	//钱包发送的交易允许在确认之前提高手续费
+	if err = newTx.SignalReplacement(); err != nil {
+		return err
+	}
+	redeemScript, isP2SH := chain.Wallet.GetRedeemScript(from)
+	isMultiSig := wallet.IsMultiSigAddress(from) || (isP2SH && script.IsMultiSig(script.StripTimeLock(redeemScript)))
+	//花费P2SH输出时，先为每个交易输入设置赎回脚本
+	if isP2SH {
+		//赎回脚本带有时间锁时，交易的锁定时间或输入的sequence需要满足该时间锁
+		if scriptLockTime, relative, _, ok := script.ExtractTimeLock(redeemScript); ok {
+			if err = chain.applyScriptTimeLock(newTx, scriptLockTime, relative); err != nil {
+				return err
+			}
+		}
+		for index := range newTx.Inputs {
+			if err = newTx.SetRedeemScript(index, redeemScript); err != nil {
+				return err
+			}
+		}
+	}
+	//对构建的交易newTx进行签名，多重签名地址由每个本地参与方依次签名
+	for _, signer := range signers {
+		err = newTx.SignTx(signer, utxos)
+		if err != nil {
+			return err
+		}
+	}
+	if isMultiSig {
+		count, required, err := newTx.CountMultiSigs(0, utxos)
+		if err != nil {
+			return err
+		}
+		if count < required {
+			return fmt.Errorf("多重签名地址%s需要%d个签名，本地钱包只能提供%d个", from, required, count)
+		}
+	}
+	return nil
+}
+
+/**
+ *把用户构建的交易与coinbase交易一起验证并打包进新区块，然后更新utxo集合
+ */
+func (chain *BlockChain) packTransactions(newTxs []transaction.Transaction) error {
 	//构建一个coinbase交易，存放到newTxs的第0个位置上，作为奖励的coinbase交易
 	miner := chain.GetCoinbase()
 	if len(miner) == 0 {
 		return errors.New("还未设置coinbase地址")
 	}
 
-	coinbase, err  := transaction.CreateCoinBase(miner)
+	//coinbase交易记录新区块的高度，并使用当前纳秒时间作为extra-nonce
+	extraNonce, err := utils.Int2Byte(time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	coinbase, err  := transaction.CreateCoinBase(miner, chain.LastBlock.Height + 1, extraNonce)
     if err != nil {
     	return err
 	}
@@ -461,17 +612,50 @@ func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts [
 
 	//对交易进行签名验证，只有通过签名验证，才能将交易打包并生成新区块
 	//此处签名验证的逻辑和存储交易到新区快的逻辑理论上应该由其他节点完成
-	for _, tx := range sumTxs {
+	//区块中所有的schnorr签名加入到批量验证器中，在所有交易验证完成后统一进行批量验证
+	//交易的时间锁以新区块的高度和上一个区块的时间戳为基准进行检查
+	//同一个utxo在区块中只能被花费一次
+	batch := wallet.NewSchnorrBatch()
+	nextHeight := chain.LastBlock.Height + 1
+	spentOutpoints := make(map[string]bool)
+	spentUtxos := make([][]transaction.UTXO, len(sumTxs))
+	var fees float64
+	for txIndex, tx := range sumTxs {
 		if tx.IsCoinbase() {//判断当前交易，如果是coinbase交易，直接跳过
 			continue
 		}
+		if !tx.IsFinal(nextHeight, chain.LastBlock.TimeStamp) {
+			return errors.New("交易尚未到达锁定时间，不能被打包")
+		}
 
         //遍历构建的每一个交易，对每一笔依次进行签名验证
 		//1，根据交易首先查询到该笔交易使用了哪些utxo
 		spendUtxos := chain.FindSpentUTXOsByTx(tx, sumTxs)
+		if len(spendUtxos) != len(tx.Inputs) {
+			return errors.New("交易所花费的utxo不存在或已被花费")
+		}
+		spentUtxos[txIndex] = spendUtxos
+		for _, input := range tx.Inputs {
+			outpoint := fmt.Sprintf("%x:%d", input.TxId, input.Vout)
+			if spentOutpoints[outpoint] {
+				return errors.New("区块中的交易重复花费了同一个utxo")
+			}
+			spentOutpoints[outpoint] = true
+		}
+		fee, err := tx.Fee(spendUtxos)
+		if err != nil {
+			return err
+		}
+		if !transaction.IsValidAmount(fee) {
+			return errors.New("交易的手续费金额无效")
+		}
+		fees += fee
+		err = tx.CheckSequenceLocks(spendUtxos, nextHeight, chain.LastBlock.TimeStamp)
+		if err != nil {
+			return err
+		}
 		//2，调用交易的签名验证方法
-        isVerify, err := tx.VerifyTx(spendUtxos)//在调用verifyTx方法时，需要将交易所消费的具体的utxo
-        fmt.Println("交易签名验证结果：", isVerify)
+        isVerify, err := tx.VerifyTxWithBatch(spendUtxos, batch)//在调用verifyTx方法时，需要将交易所消费的具体的utxo
         if err != nil {
         	return err
 		}
@@ -481,6 +665,17 @@ func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts [
 			return errors.New("交易签名验证失败，请重试！")
 		}
 	}
+	err = batch.Verify()
+	if err != nil {
+		return err
+	}
+	//矿工通过coinbase交易领取区块中所有交易的手续费
+	if !transaction.IsValidAmount(fees) {
+		return errors.New("区块的手续费金额无效")
+	}
+	if err = sumTxs[0].AddCoinbaseFees(fees); err != nil {
+		return err
+	}
 
 	//sumTxs是要存入到区块中的所有交易
 	     //sumTxs：coinbase + 用户构建的
@@ -493,7 +688,13 @@ func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts [
 	utxoSet := make(map[string][]transaction.UTXO)
 	for txIndex, tx := range sumTxs {
 		for index, output := range tx.Outputs {
+			//携带数据的输出无法被花费，不保存到utxo集合中
+			if output.IsUnspendable() {
+				continue
+			}
 			utxo := transaction.NewUTXO(tx.TxHash, index, output)
+			utxo.Height = chain.LastBlock.Height
+			utxo.Time = chain.LastBlock.TimeStamp
 
 			isSpent := false
 			//判断是否交易输出是否被后面的某个交易所消费
@@ -532,11 +733,20 @@ func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts [
 	//把已经消费了的utxo从utxoSet中删除
 	spendRecords := make(map[string][]utxoset.SpendRecord)
 	for _, tx := range sumTxs {
+		if tx.IsCoinbase() {//coinbase交易的输入不消费任何utxo
+			continue
+		}
 		for _, input :=  range tx.Inputs {
 			//用于记录一笔消费
 			record := utxoset.NewSpendRecord(input.TxId, input.Vout)
-			//先获取某个地址的消费统计结
-			address := chain.Wallet.GetAddressByPubk(input.PubK)
+			//通过utxo的地址索引获取该笔消费所属的地址，未找到说明该utxo在本区块中产生并被花费
+			address, err := chain.UTXOSet.GetAddressBySpendRecord(record)
+			if err != nil {
+				return err
+			}
+			if len(address) == 0 {
+				continue
+			}
 			records := spendRecords[address]//可能有数据可能没数据
 			if len(records) == 0 {//说明map中还未统计到该地址的消费
 				records = make([]utxoset.SpendRecord, 0)
@@ -558,7 +768,53 @@ func (chain *BlockChain) SendTransaction(froms []string, tos []string, amounts [
 			return errors.New("删除utxo数据记录出现错误")
 		}
 	}
-	return err
+	//记录区块中与钱包相关的交易
+	if err = chain.recordWalletTxs(sumTxs, spentUtxos); err != nil {
+		return err
+	}
+	//从交易池中移除已被打包以及与区块中的交易冲突的交易
+	return chain.removeMinedFromMemPool(sumTxs)
+}
+
+/**
+ *根据赎回脚本中的时间锁设置交易：绝对时间锁设置交易的锁定时间，相对时间锁设置每个输入的sequence
+ */
+func (chain *BlockChain) applyScriptTimeLock(tx *transaction.Transaction, lockTime int64, relative bool) error {
+	if relative {
+		for index := range tx.Inputs {
+			if err := tx.SetSequence(index, uint32(lockTime)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tx.LockedTime != 0 && (tx.LockedTime < script.LOCKTIMETHRESHOLD) != (lockTime < script.LOCKTIMETHRESHOLD) {
+		return errors.New("交易的锁定时间与赎回脚本中时间锁的类型不一致")
+	}
+	if tx.LockedTime >= lockTime {
+		return nil
+	}
+	return tx.SetLockTime(lockTime)
+}
+
+/**
+ *使用某个普通地址生成一个带时间锁的P2SH地址，lockTime为区块高度或unix时间戳，
+ *relativeBlocks大于0时生成相对时间锁地址，资金被确认relativeBlocks个区块以后才能花费
+ */
+func (chain *BlockChain) CreateTimeLockAddress(address string, lockTime int64, relativeBlocks int64) (string, error) {
+	if !chain.Wallet.CheckAddress(address) {
+		return "", errors.New("地址不符合规范，请检查后重试")
+	}
+	if (lockTime > 0) == (relativeBlocks > 0) {
+		return "", errors.New("绝对时间锁和相对时间锁必须且只能设置一个")
+	}
+	if relativeBlocks > 0 {
+		if relativeBlocks > script.SEQUENCEMASK {
+			return "", errors.New("相对时间锁的区块个数超出范围")
+		}
+		return chain.Wallet.NewTimeLockAddress(address, relativeBlocks, true)
+	}
+	return chain.Wallet.NewTimeLockAddress(address, lockTime, false)
 }
 
 /**
@@ -568,6 +824,85 @@ func (chain *BlockChain) GetNewAddress() (string, error) {
 	return chain.Wallet.NewAddress()
 }
 
+/**
+ *使用指定名称的曲线生成地址，支持p256和secp256k1
+ */
+func (chain *BlockChain) GetNewAddressWithCurve(curveName string) (string, error) {
+	curve, err := wallet.GetCurveByName(curveName)
+	if err != nil {
+		return "", err
+	}
+	return chain.Wallet.NewAddressWithCurve(curve)
+}
+
+/**
+ *使用钱包中的schnorr地址和其他参与方的十六进制schnorr公钥生成一个MuSig聚合地址
+ */
+func (chain *BlockChain) CreateMuSigAddress(addresses []string, pubkeys []string) (string, error) {
+	for _, address := range addresses {
+		if !chain.Wallet.CheckAddress(address) {
+			return "", errors.New("地址不符合规范，请检查后重试")
+		}
+	}
+	pubks := make([][]byte, 0, len(pubkeys))
+	for _, key := range pubkeys {
+		pubk, err := hex.DecodeString(key)
+		if err != nil {
+			return "", errors.New("公钥" + key + "不是合法的十六进制编码")
+		}
+		pubks = append(pubks, pubk)
+	}
+	return chain.Wallet.NewMuSigAddress(addresses, pubks)
+}
+
+/**
+ *使用多个公钥生成一个M-of-N的多重签名地址，公钥为十六进制编码，也可以直接使用钱包中的地址代替其公钥
+ *p2sh为true时生成P2SH包装的多重签名地址，赎回脚本保存在钱包中
+ */
+func (chain *BlockChain) CreateMultiSigAddress(required int, keys []string, p2sh bool) (string, error) {
+	pubks := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		if keyPair := chain.Wallet.GetKeyPairByAddress(key); keyPair != nil {
+			pubks = append(pubks, keyPair.Pub)
+			continue
+		}
+		pubk, err := hex.DecodeString(key)
+		if err != nil {
+			return "", errors.New("公钥" + key + "不是合法的十六进制编码，且钱包中不存在该地址")
+		}
+		pubks = append(pubks, pubk)
+	}
+	if p2sh {
+		return chain.Wallet.NewP2SHMultiSigAddress(required, pubks)
+	}
+	return chain.Wallet.NewMultiSigAddress(required, pubks)
+}
+
+/**
+ *导入一个十六进制编码的赎回脚本，返回对应的P2SH地址
+ */
+func (chain *BlockChain) AddRedeemScript(redeemHex string) (string, error) {
+	redeemScript, err := hex.DecodeString(redeemHex)
+	if err != nil {
+		return "", errors.New("赎回脚本不是合法的十六进制编码")
+	}
+	return chain.Wallet.AddRedeemScript(redeemScript)
+}
+
+/**
+ *获取钱包中某个地址的原始公钥，用于与其他钱包共同创建多重签名地址
+ */
+func (chain *BlockChain) GetPubKey(address string) ([]byte, error) {
+	if !chain.Wallet.CheckAddress(address) {
+		return nil, errors.New("地址不符合规范，请检查后重试")
+	}
+	keyPair := chain.Wallet.GetKeyPairByAddress(address)
+	if keyPair == nil {
+		return nil, errors.New("当前钱包未找到对应地址的秘钥对")
+	}
+	return keyPair.Pub, nil
+}
+
 /**
  *获取钱包中的地址列表
  */
@@ -586,23 +921,27 @@ func (chain *BlockChain) GetAddressList() ([]string, error) {
 /**
  *该方法用于导出某个特定地址的私钥
  */
-func (chain *BlockChain) DumpPrivkey(addr string) (*ecdsa.PrivateKey, error) {
+func (chain *BlockChain) DumpPrivkey(addr string) (string, error) {
     //1，地址规范性检查
 	isAddrValid := chain.Wallet.CheckAddress(addr)
 	if !isAddrValid {
-		return nil, errors.New("地址不符合规范，请重试")
+		return "", errors.New("地址不符合规范，请重试")
 	}
 	//2，钱包为空
 	if chain.Wallet.Address == nil {
-    	return nil, errors.New("当前钱包为找到对应地址的私钥")
+    	return "", errors.New("当前钱包为找到对应地址的私钥")
 	}
 	//3，到wallet中找addr的对应的keypair
 	keyPair := chain.Wallet.Address[addr]
 	if keyPair == nil {
-		return nil, errors.New("当前钱包未找到对应的地址的私钥")
+		return "", errors.New("当前钱包未找到对应的地址的私钥")
+	}
+	//4，加密钱包被锁定时无法导出私钥
+	if err := chain.Wallet.CheckUnlocked(); err != nil {
+		return "", err
 	}
-	//4，找到了具体结果，将私钥返回
-	return keyPair.Priv, nil
+	//5，找到了具体结果，将私钥编码为WIF格式返回
+	return wallet.EncodeWIF(keyPair)
 }
 
 /**
@@ -628,18 +967,28 @@ func (chain BlockChain) FindSpentUTXOsByTx(tran transaction.Transaction, memTxs
 		return spendUTXOs
 	}
 
-	spendRecords := make([]utxoset.SpendRecord, 0)
+	//按照utxo所属的地址对消费记录进行分组，每个地址的utxo分别到utxoset中查询
+	spendRecords := make(map[string][]utxoset.SpendRecord)
 	for _, input := range tran.Inputs {
 		//遍历到的每一个input都代表一个消费，即都代表一张钱
 		record := utxoset.NewSpendRecord(input.TxId, input.Vout)
-		spendRecords = append(spendRecords, record)
+		address, err := chain.UTXOSet.GetAddressBySpendRecord(record)
+		if err != nil {
+			return nil
+		}
+		if len(address) == 0 {//utxoset中不存在，可能是内存交易中产生的utxo
+			continue
+		}
+		spendRecords[address] = append(spendRecords[address], record)
 	}
 
-	address := chain.Wallet.GetAddressByPubk(tran.Inputs[0].PubK)
-	var err error
-	spendUTXOs, err = chain.UTXOSet.GetUTXOsBySpendRecords(address, spendRecords)
-    if err != nil {
-    	return nil
+	foundUTXOs := make([]transaction.UTXO, 0)
+	for address, records := range spendRecords {
+		utxos, err := chain.UTXOSet.GetUTXOsBySpendRecords(address, records)
+		if err != nil {
+			return nil
+		}
+		foundUTXOs = append(foundUTXOs, utxos...)
 	}
 
 	//从内存中的交易序列中找出当前交易已消费的utxo
@@ -648,14 +997,27 @@ func (chain BlockChain) FindSpentUTXOsByTx(tran transaction.Transaction, memTxs
 			continue
 		}
 		for index, output := range memTx.Outputs {
+			//内存中的交易与当前交易打包在同一个区块中
 			utxo := transaction.NewUTXO(memTx.TxHash, index, output)
+			utxo.Height = chain.LastBlock.Height + 1
+			utxo.Time = chain.LastBlock.TimeStamp
 			for _, input := range tran.Inputs {
 				if utxo.IsUTXOSpend(input) {
-					spendUTXOs = append(spendUTXOs, utxo)
+					foundUTXOs = append(foundUTXOs, utxo)
 				}
 			}
 		}
 	}
+
+	//按照交易输入的顺序排列所花费的utxo，验签时交易输入与utxo一一对应
+	for _, input := range tran.Inputs {
+		for _, utxo := range foundUTXOs {
+			if utxo.IsUTXOSpend(input) {
+				spendUTXOs = append(spendUTXOs, utxo)
+				break
+			}
+		}
+	}
 	//把找到的特定交易的所花费的utxo集合返回
 	return spendUTXOs
 }
@@ -673,8 +1035,13 @@ func (chain *BlockChain) Setcoinbase(address string) error {
 }
 
 /**
- *返回当前节点的矿工的地址
+ *返回当前节点的矿工的地址，当前钱包没有设置矿工地址时使用默认钱包的矿工地址
  */
 func (chain *BlockChain) GetCoinbase() string {
-	return chain.Wallet.GetCoinbase()
+	miner := chain.Wallet.GetCoinbase()
+	if len(miner) == 0 && chain.Wallet.Name != wallet.DEFAULTWALLET {
+		defaultWallet := wallet.Wallet{Engine: chain.DB}
+		miner = defaultWallet.GetCoinbase()
+	}
+	return miner
 }
\ No newline at end of file
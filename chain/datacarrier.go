@@ -0,0 +1,93 @@
+package chain
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/transaction"
+	"errors"
+)
+
+/**
+ *区块中某个数据输出所携带的数据
+ */
+type DataPayload struct {
+	TxHash [32]byte //数据输出所在交易的哈希
+	Vout   int      //数据输出在交易中的下标
+	Data   []byte   //数据输出所携带的数据
+}
+
+/**
+ *把数据写入链上：花费from的一个utxo构建携带数据的交易，资金扣除手续费后全部转入新的找零地址，
+ *memPoolOnly为true时交易只提交到交易池，否则随后打包新区块，返回交易哈希
+ */
+func (chain *BlockChain) SendData(from string, data []byte, memPoolOnly bool) ([32]byte, error) {
+	if !chain.Wallet.CheckAddress(from) {
+		return [32]byte{}, errors.New("地址不符合规范，请检查后重试")
+	}
+	if len(data) == 0 {
+		return [32]byte{}, errors.New("要写入的数据不能为空")
+	}
+	utxos, _ := chain.GetUTXOsWithBalance(from, chain.memPoolTxs())
+	if len(utxos) == 0 {
+		return [32]byte{}, errors.New(from + "没有可花费的utxo，无法支付写入数据的交易")
+	}
+	pubk, err := chain.getInputPubKey(from)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	change, changeKey, err := chain.reserveChangeAddress(from)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	feeRate, err := chain.resolveFeeRate(0)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	newTx, err := fundFixedInputs(feeRate, func(fee float64) (*transaction.Transaction, error) {
+		newTx, err := transaction.CreateDataTransaction(utxos[:1], change, pubk, data, fee)
+		if err != nil {
+			return nil, err
+		}
+		return newTx, chain.signTransaction(newTx, from, utxos[:1], 0)
+	})
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if err = chain.keepChangeAddress(newTx, change, changeKey); err != nil {
+		return [32]byte{}, err
+	}
+	err = chain.submitWalletTransactions([]transaction.Transaction{*newTx}, memPoolOnly)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return newTx.TxHash, nil
+}
+
+/**
+ *查询指定高度的区块中所有数据输出所携带的数据
+ */
+func (chain *BlockChain) GetBlockData(height int64) ([]DataPayload, error) {
+	if height < 0 || height > chain.LastBlock.Height {
+		return nil, errors.New("区块高度超出范围")
+	}
+	blocks, err := chain.GetAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+	payloads := make([]DataPayload, 0)
+	for _, block := range blocks {
+		if block.Height != height {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			for index, output := range tx.Outputs {
+				data := script.ExtractNullData(output.GetScriptPub())
+				if data == nil {
+					continue
+				}
+				payloads = append(payloads, DataPayload{TxHash: tx.TxHash, Vout: index, Data: data})
+			}
+		}
+		return payloads, nil
+	}
+	return nil, errors.New("未找到指定高度的区块")
+}
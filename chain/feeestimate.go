@@ -0,0 +1,99 @@
+package chain
+
+import (
+	"XianfengChain04/mempool"
+	"XianfengChain04/transaction"
+	"errors"
+	"strconv"
+)
+
+const DEFAULTCONFIRMTARGET = 6 //未指定手续费率时，希望交易被确认所经过的区块个数
+const MAXCONFIRMTARGET = 144   //估算手续费时支持的最大确认区块个数
+const FEEMAXATTEMPTS = 5       //根据交易大小调整手续费的最大尝试次数
+
+/**
+ *估算交易在blocks个区块内被确认所需的手续费率（每字节）
+ *根据最近区块中交易池交易的确认情况进行估算，估算值不会低于打包区块所需的最低手续费率，
+ *历史数据不足时返回BLOCKMINFEERATE，并且第二个返回值为false
+ */
+func (chain *BlockChain) EstimateFee(blocks int64) (float64, bool, error) {
+	if blocks < 1 || blocks > MAXCONFIRMTARGET {
+		return 0, false, errors.New("确认区块个数需要在1到" + strconv.Itoa(MAXCONFIRMTARGET) + "之间")
+	}
+	samples, err := chain.MemPool.GetFeeSamples()
+	if err != nil {
+		return 0, false, err
+	}
+	pending, err := chain.MemPool.GetEntries()
+	if err != nil {
+		return 0, false, err
+	}
+	feeRate, ok := mempool.EstimateFeeRate(samples, pending, chain.LastBlock.Height, blocks)
+	if !ok {
+		return BLOCKMINFEERATE, false, nil
+	}
+	if feeRate < BLOCKMINFEERATE {
+		feeRate = BLOCKMINFEERATE
+	}
+	return feeRate, true, nil
+}
+
+/**
+ *确定发送交易时使用的手续费率：feeRate大于0时直接使用，否则估算在DEFAULTCONFIRMTARGET个区块内被确认所需的手续费率
+ */
+func (chain *BlockChain) resolveFeeRate(feeRate float64) (float64, error) {
+	if feeRate > 0 {
+		return feeRate, nil
+	}
+	if feeRate < 0 {
+		return 0, errors.New("手续费率不能为负数")
+	}
+	estimate, _, err := chain.EstimateFee(DEFAULTCONFIRMTARGET)
+	return estimate, err
+}
+
+/**
+ *为交易选择utxo并支付手续费：先按转账金额加上当前的手续费选币，由build构建并签名交易，
+ *再根据签名后的交易大小计算所需的手续费，手续费不足时重新选币，直到交易支付的手续费满足feeRate
+ */
+func (chain *BlockChain) fundTransaction(utxos []transaction.UTXO, amount float64, feeRate float64, selector transaction.CoinSelector,
+	build func(selected []transaction.UTXO, fee float64) (*transaction.Transaction, error)) (*transaction.Transaction, []transaction.UTXO, error) {
+	var fee float64
+	for attempt := 0; attempt < FEEMAXATTEMPTS; attempt++ {
+		selected, err := selector.Select(utxos, amount+fee)
+		if err != nil {
+			return nil, nil, err
+		}
+		newTx, err := build(selected, fee)
+		if err != nil {
+			return nil, nil, err
+		}
+		size, err := newTx.Size()
+		if err != nil {
+			return nil, nil, err
+		}
+		//签名的长度可能略有变化，按每个输入多出SIGSIZEMARGIN字节计算所需的手续费
+		required := feeRate * float64(size+SIGSIZEMARGIN*len(newTx.Inputs))
+		if fee > required-transaction.AMOUNTPRECISION {
+			return newTx, selected, nil
+		}
+		fee = required
+	}
+	return nil, nil, errors.New("无法确定交易的手续费，请重试")
+}
+
+/**
+ *为花费固定utxo且没有找零的交易支付手续费：先按手续费为0构建并签名交易，
+ *再根据签名后的交易大小按feeRate计算手续费，由build从交易输出中扣除后重新构建并签名
+ */
+func fundFixedInputs(feeRate float64, build func(fee float64) (*transaction.Transaction, error)) (*transaction.Transaction, error) {
+	newTx, err := build(0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := newTx.Size()
+	if err != nil {
+		return nil, err
+	}
+	return build(feeRate * float64(size+SIGSIZEMARGIN*len(newTx.Inputs)))
+}
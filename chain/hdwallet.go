@@ -0,0 +1,72 @@
+package chain
+
+import (
+	"XianfengChain04/utils"
+	"XianfengChain04/wallet"
+	"errors"
+)
+
+/**
+ *使用助记词恢复钱包：设置HD种子后按派生路径依次生成地址，并扫描区块和交易池找出收到过资金的地址，
+ *收到过资金的地址以及它们之前派生的地址被加入钱包，返回恢复的地址
+ *name不为空时创建一个该名称的新钱包并恢复到新钱包中，replace为true时替换当前钱包已有的HD种子
+ */
+func (chain *BlockChain) RestoreWallet(mnemonic string, name string, replace bool) ([]string, error) {
+	if len(name) > 0 {
+		if !wallet.IsMnemonicValid(mnemonic) {
+			return nil, errors.New("助记词不正确，请检查后重试")
+		}
+		if err := chain.CreateWallet(name); err != nil {
+			return nil, err
+		}
+		if err := chain.UseWallet(name); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if replace && chain.Wallet.HDChain != nil {
+		err = chain.Wallet.ReplaceHDSeed(mnemonic)
+	} else {
+		err = chain.Wallet.SetHDSeed(mnemonic)
+	}
+	if err != nil {
+		return nil, err
+	}
+	used, err := chain.usedPubkHashes()
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := chain.Wallet.RestoreHDAddresses(func(address string) bool {
+		reAddr := utils.Decode(address)
+		return used[string(reAddr[:len(reAddr)-4])]
+	})
+	if err != nil {
+		return nil, err
+	}
+	//重建恢复的地址的交易记录
+	return addresses, chain.RescanWallet()
+}
+
+/**
+ *扫描所有区块和交易池中的交易输出，返回出现过的公钥哈希
+ */
+func (chain *BlockChain) usedPubkHashes() (map[string]bool, error) {
+	blocks, err := chain.GetAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			for _, output := range tx.Outputs {
+				used[string(output.PubkHash)] = true
+			}
+		}
+	}
+	for _, tx := range chain.memPoolTxs() {
+		for _, output := range tx.Outputs {
+			used[string(output.PubkHash)] = true
+		}
+	}
+	return used, nil
+}
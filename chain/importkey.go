@@ -0,0 +1,35 @@
+package chain
+
+/**
+ *导入WIF格式的私钥，rescan为true时重新扫描区块链记录该地址的历史交易，并返回该地址当前的余额
+ */
+func (chain *BlockChain) ImportPrivKey(wif string, rescan bool) (string, float64, error) {
+	address, err := chain.Wallet.ImportPrivKey(wif)
+	if err != nil {
+		return "", 0, err
+	}
+	if !rescan {
+		return address, 0, nil
+	}
+	if err = chain.RescanWallet(); err != nil {
+		return "", 0, err
+	}
+	balance, err := chain.GetBalance(address)
+	return address, balance, err
+}
+
+/**
+ *导入观察地址，rescan为true时重新扫描区块链记录该地址的历史交易，并返回该地址当前的余额
+ */
+func (chain *BlockChain) ImportAddress(address string, rescan bool) (float64, error) {
+	if err := chain.Wallet.ImportAddress(address); err != nil {
+		return 0, err
+	}
+	if !rescan {
+		return 0, nil
+	}
+	if err := chain.RescanWallet(); err != nil {
+		return 0, err
+	}
+	return chain.GetBalance(address)
+}
@@ -0,0 +1,353 @@
+package chain
+
+import (
+	"XianfengChain04/mempool"
+	"XianfengChain04/transaction"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const MINRELAYFEERATE = 0.000001         //交易进入交易池所需的最低手续费率（每字节）
+const INCREMENTALRELAYFEERATE = 0.000001 //替换交易在被替换交易的手续费之外至少需要多支付的手续费率（每字节）
+const MAXREPLACEMENTEVICTIONS = 100      //一笔替换交易最多可以从交易池中移除的交易个数
+const BLOCKMINFEERATE = 0.00001          //交易包被打包进区块所需的最低手续费率（每字节）
+const BLOCKMAXSIZE = 1000000             //打包区块时所有交易的最大字节数
+const SIGSIZEMARGIN = 2                  //估算重新签名后的交易大小时，每个交易输入预留的字节数
+
+/**
+ *把交易提交到交易池中等待打包，返回交易哈希
+ *交易与交易池中的交易花费了同一个utxo时，按照替换规则判断能否替换交易池中的交易：
+ *被替换的交易需要声明允许替换，替换交易支付的手续费不能少于被移除的所有交易的手续费之和，
+ *并且需要按INCREMENTALRELAYFEERATE为自身的大小额外支付手续费，手续费率也需要高于被替换的交易
+ */
+func (chain *BlockChain) AcceptToMemPool(tx *transaction.Transaction) ([32]byte, error) {
+	if tx.IsCoinbase() {
+		return [32]byte{}, errors.New("coinbase交易不能进入交易池")
+	}
+	if existing, err := chain.MemPool.GetEntry(tx.TxHash); err != nil || existing != nil {
+		return [32]byte{}, errors.New("交易已经在交易池中")
+	}
+	nextHeight := chain.LastBlock.Height + 1
+	if !tx.IsFinal(nextHeight, chain.LastBlock.TimeStamp) {
+		return [32]byte{}, errors.New("交易尚未到达锁定时间，不能进入交易池")
+	}
+	entries, err := chain.MemPool.GetEntries()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	//与当前交易冲突的交易及其后代交易将被移除，当前交易不能花费它们的输出
+	conflicts := mempool.Conflicts(entries, *tx)
+	conflictIds := make([][32]byte, 0)
+	for _, conflict := range conflicts {
+		conflictIds = append(conflictIds, conflict.Tx.TxHash)
+	}
+	evicted := append(conflicts, mempool.Descendants(entries, conflictIds)...)
+	evictedIds := make(map[[32]byte]bool)
+	for _, entry := range evicted {
+		evictedIds[entry.Tx.TxHash] = true
+	}
+	memTxs := make([]transaction.Transaction, 0)
+	for _, entry := range entries {
+		if !evictedIds[entry.Tx.TxHash] {
+			memTxs = append(memTxs, entry.Tx)
+		}
+	}
+
+	utxos := chain.FindSpentUTXOsByTx(*tx, memTxs)
+	if len(utxos) != len(tx.Inputs) {
+		return [32]byte{}, errors.New("交易所花费的utxo不存在或已被花费")
+	}
+	if err = tx.CheckSequenceLocks(utxos, nextHeight, chain.LastBlock.TimeStamp); err != nil {
+		return [32]byte{}, err
+	}
+	isVerify, err := tx.VerifyTx(utxos)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if !isVerify {
+		return [32]byte{}, errors.New("交易签名验证失败，请重试！")
+	}
+	fee, err := tx.Fee(utxos)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	size, err := tx.Size()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	entry := mempool.TxEntry{Tx: *tx, Fee: fee, Size: size, Time: time.Now().UnixNano(), Height: chain.LastBlock.Height}
+	if entry.FeeRate() < MINRELAYFEERATE {
+		return [32]byte{}, fmt.Errorf("交易的手续费率%.8f低于交易池的最低手续费率%.8f", entry.FeeRate(), MINRELAYFEERATE)
+	}
+	if len(conflicts) > 0 {
+		if err = checkReplacement(entry, conflicts, evicted, memTxs); err != nil {
+			return [32]byte{}, err
+		}
+		removeIds := make([][32]byte, 0, len(evicted))
+		for _, evictedEntry := range evicted {
+			removeIds = append(removeIds, evictedEntry.Tx.TxHash)
+		}
+		if err = chain.MemPool.RemoveEntries(removeIds); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	if err = chain.MemPool.AddEntry(entry); err != nil {
+		return [32]byte{}, err
+	}
+	return tx.TxHash, nil
+}
+
+/**
+ *把钱包构建的交易依次提交到交易池，memPoolOnly为false时随后从交易池中挑选交易打包新区块，
+ *任意一笔交易无法进入交易池时撤回本次已提交的交易；手续费率低于BLOCKMINFEERATE的交易不会被打包，留在交易池中等待
+ */
+func (chain *BlockChain) submitWalletTransactions(txs []transaction.Transaction, memPoolOnly bool) error {
+	if !memPoolOnly && len(chain.GetCoinbase()) == 0 {
+		return errors.New("还未设置coinbase地址")
+	}
+	accepted := make([][32]byte, 0, len(txs))
+	for index := range txs {
+		if _, err := chain.AcceptToMemPool(&txs[index]); err != nil {
+			if removeErr := chain.MemPool.RemoveEntries(accepted); removeErr != nil {
+				return removeErr
+			}
+			return err
+		}
+		accepted = append(accepted, txs[index].TxHash)
+	}
+	if memPoolOnly {
+		return nil
+	}
+	if _, err := chain.GenerateBlock(); err != nil {
+		return err
+	}
+	for _, txId := range accepted {
+		entry, err := chain.MemPool.GetEntry(txId)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			return fmt.Errorf("交易%x的手续费率低于打包所需的最低手续费率%.8f，已进入交易池等待打包", txId, BLOCKMINFEERATE)
+		}
+	}
+	return nil
+}
+
+/**
+ *获取交易池中的所有交易条目
+ */
+func (chain *BlockChain) GetMemPool() ([]mempool.TxEntry, error) {
+	return chain.MemPool.GetEntries()
+}
+
+/**
+ *按照交易包的手续费率从交易池中挑选交易打包进新区块，返回被打包的交易个数
+ */
+func (chain *BlockChain) GenerateBlock() (int, error) {
+	entries, err := chain.MemPool.GetEntries()
+	if err != nil {
+		return 0, err
+	}
+	selected := mempool.SelectPackages(entries, BLOCKMAXSIZE, BLOCKMINFEERATE)
+	txs := make([]transaction.Transaction, 0, len(selected))
+	for _, entry := range selected {
+		txs = append(txs, entry.Tx)
+	}
+	//被打包的交易由packTransactions从交易池中移除
+	err = chain.packTransactions(txs)
+	if err != nil {
+		return 0, err
+	}
+	return len(txs), nil
+}
+
+/**
+ *提高交易池中一笔钱包交易的手续费：构建花费相同输入的替换交易，从钱包的找零输出中扣除增加的手续费
+ *feeRate为替换交易的手续费率，为0时使用满足替换规则的最低手续费，返回替换交易的哈希
+ */
+func (chain *BlockChain) BumpFee(txId [32]byte, feeRate float64) ([32]byte, error) {
+	entry, err := chain.MemPool.GetEntry(txId)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if entry == nil {
+		return [32]byte{}, errors.New("交易池中不存在该交易，交易可能已被确认")
+	}
+	if !entry.Tx.SignalsReplacement() {
+		return [32]byte{}, errors.New("该交易未声明允许替换，无法提高手续费")
+	}
+	entries, err := chain.MemPool.GetEntries()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	//替换交易需要同时支付被移除的后代交易的手续费
+	evictedFee := entry.Fee
+	for _, descendant := range mempool.Descendants(entries, [][32]byte{txId}) {
+		evictedFee += descendant.Fee
+	}
+	//重新签名后签名的长度可能略有变化，按每个输入多出SIGSIZEMARGIN字节估算替换交易的大小
+	size := float64(entry.Size + SIGSIZEMARGIN*len(entry.Tx.Inputs))
+	newFee := evictedFee + INCREMENTALRELAYFEERATE*size
+	if feeRate*size > newFee {
+		newFee = feeRate * size
+	}
+
+	//找到属于本地钱包的找零输出，从找零中扣除增加的手续费，优先使用找零地址的输出
+	changeIndex := -1
+	for index, output := range entry.Tx.Outputs {
+		if output.IsUnspendable() {
+			continue
+		}
+		address := chain.Wallet.GetAddressByPubkHash(output.PubkHash)
+		if chain.Wallet.IsInternalAddress(address) {
+			changeIndex = index
+			break
+		}
+		if _, err := chain.Wallet.GetSignersByAddress(address); err == nil {
+			changeIndex = index
+		}
+	}
+	if changeIndex < 0 {
+		return [32]byte{}, errors.New("交易中没有属于当前钱包的找零输出，无法提高手续费")
+	}
+	outputs := make([]transaction.TxOutPut, 0, len(entry.Tx.Outputs))
+	for index, output := range entry.Tx.Outputs {
+		if index == changeIndex {
+			output.Value -= newFee - entry.Fee
+			if output.Value < -transaction.AMOUNTPRECISION {
+				return [32]byte{}, errors.New("找零不足以支付新的手续费")
+			}
+			//找零被全部用于支付手续费时不再保留找零输出
+			if output.Value < transaction.AMOUNTPRECISION {
+				continue
+			}
+		}
+		outputs = append(outputs, output)
+	}
+	inputs := make([]transaction.TxInput, 0, len(entry.Tx.Inputs))
+	for _, input := range entry.Tx.Inputs {
+		inputs = append(inputs, transaction.TxInput{TxId: input.TxId, Vout: input.Vout, Sequence: input.Sequence})
+	}
+	newTx, err := transaction.NewRawTransaction(inputs, outputs, entry.Tx.LockedTime)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	complete, err := chain.signRawTransaction(newTx, &chain.Wallet, nil, transaction.SIGHASH_ALL)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if !complete {
+		return [32]byte{}, errors.New("当前钱包无法完成替换交易的全部签名")
+	}
+	return chain.AcceptToMemPool(newTx)
+}
+
+/**
+ *获取交易池中的所有交易，用于查找未确认交易产生的utxo
+ */
+func (chain *BlockChain) memPoolTxs() []transaction.Transaction {
+	txs := make([]transaction.Transaction, 0)
+	entries, err := chain.MemPool.GetEntries()
+	if err != nil {
+		return txs
+	}
+	for _, entry := range entries {
+		txs = append(txs, entry.Tx)
+	}
+	return txs
+}
+
+/**
+ *检查替换交易是否满足替换规则
+ */
+func checkReplacement(entry mempool.TxEntry, conflicts []mempool.TxEntry, evicted []mempool.TxEntry, memTxs []transaction.Transaction) error {
+	for _, conflict := range conflicts {
+		if !conflict.Tx.SignalsReplacement() {
+			return fmt.Errorf("交易%x未声明允许替换", conflict.Tx.TxHash)
+		}
+		if entry.FeeRate() <= conflict.FeeRate() {
+			return fmt.Errorf("替换交易的手续费率需要高于被替换交易的手续费率%.8f", conflict.FeeRate())
+		}
+	}
+	if len(evicted) > MAXREPLACEMENTEVICTIONS {
+		return fmt.Errorf("替换交易最多只能移除%d笔交易", MAXREPLACEMENTEVICTIONS)
+	}
+	//替换交易只能花费被替换交易已经花费的未确认输出，不能引入新的未确认交易
+	unconfirmedParents := make(map[[32]byte]bool)
+	for _, conflict := range conflicts {
+		for _, input := range conflict.Tx.Inputs {
+			unconfirmedParents[input.TxId] = true
+		}
+	}
+	for _, input := range entry.Tx.Inputs {
+		for _, memTx := range memTxs {
+			if memTx.TxHash == input.TxId && !unconfirmedParents[input.TxId] {
+				return errors.New("替换交易不能花费新的未确认交易的输出")
+			}
+		}
+	}
+	var evictedFee float64
+	for _, evictedEntry := range evicted {
+		evictedFee += evictedEntry.Fee
+	}
+	if entry.Fee < evictedFee {
+		return fmt.Errorf("替换交易的手续费不能少于被移除交易的手续费之和%.8f", evictedFee)
+	}
+	if entry.Fee-evictedFee < INCREMENTALRELAYFEERATE*float64(entry.Size)-transaction.AMOUNTPRECISION {
+		return fmt.Errorf("替换交易至少需要多支付%.8f的手续费", INCREMENTALRELAYFEERATE*float64(entry.Size))
+	}
+	return nil
+}
+
+/**
+ *区块被打包后，从交易池中移除已被打包的交易，以及与区块中的交易冲突的交易及其后代交易，
+ *并记录交易池中的交易经过多少个区块才被确认，用于估算手续费
+ */
+func (chain *BlockChain) removeMinedFromMemPool(blockTxs []transaction.Transaction) error {
+	entries, err := chain.MemPool.GetEntries()
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	minedIds := make(map[[32]byte]bool)
+	for _, tx := range blockTxs {
+		minedIds[tx.TxHash] = true
+	}
+	byId := make(map[[32]byte]mempool.TxEntry)
+	for _, entry := range entries {
+		byId[entry.Tx.TxHash] = entry
+	}
+	samples := make([]mempool.FeeSample, 0)
+	removeIds := make([][32]byte, 0)
+	conflictIds := make([][32]byte, 0)
+	for _, tx := range blockTxs {
+		if tx.IsCoinbase() {
+			continue
+		}
+		if entry, ok := byId[tx.TxHash]; ok {
+			samples = append(samples, mempool.FeeSample{
+				FeeRate: entry.FeeRate(),
+				Blocks:  chain.LastBlock.Height - entry.Height,
+				Height:  chain.LastBlock.Height,
+			})
+		}
+		removeIds = append(removeIds, tx.TxHash)
+		for _, conflict := range mempool.Conflicts(entries, tx) {
+			if !minedIds[conflict.Tx.TxHash] {
+				conflictIds = append(conflictIds, conflict.Tx.TxHash)
+			}
+		}
+	}
+	//已被打包交易的后代交易仍然有效，只移除冲突交易的后代交易
+	removeIds = append(removeIds, conflictIds...)
+	for _, descendant := range mempool.Descendants(entries, conflictIds) {
+		removeIds = append(removeIds, descendant.Tx.TxHash)
+	}
+	if len(samples) > 0 {
+		if err = chain.MemPool.RecordFeeSamples(samples, chain.LastBlock.Height); err != nil {
+			return err
+		}
+	}
+	return chain.MemPool.RemoveEntries(removeIds)
+}
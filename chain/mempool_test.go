@@ -0,0 +1,122 @@
+package chain
+
+import (
+	"XianfengChain04/mempool"
+	"XianfengChain04/transaction"
+	"testing"
+)
+
+/**
+ *在临时数据库中创建一条只有创世区块的链，创世区块的奖励属于钱包中的一个新地址，该地址同时是矿工地址
+ */
+func newTestChain(t *testing.T) (*BlockChain, string) {
+	chain, err := CreateChain(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := chain.GetNewAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = chain.CreateCoinBase(address); err != nil {
+		t.Fatal(err)
+	}
+	return chain, address
+}
+
+/**
+ *构建一个花费parent交易第0个输出的测试交易条目
+ */
+func newTestEntry(id byte, parent byte, sequence uint32, fee float64, size int) mempool.TxEntry {
+	tx := transaction.Transaction{TxHash: [32]byte{id}}
+	tx.Inputs = []transaction.TxInput{{TxId: [32]byte{parent}, Vout: 0, Sequence: sequence}}
+	return mempool.TxEntry{Tx: tx, Fee: fee, Size: size}
+}
+
+func TestCheckReplacement(t *testing.T) {
+	conflict := newTestEntry(2, 1, transaction.REPLACEABLESEQUENCE, 0.001, 100)
+	conflicts := []mempool.TxEntry{conflict}
+	if err := checkReplacement(newTestEntry(3, 1, 0, 0.002, 100), conflicts, conflicts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	final := newTestEntry(2, 1, transaction.DEFAULTSEQUENCE, 0.001, 100)
+	cases := []struct {
+		name      string
+		entry     mempool.TxEntry
+		conflicts []mempool.TxEntry
+		evicted   []mempool.TxEntry
+	}{
+		{"未声明允许替换", newTestEntry(3, 1, 0, 0.002, 100), []mempool.TxEntry{final}, []mempool.TxEntry{final}},
+		{"手续费率没有提高", newTestEntry(3, 1, 0, 0.0009, 100), conflicts, conflicts},
+		{"手续费少于被移除的交易", newTestEntry(3, 1, 0, 0.0008, 50), conflicts, conflicts},
+		{"没有支付增量手续费", newTestEntry(3, 1, 0, 0.00105, 100), conflicts, conflicts},
+		{"移除的交易过多", newTestEntry(3, 1, 0, 1, 100), conflicts, make([]mempool.TxEntry, MAXREPLACEMENTEVICTIONS+1)},
+	}
+	for _, c := range cases {
+		if err := checkReplacement(c.entry, c.conflicts, c.evicted, nil); err == nil {
+			t.Fatalf("%s的替换交易应当被拒绝", c.name)
+		}
+	}
+
+	//替换交易不能引入新的未确认父交易
+	entry := newTestEntry(3, 1, 0, 0.002, 100)
+	entry.Tx.Inputs = append(entry.Tx.Inputs, transaction.TxInput{TxId: [32]byte{9}})
+	memTxs := []transaction.Transaction{{TxHash: [32]byte{9}}}
+	if err := checkReplacement(entry, conflicts, conflicts, memTxs); err == nil {
+		t.Fatal("花费新的未确认交易输出的替换交易应当被拒绝")
+	}
+}
+
+func TestWalletSendBumpFee(t *testing.T) {
+	chain, from := newTestChain(t)
+	to, err := chain.GetNewAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = chain.SendTransaction([]string{from}, []string{to}, []float64{1}, 0, nil, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := chain.GetMemPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].Tx.SignalsReplacement() {
+		t.Fatalf("钱包交易应当声明允许替换并留在交易池中：%+v", entries)
+	}
+	original := entries[0]
+
+	replacementId, err := chain.BumpFee(original.Tx.TxHash, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := chain.MemPool.GetEntry(original.Tx.TxHash); err != nil || entry != nil {
+		t.Fatal("被替换的交易应当从交易池中移除")
+	}
+	replacement, err := chain.MemPool.GetEntry(replacementId)
+	if err != nil || replacement == nil {
+		t.Fatal("替换交易应当进入交易池")
+	}
+	if replacement.Fee <= original.Fee || replacement.Tx.Outputs[0].Value != 1 {
+		t.Fatalf("替换交易应当从找零中支付更高的手续费：%+v", replacement)
+	}
+
+	count, err := chain.GenerateBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("新区块应当打包1笔交易，实际打包了%d笔", count)
+	}
+	samples, err := chain.MemPool.GetFeeSamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Blocks != 1 {
+		t.Fatalf("钱包交易被确认后应当记录手续费样本：%+v", samples)
+	}
+	if _, err = chain.BumpFee(replacementId, 0); err == nil {
+		t.Fatal("已被确认的交易不能提高手续费")
+	}
+}
@@ -0,0 +1,209 @@
+package chain
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/transaction"
+	"XianfengChain04/utxoset"
+	"XianfengChain04/wallet"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+)
+
+/**
+ *旧版本使用gob编码保存的区块，交易没有版本号、序列号和锁定脚本
+ */
+type legacyBlock struct {
+	Height       int64
+	Version      int64
+	PrevHash     [32]byte
+	Hash         [32]byte
+	TimeStamp    int64
+	Nonce        int64
+	Transactions []legacyTransaction
+}
+
+type legacyTransaction struct {
+	TxHash     [32]byte
+	Inputs     []legacyTxInput
+	Outputs    []legacyTxOutPut
+	LockedTime int64 //旧版本中表示交易的生成时间，不是锁定时间
+}
+
+type legacyTxInput struct {
+	TxId [32]byte
+	Vout int
+	Sig  []byte
+	PubK []byte
+}
+
+type legacyTxOutPut struct {
+	Value    float64
+	PubkHash []byte
+}
+
+/**
+ *把旧版本使用gob编码保存的区块迁移到新的二进制序列化格式，并按照新的交易哈希重建utxo集合
+ *区块哈希和前一个区块的哈希保持不变；交易哈希按照新的格式重新计算，交易输入所引用的交易哈希随之替换；
+ *旧版本的coinbase交易没有交易输入，迁移时补充一个coinbase交易输入，附加数据为旧的交易哈希
+ *最新区块可以按照新格式解码时不做任何处理
+ */
+func migrateLegacyBlocks(db *bolt.DB, w *wallet.Wallet) error {
+	legacyBlocks, err := readLegacyBlocks(db)
+	if err != nil || len(legacyBlocks) == 0 {
+		return err
+	}
+
+	//按照从创世区块到最新区块的顺序转换交易，后面的交易才能找到所引用交易的新哈希
+	txIds := make(map[[32]byte][32]byte)
+	blocks := make([]Block, 0, len(legacyBlocks))
+	utxos := make(map[string]transaction.UTXO)
+	for i := len(legacyBlocks) - 1; i >= 0; i-- {
+		legacy := legacyBlocks[i]
+		block := Block{
+			Height:    legacy.Height,
+			Version:   VERSION,
+			PrevHash:  legacy.PrevHash,
+			Hash:      legacy.Hash,
+			TimeStamp: legacy.TimeStamp,
+			Nonce:     legacy.Nonce,
+		}
+		for _, legacyTx := range legacy.Transactions {
+			tx, err := convertLegacyTx(legacyTx, legacy.Height, txIds)
+			if err != nil {
+				return err
+			}
+			txIds[legacyTx.TxHash] = tx.TxHash
+			for _, input := range tx.Inputs {
+				delete(utxos, fmt.Sprintf("%x:%d", input.TxId, input.Vout))
+			}
+			for index, output := range tx.Outputs {
+				utxo := transaction.NewUTXO(tx.TxHash, index, output)
+				utxo.Height = block.Height
+				utxo.Time = block.TimeStamp
+				utxos[fmt.Sprintf("%x:%d", tx.TxHash, index)] = utxo
+			}
+			block.Transactions = append(block.Transactions, *tx)
+		}
+		blocks = append(blocks, block)
+	}
+
+	//先重建utxo集合再改写区块，迁移中断时下次启动仍然能识别出旧格式的区块并重新迁移
+	utxoSet := make(map[string][]transaction.UTXO)
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			for index, output := range tx.Outputs {
+				utxo, ok := utxos[fmt.Sprintf("%x:%d", tx.TxHash, index)]
+				if !ok {
+					continue
+				}
+				address := w.GetAddressByPubkHash(output.PubkHash)
+				utxoSet[address] = append(utxoSet[address], utxo)
+			}
+		}
+	}
+	set := utxoset.NewUTXOSet(db)
+	if err = set.Rebuild(utxoSet); err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BLOCKS))
+		for _, block := range blocks {
+			blockBytes, err := block.Serialize()
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put(block.Hash[:], blockBytes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/**
+ *从最新区块开始沿着前一个区块的哈希读取全部旧格式的区块，返回的区块按照从新到旧的顺序排列
+ *最新区块不存在或者已经是新格式时返回空切片，两种格式都无法解码时返回错误
+ */
+func readLegacyBlocks(db *bolt.DB) ([]legacyBlock, error) {
+	blocks := make([]legacyBlock, 0)
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BLOCKS))
+		if bucket == nil {
+			return nil
+		}
+		hash := bucket.Get([]byte(LASTHASH))
+		if len(hash) == 0 {
+			return nil
+		}
+		lastBlockBytes := bucket.Get(hash)
+		_, err := Deserialize(lastBlockBytes)
+		if err == nil {
+			return nil
+		}
+		if decodeLegacyBlock(lastBlockBytes, &legacyBlock{}) != nil {
+			return errors.New("读取最新区块时遇到错误：" + err.Error())
+		}
+		for {
+			var block legacyBlock
+			if err = decodeLegacyBlock(bucket.Get(hash), &block); err != nil {
+				return errors.New("读取旧版本区块时遇到错误：" + err.Error())
+			}
+			blocks = append(blocks, block)
+			if block.PrevHash == [32]byte{} {
+				return nil
+			}
+			hash = block.PrevHash[:]
+		}
+	})
+	return blocks, err
+}
+
+/**
+ *使用gob解码一个旧格式的区块
+ */
+func decodeLegacyBlock(data []byte, block *legacyBlock) error {
+	if len(data) == 0 {
+		return errors.New("区块数据不存在")
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(block)
+}
+
+/**
+ *把旧格式的交易转换为新格式的交易，txIds记录了之前已经转换的交易的旧哈希与新哈希的对应关系
+ *旧交易的签名是对旧格式数据的签名，只作为历史记录保留，交易输入的sequence为SEQUENCEFINAL，不启用时间锁
+ */
+func convertLegacyTx(legacyTx legacyTransaction, height int64, txIds map[[32]byte][32]byte) (*transaction.Transaction, error) {
+	tx := transaction.Transaction{Version: transaction.TXVERSION}
+	if len(legacyTx.Inputs) == 0 {
+		input, err := transaction.NewCoinbaseInput(height, legacyTx.TxHash[:])
+		if err != nil {
+			return nil, err
+		}
+		tx.Inputs = append(tx.Inputs, input)
+	}
+	for _, legacyInput := range legacyTx.Inputs {
+		txId, ok := txIds[legacyInput.TxId]
+		if !ok {
+			return nil, fmt.Errorf("旧版本交易%x引用的交易%x不存在", legacyTx.TxHash, legacyInput.TxId)
+		}
+		input := transaction.NewTxInput(txId, legacyInput.Vout, legacyInput.PubK)
+		input.Sequence = script.SEQUENCEFINAL
+		input.Sig = legacyInput.Sig
+		tx.Inputs = append(tx.Inputs, input)
+	}
+	for _, legacyOutput := range legacyTx.Outputs {
+		output := transaction.TxOutPut{Value: legacyOutput.Value, PubkHash: legacyOutput.PubkHash}
+		output.ScriptPub = output.GetScriptPub()
+		tx.Outputs = append(tx.Outputs, output)
+	}
+	txHash, err := tx.CalculateTxHash()
+	if err != nil {
+		return nil, err
+	}
+	copy(tx.TxHash[:], txHash)
+	return &tx, nil
+}
@@ -0,0 +1,100 @@
+package chain
+
+import (
+	"XianfengChain04/transaction"
+	"XianfengChain04/utils"
+	"XianfengChain04/utxoset"
+	"XianfengChain04/wallet"
+	"github.com/boltdb/bolt"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ *在临时目录中创建一个测试用的数据库
+ */
+func openTestDB(t *testing.T) *bolt.DB {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+/**
+ *旧版本的两个区块：创世区块的coinbase交易，以及花费该coinbase并找零的转账交易
+ */
+func TestMigrateLegacyBlocks(t *testing.T) {
+	db := openTestDB(t)
+	pubkHash := transaction.LockMoney2PubkHash(1, testAddress).PubkHash
+	coinbase := legacyTransaction{TxHash: [32]byte{0xc0}, Outputs: []legacyTxOutPut{{50, pubkHash}}}
+	spend := legacyTransaction{
+		TxHash:  [32]byte{0x5e},
+		Inputs:  []legacyTxInput{{TxId: coinbase.TxHash, Vout: 0, Sig: make([]byte, 64), PubK: []byte{0x04}}},
+		Outputs: []legacyTxOutPut{{20, pubkHash}, {30, pubkHash}},
+	}
+	genesis := legacyBlock{Height: 0, Hash: [32]byte{1}, TimeStamp: 100, Transactions: []legacyTransaction{coinbase}}
+	second := legacyBlock{Height: 1, PrevHash: genesis.Hash, Hash: [32]byte{2}, TimeStamp: 200,
+		Transactions: []legacyTransaction{spend}}
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte(BLOCKS))
+		if err != nil {
+			return err
+		}
+		for _, block := range []legacyBlock{genesis, second} {
+			blockBytes, err := utils.Encoder(block)
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put(block.Hash[:], blockBytes); err != nil {
+				return err
+			}
+		}
+		return bucket.Put([]byte(LASTHASH), second.Hash[:])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = migrateLegacyBlocks(db, &wallet.Wallet{}); err != nil {
+		t.Fatal(err)
+	}
+	blocks := make([]Block, 0)
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BLOCKS))
+		for _, hash := range [][32]byte{genesis.Hash, second.Hash} {
+			block, err := Deserialize(bucket.Get(hash[:]))
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, block)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migratedCoinbase := blocks[0].Transactions[0]
+	if !migratedCoinbase.Inputs[0].IsCoinbaseInput() {
+		t.Fatal("迁移后的coinbase交易缺少coinbase交易输入")
+	}
+	migratedSpend := blocks[1].Transactions[0]
+	if blocks[1].PrevHash != genesis.Hash || migratedSpend.Inputs[0].TxId != migratedCoinbase.TxHash {
+		t.Fatal("迁移后的交易输入没有引用迁移后的交易哈希")
+	}
+
+	set := utxoset.NewUTXOSet(db)
+	utxos, err := set.QueryUTXOsByAddress(testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 2 || utxos[0].TxId != migratedSpend.TxHash || utxos[0].Value+utxos[1].Value != 50 {
+		t.Fatalf("迁移后的utxo集合不正确：%+v", utxos)
+	}
+
+	//再次迁移时区块已经是新格式，不做任何处理
+	if err = migrateLegacyBlocks(db, &wallet.Wallet{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,160 @@
+package chain
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/transaction"
+	"XianfengChain04/wallet"
+	"encoding/hex"
+)
+
+/**
+ *使用指定的交易输入和交易输出创建部分签名交易，并使用本地链和钱包补充所花费的utxo和赎回脚本
+ */
+func (chain *BlockChain) CreatePSBT(inputs []RawTxInput, outputs []RawTxOutput, lockTime int64) (*transaction.PSBT, error) {
+	tx, err := chain.CreateRawTransaction(inputs, outputs, lockTime)
+	if err != nil {
+		return nil, err
+	}
+	psbt, err := transaction.NewPSBT(tx)
+	if err != nil {
+		return nil, err
+	}
+	return psbt, chain.UpdatePSBT(psbt)
+}
+
+/**
+ *为部分签名交易补充本地链上能够查询到的utxo，本地钱包中保存的P2SH赎回脚本，以及MuSig聚合地址的参与方公钥
+ */
+func (chain *BlockChain) UpdatePSBT(psbt *transaction.PSBT) error {
+	found := chain.FindSpentUTXOsByTx(*psbt.Tx, nil)
+	for index, input := range psbt.Tx.Inputs {
+		utxo := psbt.Inputs[index].UTXO
+		if utxo == nil {
+			for i := range found {
+				if found[i].IsUTXOSpend(input) {
+					utxo = &found[i]
+					break
+				}
+			}
+		}
+		if utxo == nil {
+			continue
+		}
+		address := chain.Wallet.GetAddressByPubkHash(utxo.PubkHash)
+		redeemScript, _ := chain.Wallet.GetRedeemScript(address)
+		if len(psbt.Inputs[index].RedeemScript) == 0 && len(redeemScript) == 0 && script.IsPayToScriptHash(utxo.GetScriptPub()) {
+			//本地钱包没有该P2SH地址的赎回脚本，只记录utxo，由其他参与方补充赎回脚本
+			psbt.Inputs[index].UTXO = utxo
+			continue
+		}
+		if err := psbt.SetInputUTXO(index, *utxo, redeemScript); err != nil {
+			return err
+		}
+		if pubks, ok := chain.Wallet.MuSigKeys[address]; ok && len(psbt.Inputs[index].MuSigKeys) == 0 {
+			if err := psbt.SetMuSigKeys(index, pubks); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/**
+ *使用本地钱包中的私钥按照hashType对部分签名交易签名，返回本次新增的签名个数和MuSig公开nonce的个数
+ *MuSig聚合地址的其他参与方不在本地时，第一次签名为本地参与方生成nonce，
+ *收集到所有参与方的nonce之后再次签名生成部分签名，所有部分签名聚合后即完成该输入的签名
+ */
+func (chain *BlockChain) SignPSBT(psbt *transaction.PSBT, hashType byte) (int, int, error) {
+	signed, nonces := 0, 0
+	for index := range psbt.Inputs {
+		if psbt.Inputs[index].UTXO == nil {
+			continue
+		}
+		pubkHash, pubks, _, err := psbt.InputKeys(index)
+		if err != nil {
+			//缺少赎回脚本或不支持的锁定脚本，留给其他参与方处理
+			continue
+		}
+		signers := make([]wallet.Signer, 0)
+		if pubkHash != nil {
+			address := chain.Wallet.GetAddressByPubkHash(append([]byte{wallet.VERSION}, pubkHash...))
+			if signer, err := chain.Wallet.GetSignerByAddress(address); err == nil {
+				signers = append(signers, signer)
+			} else if len(psbt.Inputs[index].MuSigKeys) > 0 {
+				newNonces, newSigs, err := chain.signMuSigInput(psbt, index, hashType)
+				if err != nil {
+					return signed, nonces, err
+				}
+				nonces += newNonces
+				signed += newSigs
+				continue
+			}
+		}
+		for _, pubk := range pubks {
+			if keyPair := chain.Wallet.GetKeyPairByAddress(chain.Wallet.GetAddressByPubk(pubk)); keyPair != nil {
+				signers = append(signers, keyPair)
+			}
+		}
+		for _, signer := range signers {
+			if err = psbt.SignInput(index, signer, hashType); err != nil {
+				return signed, nonces, err
+			}
+			signed++
+		}
+	}
+	return signed, nonces, nil
+}
+
+/**
+ *使用本地持有的MuSig参与方秘钥对推进第index个输入的多方签名：还没有提供nonce的本地参与方生成nonce，
+ *所有参与方的nonce都已收集时尚未签名的本地参与方生成部分签名，返回新增的nonce个数和部分签名个数
+ */
+func (chain *BlockChain) signMuSigInput(psbt *transaction.PSBT, index int, hashType byte) (int, int, error) {
+	input := psbt.Inputs[index]
+	nonces, signed := 0, 0
+	for _, pubk := range input.MuSigKeys {
+		keyPair := chain.Wallet.GetKeyPairByAddress(chain.Wallet.GetAddressByPubk(pubk))
+		if keyPair == nil {
+			continue
+		}
+		key := hex.EncodeToString(pubk)
+		if _, ok := input.MuSigNonces[key]; !ok {
+			sigHash, err := psbt.SigHash(index, hashType)
+			if err != nil {
+				return nonces, signed, err
+			}
+			nonce, err := chain.Wallet.NewMuSigNonce(keyPair, input.MuSigKeys, sigHash)
+			if err != nil {
+				return nonces, signed, err
+			}
+			if err = psbt.AddMuSigNonce(index, pubk, nonce, hashType); err != nil {
+				return nonces, signed, err
+			}
+			nonces++
+			continue
+		}
+		if _, ok := input.MuSigPartialSigs[key]; ok {
+			continue
+		}
+		pubNonces, nonceHashType, complete, err := psbt.MuSigNonces(index)
+		if err != nil {
+			return nonces, signed, err
+		}
+		if !complete {
+			continue
+		}
+		sigHash, err := psbt.SigHash(index, nonceHashType)
+		if err != nil {
+			return nonces, signed, err
+		}
+		partialSig, err := chain.Wallet.MuSigPartialSign(keyPair, input.MuSigKeys, pubNonces, sigHash)
+		if err != nil {
+			return nonces, signed, err
+		}
+		if err = psbt.AddMuSigPartialSig(index, pubk, partialSig); err != nil {
+			return nonces, signed, err
+		}
+		signed++
+	}
+	return nonces, signed, psbt.CombineMuSig(index)
+}
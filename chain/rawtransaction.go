@@ -0,0 +1,264 @@
+package chain
+
+import (
+	"XianfengChain04/transaction"
+	"XianfengChain04/wallet"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+/**
+ *原始交易的交易输入参数
+ */
+type RawTxInput struct {
+	TxId     string  `json:"txid"`     //所花费utxo所在交易的哈希
+	Vout     int     `json:"vout"`     //所花费utxo在交易中的下标
+	Sequence *uint32 `json:"sequence"` //输入的sequence，为空时使用默认值
+}
+
+/**
+ *原始交易的交易输出参数，Address和Data只能设置其中一个
+ */
+type RawTxOutput struct {
+	Address string  `json:"address"` //接收地址
+	Amount  float64 `json:"amount"`  //转账金额
+	Data    string  `json:"data"`    //十六进制编码的数据，设置时生成携带数据的输出
+}
+
+/**
+ *离线签名时由调用者提供的所花费utxo的信息
+ */
+type PrevTx struct {
+	TxId         string  `json:"txid"`         //utxo所在交易的哈希
+	Vout         int     `json:"vout"`         //utxo在交易中的下标
+	ScriptPubKey string  `json:"scriptpubkey"` //十六进制编码的锁定脚本
+	Amount       float64 `json:"amount"`       //utxo的金额
+	RedeemScript string  `json:"redeemscript"` //花费P2SH输出时所需的十六进制编码的赎回脚本
+}
+
+/**
+ *列出某个地址可以花费的utxo
+ */
+func (chain *BlockChain) ListUnspent(address string) ([]transaction.UTXO, error) {
+	if !chain.Wallet.CheckAddress(address) {
+		return nil, errors.New("地址不符合规范，请检查后重试")
+	}
+	utxos, _ := chain.GetUTXOsWithBalance(address, nil)
+	return utxos, nil
+}
+
+/**
+ *使用指定的交易输入和交易输出构建一笔未签名的原始交易
+ */
+func (chain *BlockChain) CreateRawTransaction(inputs []RawTxInput, outputs []RawTxOutput, lockTime int64) (*transaction.Transaction, error) {
+	txInputs := make([]transaction.TxInput, 0, len(inputs))
+	for _, rawInput := range inputs {
+		txId, err := decodeTxId(rawInput.TxId)
+		if err != nil {
+			return nil, err
+		}
+		if rawInput.Vout < 0 {
+			return nil, errors.New("交易输入的vout不能为负数")
+		}
+		input := transaction.NewTxInput(txId, rawInput.Vout, nil)
+		if rawInput.Sequence != nil {
+			input.Sequence = *rawInput.Sequence
+		}
+		txInputs = append(txInputs, input)
+	}
+	txOutputs := make([]transaction.TxOutPut, 0, len(outputs))
+	for _, rawOutput := range outputs {
+		if len(rawOutput.Data) > 0 {
+			if len(rawOutput.Address) > 0 || rawOutput.Amount != 0 {
+				return nil, errors.New("携带数据的输出不能同时设置地址和金额")
+			}
+			data, err := hex.DecodeString(rawOutput.Data)
+			if err != nil {
+				return nil, errors.New("输出携带的数据不是合法的十六进制编码")
+			}
+			output, err := transaction.NewDataOutput(data)
+			if err != nil {
+				return nil, err
+			}
+			txOutputs = append(txOutputs, output)
+			continue
+		}
+		if !chain.Wallet.CheckAddress(rawOutput.Address) {
+			return nil, errors.New("地址" + rawOutput.Address + "不符合规范，请检查后重试")
+		}
+		if rawOutput.Amount <= 0 {
+			return nil, errors.New("交易输出的金额必须大于0")
+		}
+		txOutputs = append(txOutputs, transaction.LockMoney2PubkHash(rawOutput.Amount, rawOutput.Address))
+	}
+	return transaction.NewRawTransaction(txInputs, txOutputs, lockTime)
+}
+
+/**
+ *把十六进制编码的序列化数据解码为交易
+ */
+func (chain *BlockChain) DecodeRawTransaction(txHex string) (*transaction.Transaction, error) {
+	data, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, errors.New("交易数据不是合法的十六进制编码")
+	}
+	return transaction.DeserializeTransaction(data)
+}
+
+/**
+ *把交易序列化并编码为十六进制
+ */
+func (chain *BlockChain) EncodeRawTransaction(tx *transaction.Transaction) (string, error) {
+	data, err := tx.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+/**
+ *使用本地钱包中的私钥对原始交易进行签名，本地钱包无法签名的输入保持不变
+ *prevTxs可以提供本地链上查询不到的utxo信息，hashType为签名哈希类型，返回交易是否已经完成全部签名
+ */
+func (chain *BlockChain) SignRawTransactionWithWallet(tx *transaction.Transaction, prevTxs []PrevTx, hashType byte) (bool, error) {
+	return chain.signRawTransaction(tx, &chain.Wallet, prevTxs, hashType)
+}
+
+/**
+ *使用给定的十六进制私钥对原始交易进行签名，不依赖本地钱包，可以在离线的机器上完成签名
+ *curveName为私钥所使用的曲线，花费P2SH输出时需要在prevTxs中提供赎回脚本
+ */
+func (chain *BlockChain) SignRawTransactionWithKey(tx *transaction.Transaction, privKeys []string, curveName string, prevTxs []PrevTx, hashType byte) (bool, error) {
+	curve, err := wallet.GetCurveByName(curveName)
+	if err != nil {
+		return false, err
+	}
+	keyWallet := &wallet.Wallet{
+		Address:       make(map[string]*wallet.KeyPair),
+		RedeemScripts: make(map[string][]byte),
+	}
+	for _, privKey := range privKeys {
+		d, err := hex.DecodeString(privKey)
+		if err != nil {
+			return false, errors.New("私钥不是合法的十六进制编码")
+		}
+		keyPair, err := wallet.NewKeyPairFromPrivKey(curve, d)
+		if err != nil {
+			return false, err
+		}
+		keyWallet.Address[keyWallet.GetAddressByPubk(keyPair.Pub)] = keyPair
+	}
+	for _, prevTx := range prevTxs {
+		if len(prevTx.RedeemScript) == 0 {
+			continue
+		}
+		redeemScript, err := hex.DecodeString(prevTx.RedeemScript)
+		if err != nil {
+			return false, errors.New("赎回脚本不是合法的十六进制编码")
+		}
+		keyWallet.RedeemScripts[keyWallet.GetAddressByRedeemScript(redeemScript)] = redeemScript
+	}
+	return chain.signRawTransaction(tx, keyWallet, prevTxs, hashType)
+}
+
+/**
+ *把已完成签名的原始交易打包进新区块，返回交易哈希
+ */
+func (chain *BlockChain) SendRawTransaction(tx *transaction.Transaction) ([32]byte, error) {
+	if tx.IsCoinbase() {
+		return [32]byte{}, errors.New("不能发送coinbase交易")
+	}
+	err := chain.packTransactions([]transaction.Transaction{*tx})
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return tx.TxHash, nil
+}
+
+/**
+ *使用signWallet中的秘钥按照hashType对原始交易的每个输入进行签名，多重签名的输入合并已有的签名
+ */
+func (chain *BlockChain) signRawTransaction(tx *transaction.Transaction, signWallet *wallet.Wallet, prevTxs []PrevTx, hashType byte) (bool, error) {
+	if err := signWallet.CheckUnlocked(); err != nil {
+		return false, err
+	}
+	utxos, err := chain.getRawTxUTXOs(tx, prevTxs)
+	if err != nil {
+		return false, err
+	}
+	for index := range tx.Inputs {
+		address := signWallet.GetAddressByPubkHash(utxos[index].PubkHash)
+		signers, err := signWallet.GetSignersByAddress(address)
+		if err != nil {
+			//钱包中没有该输入的私钥，留给其他参与方签名
+			continue
+		}
+		if redeemScript, ok := signWallet.GetRedeemScript(address); ok && len(tx.Inputs[index].ScriptSig) == 0 {
+			if err = tx.SetRedeemScript(index, redeemScript); err != nil {
+				return false, err
+			}
+		}
+		for _, signer := range signers {
+			err = tx.SignInput(index, signer, utxos, hashType)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	complete, _ := tx.VerifyTx(utxos)
+	return complete, nil
+}
+
+/**
+ *获取原始交易每个输入所花费的utxo，优先使用prevTxs中提供的信息，其次到本地链上和交易池中查询
+ */
+func (chain *BlockChain) getRawTxUTXOs(tx *transaction.Transaction, prevTxs []PrevTx) ([]transaction.UTXO, error) {
+	provided := make([]transaction.UTXO, 0, len(prevTxs))
+	for _, prevTx := range prevTxs {
+		txId, err := decodeTxId(prevTx.TxId)
+		if err != nil {
+			return nil, err
+		}
+		scriptPub, err := hex.DecodeString(prevTx.ScriptPubKey)
+		if err != nil {
+			return nil, errors.New("锁定脚本不是合法的十六进制编码")
+		}
+		output, err := transaction.NewTxOutPutWithScript(prevTx.Amount, scriptPub)
+		if err != nil {
+			return nil, err
+		}
+		provided = append(provided, transaction.NewUTXO(txId, prevTx.Vout, output))
+	}
+	//交易池中未确认交易的输出也可以被花费
+	found := append(provided, chain.FindSpentUTXOsByTx(*tx, chain.memPoolTxs())...)
+
+	utxos := make([]transaction.UTXO, 0, len(tx.Inputs))
+	for index, input := range tx.Inputs {
+		var spent *transaction.UTXO
+		for i := range found {
+			if found[i].IsUTXOSpend(input) {
+				spent = &found[i]
+				break
+			}
+		}
+		if spent == nil {
+			return nil, errors.New("未找到第" + strconv.Itoa(index) + "个交易输入所花费的utxo，请通过prevtxs提供")
+		}
+		utxos = append(utxos, *spent)
+	}
+	return utxos, nil
+}
+
+/**
+ *解码十六进制的交易哈希
+ */
+func decodeTxId(txIdHex string) ([32]byte, error) {
+	var txId [32]byte
+	data, err := hex.DecodeString(txIdHex)
+	if err != nil || len(data) != len(txId) {
+		return txId, errors.New("交易哈希" + txIdHex + "格式不正确")
+	}
+	copy(txId[:], data)
+	return txId, nil
+}
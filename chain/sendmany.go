@@ -0,0 +1,111 @@
+package chain
+
+import (
+	"XianfengChain04/transaction"
+	"XianfengChain04/wallet"
+	"errors"
+	"sort"
+)
+
+/**
+ *批量转账：使用froms中的资金在一笔交易中向payments里的每个地址转账，剩余资金合并为一个找零输出
+ *change为找零地址，为空时找零给一个新的找零地址，selector为选币策略，为空时使用默认策略，
+ *feeRate为手续费率（每字节），为0时使用估算的手续费率，memPoolOnly为true时交易只提交到交易池，返回交易哈希
+ */
+func (chain *BlockChain) SendMany(froms []string, payments map[string]float64, change string, selector transaction.CoinSelector, feeRate float64, memPoolOnly bool) ([32]byte, error) {
+	if len(froms) == 0 || len(payments) == 0 {
+		return [32]byte{}, errors.New("批量转账至少需要一个付款地址和一个收款地址")
+	}
+	if len(change) != 0 && !chain.Wallet.CheckAddress(change) {
+		return [32]byte{}, errors.New("找零地址不符合规范，请检查后重试")
+	}
+	if selector == nil {
+		selector = transaction.DefaultCoinSelector()
+	}
+	feeRate, err := chain.resolveFeeRate(feeRate)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	//按地址排序，使相同的参数总是构建出相同的交易输出
+	tos := make([]string, 0, len(payments))
+	for to := range payments {
+		if !chain.Wallet.CheckAddress(to) {
+			return [32]byte{}, errors.New("地址" + to + "不符合规范，请检查后重试")
+		}
+		tos = append(tos, to)
+	}
+	sort.Strings(tos)
+	amounts := make([]float64, 0, len(tos))
+	var total float64
+	for _, to := range tos {
+		if payments[to] <= 0 {
+			return [32]byte{}, errors.New("转账金额必须大于0")
+		}
+		amounts = append(amounts, payments[to])
+		total += payments[to]
+	}
+
+	//汇总所有付款地址可花费的utxo，付款地址必须由本地钱包持有
+	utxos := make([]transaction.UTXO, 0)
+	var balance float64
+	visited := make(map[string]bool)
+	memTxs := chain.memPoolTxs()
+	for _, from := range froms {
+		if visited[from] {
+			continue
+		}
+		visited[from] = true
+		if !chain.Wallet.CheckAddress(from) {
+			return [32]byte{}, errors.New("地址" + from + "不符合规范，请检查后重试")
+		}
+		if _, err := chain.Wallet.GetSignersByAddress(from); err != nil {
+			return [32]byte{}, errors.New("当前钱包无法花费" + from + "的资金")
+		}
+		fromUTXOs, fromBalance := chain.GetUTXOsWithBalance(from, memTxs)
+		utxos = append(utxos, fromUTXOs...)
+		balance += fromBalance
+	}
+	if balance < total-transaction.AMOUNTPRECISION {
+		return [32]byte{}, errors.New("付款地址的余额不足，赶紧去搬砖挣钱")
+	}
+	//没有指定找零地址时，找零转入一个与第一个付款地址使用相同曲线的新找零地址
+	var changeKey *wallet.KeyPair
+	if len(change) == 0 {
+		if change, changeKey, err = chain.reserveChangeAddress(froms[0]); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	newTx, _, err := chain.fundTransaction(utxos, total, feeRate, selector,
+		func(selected []transaction.UTXO, fee float64) (*transaction.Transaction, error) {
+			newTx, err := transaction.CreateBatchTransaction(selected, tos, amounts, change, fee)
+			if err != nil {
+				return nil, err
+			}
+			if err = newTx.SignalReplacement(); err != nil {
+				return nil, err
+			}
+			//不同付款地址的交易输入分别使用各自的秘钥签名
+			complete, err := chain.signRawTransaction(newTx, &chain.Wallet, nil, transaction.SIGHASH_ALL)
+			if err != nil {
+				return nil, err
+			}
+			if !complete {
+				return nil, errors.New("当前钱包无法完成交易的全部签名")
+			}
+			return newTx, nil
+		})
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if changeKey != nil {
+		if err = chain.keepChangeAddress(newTx, change, changeKey); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	err = chain.submitWalletTransactions([]transaction.Transaction{*newTx}, memPoolOnly)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return newTx.TxHash, nil
+}
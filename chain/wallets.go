@@ -0,0 +1,55 @@
+package chain
+
+import (
+	"XianfengChain04/wallet"
+	"XianfengChain04/wallettx"
+)
+
+/**
+ *切换当前使用的钱包，之后的钱包操作都作用于该钱包，名称为空时使用默认钱包
+ */
+func (chain *BlockChain) UseWallet(name string) error {
+	w, err := wallet.OpenWallet(chain.DB, name)
+	if err != nil {
+		return err
+	}
+	chain.Wallet = *w
+	chain.WalletTxs = wallettx.NewTxStore(chain.DB, name)
+	return nil
+}
+
+/**
+ *创建一个新的钱包，新钱包没有历史交易，交易记录直接从当前的区块高度开始同步
+ */
+func (chain *BlockChain) CreateWallet(name string) error {
+	if _, err := wallet.CreateWallet(chain.DB, name); err != nil {
+		return err
+	}
+	if len(chain.LastBlock.Transactions) == 0 {
+		return nil
+	}
+	store := wallettx.NewTxStore(chain.DB, name)
+	return store.AddBlockTxs(nil, chain.LastBlock.Height)
+}
+
+/**
+ *加载一个已经存在的钱包，钱包卸载期间产生的交易在下次查询钱包时重新扫描得到
+ */
+func (chain *BlockChain) LoadWallet(name string) error {
+	_, err := wallet.LoadWallet(chain.DB, name)
+	return err
+}
+
+/**
+ *卸载一个钱包，卸载后的钱包不再记录新区块中的交易，也不能在命令中使用
+ */
+func (chain *BlockChain) UnloadWallet(name string) error {
+	return wallet.UnloadWallet(chain.DB, name)
+}
+
+/**
+ *列出所有已经加载的钱包名称
+ */
+func (chain *BlockChain) ListWallets() ([]string, error) {
+	return wallet.ListWallets(chain.DB)
+}
@@ -0,0 +1,310 @@
+package chain
+
+import (
+	"XianfengChain04/transaction"
+	"XianfengChain04/wallet"
+	"XianfengChain04/wallettx"
+	"fmt"
+	"time"
+)
+
+/**
+ *钱包的余额：已确认、未确认（交易池中）、未成熟的coinbase奖励，以及观察地址的余额
+ */
+type WalletBalance struct {
+	Confirmed   float64
+	Unconfirmed float64
+	Immature    float64
+	WatchOnly   float64
+}
+
+/**
+ *钱包的概况
+ */
+type WalletInfo struct {
+	WalletBalance
+	TxCount        int   //钱包交易的个数，包含交易池中的交易
+	AddressCount   int   //钱包持有私钥的地址个数
+	WatchOnlyCount int   //观察地址的个数
+	HDEnabled      bool  //是否已经设置了HD种子
+	Encrypted      bool  //钱包是否已经加密
+	Locked         bool  //钱包是否处于锁定状态
+	SyncHeight     int64 //钱包交易记录已经同步到的区块高度
+}
+
+/**
+ *区块被添加到区块链上之后，为每个已经加载的钱包记录区块中与其相关的交易，spent[i]为第i笔交易所花费的utxo
+ */
+func (chain *BlockChain) recordWalletTxs(txs []transaction.Transaction, spent [][]transaction.UTXO) error {
+	names, err := wallet.ListWallets(chain.DB)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == chain.Wallet.Name {
+			err = chain.recordTxsToWallet(&chain.Wallet, &chain.WalletTxs, txs, spent)
+		} else {
+			err = chain.recordTxsToLoadedWallet(name, txs, spent)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ *为当前命令没有使用的已加载钱包记录区块中的交易
+ */
+func (chain *BlockChain) recordTxsToLoadedWallet(name string, txs []transaction.Transaction, spent [][]transaction.UTXO) error {
+	loaded, err := wallet.OpenWallet(chain.DB, name)
+	if err != nil {
+		return err
+	}
+	store := wallettx.NewTxStore(chain.DB, name)
+	return chain.recordTxsToWallet(loaded, &store, txs, spent)
+}
+
+/**
+ *记录区块中与钱包相关的交易，钱包交易记录没有同步到上一个区块时，重新扫描整条区块链
+ */
+func (chain *BlockChain) recordTxsToWallet(w *wallet.Wallet, store *wallettx.TxStore, txs []transaction.Transaction, spent [][]transaction.UTXO) error {
+	syncHeight, err := store.GetSyncHeight()
+	if err != nil {
+		return err
+	}
+	if syncHeight == chain.LastBlock.Height {
+		return nil
+	}
+	if syncHeight != chain.LastBlock.Height-1 {
+		return chain.rescanWallet(w, store)
+	}
+	walletTxs := make([]wallettx.WalletTx, 0)
+	for index, tx := range txs {
+		walletTx, ok := wallettx.NewWalletTx(w, tx, spent[index], chain.LastBlock.Height, chain.LastBlock.TimeStamp)
+		if ok {
+			walletTxs = append(walletTxs, walletTx)
+		}
+	}
+	return store.AddBlockTxs(walletTxs, chain.LastBlock.Height)
+}
+
+/**
+ *从创世区块开始重新扫描区块链，重建钱包交易记录，导入私钥、观察地址或者恢复钱包之后使用
+ */
+func (chain *BlockChain) RescanWallet() error {
+	return chain.rescanWallet(&chain.Wallet, &chain.WalletTxs)
+}
+
+/**
+ *从创世区块开始重新扫描区块链，重建指定钱包的交易记录
+ */
+func (chain *BlockChain) rescanWallet(w *wallet.Wallet, store *wallettx.TxStore) error {
+	blocks, err := chain.GetAllBlocks()
+	if err != nil {
+		return err
+	}
+	if err = store.Clear(); err != nil {
+		return err
+	}
+	//扫描过程中记录所有未花费的交易输出，用于确定交易输入所花费的金额和地址
+	outputs := make(map[string]transaction.UTXO)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		walletTxs := make([]wallettx.WalletTx, 0)
+		for _, tx := range block.Transactions {
+			spent := make([]transaction.UTXO, 0, len(tx.Inputs))
+			if !tx.IsCoinbase() {
+				for _, input := range tx.Inputs {
+					outpoint := fmt.Sprintf("%x:%d", input.TxId, input.Vout)
+					spent = append(spent, outputs[outpoint])
+					delete(outputs, outpoint)
+				}
+			}
+			for vout, output := range tx.Outputs {
+				outputs[fmt.Sprintf("%x:%d", tx.TxHash, vout)] = transaction.NewUTXO(tx.TxHash, vout, output)
+			}
+			walletTx, ok := wallettx.NewWalletTx(w, tx, spent, block.Height, block.TimeStamp)
+			if ok {
+				walletTxs = append(walletTxs, walletTx)
+			}
+		}
+		if err = store.AddBlockTxs(walletTxs, block.Height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ *钱包交易记录落后于区块链时重新扫描，例如在该功能加入之前已经存在的钱包
+ */
+func (chain *BlockChain) syncWalletTxs() error {
+	if len(chain.LastBlock.Transactions) == 0 {
+		return nil
+	}
+	syncHeight, err := chain.WalletTxs.GetSyncHeight()
+	if err != nil {
+		return err
+	}
+	if syncHeight == chain.LastBlock.Height {
+		return nil
+	}
+	return chain.RescanWallet()
+}
+
+/**
+ *列出钱包的交易，包含交易池中的未确认交易，按时间从旧到新排列，返回跳过最新的skip笔之后的最多count笔交易
+ */
+func (chain *BlockChain) ListTransactions(count int, skip int) ([]wallettx.WalletTx, error) {
+	if err := chain.syncWalletTxs(); err != nil {
+		return nil, err
+	}
+	walletTxs, err := chain.WalletTxs.GetTxs()
+	if err != nil {
+		return nil, err
+	}
+	walletTxs = append(walletTxs, chain.memPoolWalletTxs()...)
+	end := len(walletTxs) - skip
+	if end < 0 {
+		end = 0
+	}
+	start := end - count
+	if start < 0 {
+		start = 0
+	}
+	return walletTxs[start:end], nil
+}
+
+/**
+ *交易池中与钱包相关的未确认交易
+ */
+func (chain *BlockChain) memPoolWalletTxs() []wallettx.WalletTx {
+	walletTxs := make([]wallettx.WalletTx, 0)
+	entries, err := chain.MemPool.GetEntries()
+	if err != nil {
+		return walletTxs
+	}
+	memTxs := chain.memPoolTxs()
+	for _, entry := range entries {
+		spent := chain.FindSpentUTXOsByTx(entry.Tx, memTxs)
+		//交易池记录的是纳秒时间，钱包交易与区块一样使用秒
+		walletTx, ok := wallettx.NewWalletTx(&chain.Wallet, entry.Tx, spent, -1, entry.Time/int64(time.Second))
+		if ok {
+			walletTxs = append(walletTxs, walletTx)
+		}
+	}
+	wallettx.SortWalletTxs(walletTxs)
+	return walletTxs
+}
+
+/**
+ *统计钱包所有地址的余额，被交易池中的交易花费的utxo不计入余额
+ */
+func (chain *BlockChain) GetWalletBalance() (WalletBalance, error) {
+	var balance WalletBalance
+	if err := chain.syncWalletTxs(); err != nil {
+		return balance, err
+	}
+	memTxs := chain.memPoolTxs()
+	memSpent := make(map[string]bool)
+	for _, tx := range memTxs {
+		for _, input := range tx.Inputs {
+			memSpent[fmt.Sprintf("%x:%d", input.TxId, input.Vout)] = true
+		}
+	}
+
+	for _, address := range chain.walletAddresses() {
+		watchOnly := !chain.Wallet.IsMine(address)
+		utxos, err := chain.UTXOSet.QueryUTXOsByAddress(address)
+		if err != nil {
+			return balance, err
+		}
+		for _, utxo := range utxos {
+			if memSpent[fmt.Sprintf("%x:%d", utxo.TxId, utxo.Vout)] {
+				continue
+			}
+			if watchOnly {
+				balance.WatchOnly += utxo.Value
+				continue
+			}
+			walletTx, err := chain.WalletTxs.GetTx(utxo.TxId)
+			if err != nil {
+				return balance, err
+			}
+			if walletTx != nil && walletTx.IsImmature(chain.LastBlock.Height) {
+				balance.Immature += utxo.Value
+				continue
+			}
+			balance.Confirmed += utxo.Value
+		}
+	}
+
+	//交易池中转入钱包地址并且没有被再次花费的输出计入未确认余额
+	for _, tx := range memTxs {
+		for vout, output := range tx.Outputs {
+			if len(output.PubkHash) == 0 || memSpent[fmt.Sprintf("%x:%d", tx.TxHash, vout)] {
+				continue
+			}
+			if chain.Wallet.IsMine(chain.Wallet.GetAddressByPubkHash(output.PubkHash)) {
+				balance.Unconfirmed += output.Value
+			}
+		}
+	}
+	return balance, nil
+}
+
+/**
+ *获取钱包的概况
+ */
+func (chain *BlockChain) GetWalletInfo() (WalletInfo, error) {
+	var info WalletInfo
+	balance, err := chain.GetWalletBalance()
+	if err != nil {
+		return info, err
+	}
+	walletTxs, err := chain.WalletTxs.GetTxs()
+	if err != nil {
+		return info, err
+	}
+	syncHeight, err := chain.WalletTxs.GetSyncHeight()
+	if err != nil {
+		return info, err
+	}
+	info.WalletBalance = balance
+	info.TxCount = len(walletTxs) + len(chain.memPoolWalletTxs())
+	info.AddressCount = len(chain.Wallet.Address)
+	info.WatchOnlyCount = len(chain.Wallet.WatchOnly)
+	info.HDEnabled = chain.Wallet.HDChain != nil
+	info.Encrypted = chain.Wallet.IsEncrypted()
+	info.Locked = chain.Wallet.IsLocked()
+	info.SyncHeight = syncHeight
+	return info, nil
+}
+
+/**
+ *钱包可以花费以及正在观察的所有地址
+ */
+func (chain *BlockChain) walletAddresses() []string {
+	visited := make(map[string]bool)
+	addresses := make([]string, 0)
+	add := func(address string) {
+		if !visited[address] && (chain.Wallet.IsMine(address) || chain.Wallet.IsWatchOnly(address)) {
+			visited[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	for address := range chain.Wallet.Address {
+		add(address)
+	}
+	for address := range chain.Wallet.MuSigKeys {
+		add(address)
+	}
+	for address := range chain.Wallet.RedeemScripts {
+		add(address)
+	}
+	for address := range chain.Wallet.WatchOnly {
+		add(address)
+	}
+	return addresses
+}
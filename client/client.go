@@ -2,11 +2,24 @@ package client
 
 import (
 	"XianfengChain04/chain"
+	"XianfengChain04/script"
+	"XianfengChain04/transaction"
 	"XianfengChain04/utils"
+	"XianfengChain04/wallet"
+	"XianfengChain04/wallettx"
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math/big"
 	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 /**
@@ -16,48 +29,1038 @@ type CmdClient struct {
     Chain chain.BlockChain
 }
 
+var stdinReader = bufio.NewReader(os.Stdin)//读取标准输入中的钱包密码
+
+/**
+ *该方法用于获取当前节点已经生成的地址列表
+ */
+func (cmd *CmdClient) ListAddress() {
+	listAddress := flag.NewFlagSet(LISTADDRESS, flag.ExitOnError)
+	listAddress.Parse(os.Args[2:])
+    if len(os.Args[2:])	> 0 {
+    	fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	addList, err := cmd.Chain.GetAddressList()
+    if err != nil {
+    	fmt.Println(err.Error())
+		return
+	}
+	watchOnly := cmd.Chain.Wallet.GetWatchOnlyAddresses()
+	//如果本地节点暂时还没有地址，需要给用户提示
+	if len(addList) == 0 && len(watchOnly) == 0 {
+		fmt.Println("暂无地址，可以使用go run main.go getnewaddress命令生成新地址")
+		return
+	}
+	fmt.Println("获取地址列表成功，地址信息如下：")
+	for index, add := range addList {
+		//HD地址同时显示其派生路径
+		keyPair := cmd.Chain.Wallet.GetKeyPairByAddress(add)
+		if keyPair != nil && len(keyPair.HDPath) > 0 {
+			fmt.Printf("[%d]:%s %s%s\n", index + 1, add, wallet.FormatHDPath(keyPair.HDPath), cmd.formatAddressMeta(add))
+			continue
+		}
+		fmt.Printf("[%d]:%s%s\n", index + 1, add, cmd.formatAddressMeta(add))
+	}
+	//观察地址没有私钥，同时显示余额以便监控
+	for index, add := range watchOnly {
+		balance, _ := cmd.Chain.GetBalance(add)
+		fmt.Printf("[%d]:%s watch-only 余额：%f%s\n", len(addList) + index + 1, add, balance, cmd.formatAddressMeta(add))
+	}
+}
+
+/**
+ *地址列表中附加显示的找零标记和标签
+ */
+func (cmd *CmdClient) formatAddressMeta(address string) string {
+	meta := cmd.Chain.Wallet.GetAddressMeta(address)
+	text := ""
+	if meta.Purpose == wallet.PURPOSECHANGE {
+		text += " change"
+	}
+	if len(meta.Label) > 0 {
+		text += " 标签：" + meta.Label
+	}
+	return text
+}
+
+/**
+ *为地址设置标签
+ */
+func (cmd *CmdClient) SetLabel() {
+	setLabel := flag.NewFlagSet(SETLABEL, flag.ExitOnError)
+	address := setLabel.String("address", "", "要设置标签的地址")
+	label := setLabel.String("label", "", "地址的标签，为空时清除标签")
+	setLabel.Parse(os.Args[2:])
+	if len(setLabel.Args()) > 0 {
+		fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	if err := cmd.Chain.SetLabel(*address, *label); err != nil {
+		fmt.Println("设置地址标签时遇到错误：", err.Error())
+		return
+	}
+	if len(*label) == 0 {
+		fmt.Println("已清除地址的标签")
+		return
+	}
+	fmt.Printf("地址%s的标签已设置为：%s\n", *address, *label)
+}
+
+/**
+ *列出使用某个标签的所有地址
+ */
+func (cmd *CmdClient) GetAddressesByLabel() {
+	getAddressesByLabel := flag.NewFlagSet(GETADDRESSESBYLABEL, flag.ExitOnError)
+	label := getAddressesByLabel.String("label", "", "要查询的标签")
+	getAddressesByLabel.Parse(os.Args[2:])
+	if len(getAddressesByLabel.Args()) > 0 {
+		fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	addresses := cmd.Chain.GetAddressesByLabel(*label)
+	if len(addresses) == 0 {
+		fmt.Println("没有使用该标签的地址")
+		return
+	}
+	for index, address := range addresses {
+		fmt.Printf("[%d]:%s %s\n", index + 1, address, cmd.Chain.Wallet.GetAddressMeta(address).Purpose)
+	}
+}
+
+/**
+ *查看地址的标签、用途、创建时间等信息
+ */
+func (cmd *CmdClient) GetAddressInfo() {
+	getAddressInfo := flag.NewFlagSet(GETADDRESSINFO, flag.ExitOnError)
+	address := getAddressInfo.String("address", "", "要查询的地址")
+	getAddressInfo.Parse(os.Args[2:])
+	if len(getAddressInfo.Args()) > 0 {
+		fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	info, balance, err := cmd.Chain.GetAddressInfo(*address)
+	if err != nil {
+		fmt.Println("查询地址信息时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("地址：", info.Address)
+	fmt.Println("标签：", info.Label)
+	fmt.Println("用途：", info.Purpose)
+	fmt.Printf("可花费：%t 观察地址：%t 脚本地址：%t 找零地址：%t\n", info.IsMine, info.IsWatchOnly, info.IsScript, info.IsChange)
+	if len(info.PubKey) > 0 {
+		curve, err := wallet.GetCurve(info.KeyType)
+		if err == nil {
+			fmt.Println("曲线：", curve.Name())
+		}
+		fmt.Println("公钥：", info.PubKey)
+		fmt.Println("私钥已加密：", info.Encrypted)
+	}
+	if len(info.HDPath) > 0 {
+		fmt.Println("派生路径：", info.HDPath)
+	}
+	if info.CreateTime > 0 {
+		fmt.Println("创建时间：", time.Unix(info.CreateTime, 0).Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Println("创建时间：未知")
+	}
+	fmt.Printf("余额：%f\n", balance)
+}
+
+/**
+ *定义新的方法，用于生成新的地址
+ */
+func (cmd *CmdClient) GetNewAddress() {
+	getNewAddress := flag.NewFlagSet(GETNEWADDRESS, flag.ExitOnError)
+	curve := getNewAddress.String("curve", "p256", "地址所使用的曲线，可选p256、secp256k1或schnorr")
+	label := getNewAddress.String("label", "", "新地址的标签")
+	getNewAddress.Parse(os.Args[2:])
+	if len(getNewAddress.Args()) > 0 {
+		fmt.Println("抱歉，生成新地址功能无法解析参数，请重试")
+		return
+	}
+	address, err := cmd.Chain.GetNewAddressWithCurve(*curve)
+	if err != nil {
+		fmt.Println("生成新地址时遇到错误，请重试", err.Error())
+		return
+	}
+	if len(*label) > 0 {
+		if err = cmd.Chain.SetLabel(address, *label); err != nil {
+			fmt.Println("设置地址标签时遇到错误：", err.Error())
+		}
+	}
+	fmt.Println("生成新的地址：", address)
+}
+
+/**
+ *使用钱包中的schnorr地址和其他参与方的schnorr公钥生成一个MuSig聚合地址
+ */
+func (cmd *CmdClient) CreateMuSigAddress() {
+	createMuSigAddress := flag.NewFlagSet(CREATEMUSIGADDRESS, flag.ExitOnError)
+	addresses := createMuSigAddress.String("addresses", "", "参与聚合的本地schnorr地址，json数组格式")
+	pubkeys := createMuSigAddress.String("pubkeys", "[]", "其他参与方的十六进制schnorr公钥，json数组格式")
+	createMuSigAddress.Parse(os.Args[2:])
+
+	addressSlice, err := utils.JSONArray2String(*addresses)
+	if err != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	pubkeySlice, err := utils.JSONArray2String(*pubkeys)
+	if err != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	address, err := cmd.Chain.CreateMuSigAddress(addressSlice, pubkeySlice)
+	if err != nil {
+		fmt.Println("生成MuSig聚合地址时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("生成MuSig聚合地址：", address)
+}
+
+/**
+ *使用多个公钥生成一个M-of-N的多重签名地址
+ */
+func (cmd *CmdClient) CreateMultiSig() {
+	createMultiSig := flag.NewFlagSet(CREATEMULTISIG, flag.ExitOnError)
+	required := createMultiSig.Int("m", 0, "花费资金所需的签名数量")
+	pubkeys := createMultiSig.String("pubkeys", "", "参与方的十六进制公钥或钱包中的地址，json数组格式")
+	p2sh := createMultiSig.Bool("p2sh", false, "是否生成P2SH包装的多重签名地址")
+	createMultiSig.Parse(os.Args[2:])
+
+	keySlice, err := utils.JSONArray2String(*pubkeys)
+	if err != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	address, err := cmd.Chain.CreateMultiSigAddress(*required, keySlice, *p2sh)
+	if err != nil {
+		fmt.Println("生成多重签名地址时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("生成%d-of-%d多重签名地址：%s\n", *required, len(keySlice), address)
+	if redeemScript, ok := cmd.Chain.Wallet.GetRedeemScript(address); ok {
+		fmt.Printf("赎回脚本：%x\n", redeemScript)
+	}
+}
+
+/**
+ *导入一个赎回脚本，钱包保存赎回脚本后即可花费对应P2SH地址的资金
+ */
+func (cmd *CmdClient) AddRedeemScript() {
+	addRedeemScript := flag.NewFlagSet(ADDREDEEMSCRIPT, flag.ExitOnError)
+	redeemScript := addRedeemScript.String("script", "", "十六进制编码的赎回脚本")
+	addRedeemScript.Parse(os.Args[2:])
+	address, err := cmd.Chain.AddRedeemScript(*redeemScript)
+	if err != nil {
+		fmt.Println("导入赎回脚本时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("赎回脚本对应的P2SH地址：", address)
+}
+
+/**
+ *使用普通地址生成带时间锁的P2SH地址，-locktime设置绝对时间锁，-sequence设置相对时间锁的区块个数
+ */
+func (cmd *CmdClient) CreateTimeLockAddress() {
+	createTimeLock := flag.NewFlagSet(CREATETIMELOCKADDRESS, flag.ExitOnError)
+	address := createTimeLock.String("address", "", "时间锁到期后可以花费资金的地址")
+	lockTime := createTimeLock.Int64("locktime", 0, "绝对时间锁，小于500000000为区块高度，否则为unix时间戳")
+	sequence := createTimeLock.Int64("sequence", 0, "相对时间锁，资金确认后需要等待的区块个数")
+	createTimeLock.Parse(os.Args[2:])
+	timeLockAddress, err := cmd.Chain.CreateTimeLockAddress(*address, *lockTime, *sequence)
+	if err != nil {
+		fmt.Println("生成时间锁地址时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("时间锁地址：", timeLockAddress)
+	if redeemScript, ok := cmd.Chain.Wallet.GetRedeemScript(timeLockAddress); ok {
+		fmt.Println("赎回脚本：", script.Disasm(redeemScript))
+	}
+}
+
+/**
+ *创建原子交换合约：不指定-secrethash时作为发起方生成秘密值，指定时作为参与方使用对方合约的秘密值哈希
+ */
+func (cmd *CmdClient) InitiateSwap() {
+	initiateSwap := flag.NewFlagSet(INITIATESWAP, flag.ExitOnError)
+	from := initiateSwap.String("from", "", "转入合约资金的地址，也是合约到期后的退款地址")
+	to := initiateSwap.String("to", "", "凭秘密值领取合约资金的对方地址")
+	amount := initiateSwap.Float64("amount", 0, "合约金额")
+	secretHash := initiateSwap.String("secrethash", "", "对方合约中的秘密值哈希，参与方创建合约时使用")
+	lockTime := initiateSwap.Int64("locktime", 0, "合约的退款锁定时间，默认发起方48小时、参与方24小时以后")
+	initiateSwap.Parse(os.Args[2:])
+	var hashBytes []byte
+	if len(*secretHash) > 0 {
+		var err error
+		hashBytes, err = hex.DecodeString(*secretHash)
+		if err != nil || len(hashBytes) != script.SECRETHASHLEN {
+			fmt.Println("秘密值哈希格式不正确，请检查后重试")
+			return
+		}
+	}
+	swap, err := cmd.Chain.InitiateSwap(*from, *to, *amount, hashBytes, *lockTime)
+	if err != nil {
+		fmt.Println("创建原子交换合约时遇到错误：", err.Error())
+		return
+	}
+	if swap.Secret != nil {
+		fmt.Printf("秘密值（请妥善保管，领取对方合约资金时使用）：%x\n", swap.Secret)
+	}
+	fmt.Printf("秘密值哈希：%x\n", swap.SecretHash)
+	fmt.Println("合约地址：", swap.Address)
+	fmt.Println("退款锁定时间：", formatLockTime(swap.LockTime))
+	fmt.Printf("合约：%x\n", swap.Contract)
+}
+
+/**
+ *使用秘密值领取原子交换合约中的资金
+ */
+func (cmd *CmdClient) RedeemSwap() {
+	redeemSwap := flag.NewFlagSet(REDEEMSWAP, flag.ExitOnError)
+	contract := redeemSwap.String("contract", "", "十六进制编码的原子交换合约")
+	secret := redeemSwap.String("secret", "", "十六进制编码的秘密值")
+	to := redeemSwap.String("to", "", "接收资金的地址，默认为合约中的接收方地址")
+	redeemSwap.Parse(os.Args[2:])
+	txHash, err := cmd.Chain.RedeemSwap(*contract, *secret, *to)
+	if err != nil {
+		fmt.Println("领取合约资金时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("领取成功，交易哈希：%x\n", txHash)
+}
+
+/**
+ *合约的锁定时间到期后取回原子交换合约中的资金
+ */
+func (cmd *CmdClient) RefundSwap() {
+	refundSwap := flag.NewFlagSet(REFUNDSWAP, flag.ExitOnError)
+	contract := refundSwap.String("contract", "", "十六进制编码的原子交换合约")
+	to := refundSwap.String("to", "", "接收退款的地址，默认为合约中的退款地址")
+	refundSwap.Parse(os.Args[2:])
+	txHash, err := cmd.Chain.RefundSwap(*contract, *to)
+	if err != nil {
+		fmt.Println("取回合约资金时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("退款成功，交易哈希：%x\n", txHash)
+}
+
+/**
+ *审计原子交换合约，合约已被领取时输出接收方公开的秘密值
+ */
+func (cmd *CmdClient) AuditSwap() {
+	auditSwap := flag.NewFlagSet(AUDITSWAP, flag.ExitOnError)
+	contract := auditSwap.String("contract", "", "十六进制编码的原子交换合约")
+	auditSwap.Parse(os.Args[2:])
+	audit, err := cmd.Chain.AuditSwap(*contract)
+	if err != nil {
+		fmt.Println("审计合约时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("合约地址：", audit.Address)
+	fmt.Printf("合约余额：%f\n", audit.Balance)
+	fmt.Println("接收方地址：", audit.RecipientAddress)
+	fmt.Println("退款地址：", audit.RefundAddress)
+	fmt.Printf("秘密值哈希：%x\n", audit.SecretHash)
+	fmt.Println("退款锁定时间：", formatLockTime(audit.LockTime))
+	if audit.Secret != nil {
+		fmt.Printf("合约已被领取，公开的秘密值：%x\n", audit.Secret)
+	}
+}
+
+/**
+ *把数据写入链上，-hex表示-data为十六进制编码，例如需要存证的文件哈希
+ */
+func (cmd *CmdClient) SendData() {
+	sendData := flag.NewFlagSet(SENDDATA, flag.ExitOnError)
+	from := sendData.String("from", "", "支付写入数据交易的地址")
+	data := sendData.String("data", "", "要写入链上的数据")
+	isHex := sendData.Bool("hex", false, "数据是否为十六进制编码")
+	memPoolOnly := sendData.Bool("mempool", false, "只把交易提交到交易池，不立即打包新区块")
+	sendData.Parse(os.Args[2:])
+	dataBytes := []byte(*data)
+	if *isHex {
+		var err error
+		dataBytes, err = hex.DecodeString(*data)
+		if err != nil {
+			fmt.Println("数据不是合法的十六进制编码，请检查后重试")
+			return
+		}
+	}
+	txHash, err := cmd.Chain.SendData(*from, dataBytes, *memPoolOnly)
+	if err != nil {
+		fmt.Println("写入数据时遇到错误：", err.Error())
+		return
+	}
+	if *memPoolOnly {
+		fmt.Printf("数据交易已提交到交易池，交易哈希：%x\n", txHash)
+		return
+	}
+	fmt.Printf("数据写入成功，交易哈希：%x\n", txHash)
+}
+
+/**
+ *查询指定高度的区块中数据输出所携带的数据
+ */
+func (cmd *CmdClient) GetBlockData() {
+	getBlockData := flag.NewFlagSet(GETBLOCKDATA, flag.ExitOnError)
+	height := getBlockData.Int64("height", 0, "要查询的区块高度")
+	getBlockData.Parse(os.Args[2:])
+	payloads, err := cmd.Chain.GetBlockData(*height)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if len(payloads) == 0 {
+		fmt.Printf("区块%d中没有携带数据的输出\n", *height)
+		return
+	}
+	for _, payload := range payloads {
+		fmt.Printf("交易%x的第%d个输出：%x", payload.TxHash, payload.Vout, payload.Data)
+		if isPrintable(payload.Data) {
+			fmt.Printf("（%s）", string(payload.Data))
+		}
+		fmt.Println()
+	}
+}
+
+/**
+ *列出某个地址可以花费的utxo，输出的信息可以直接用于构建和离线签名原始交易
+ */
+func (cmd *CmdClient) ListUnspent() {
+	listUnspent := flag.NewFlagSet(LISTUNSPENT, flag.ExitOnError)
+	address := listUnspent.String("address", "", "要查询的地址")
+	listUnspent.Parse(os.Args[2:])
+	utxos, err := cmd.Chain.ListUnspent(*address)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if len(utxos) == 0 {
+		fmt.Println("该地址暂无可以花费的utxo")
+		return
+	}
+	for _, utxo := range utxos {
+		fmt.Printf("txid：%x vout：%d amount：%f%s height：%d scriptpubkey：%x\n", utxo.TxId, utxo.Vout, utxo.Value, amountWarning(utxo.Value), utxo.Height, utxo.GetScriptPub())
+	}
+}
+
+/**
+ *金额为NaN、无穷大或负数时返回警告文字，这样的交易会被拒绝，离线签名者在签名之前就能发现
+ */
+func amountWarning(value float64) string {
+	if transaction.IsValidAmount(value) {
+		return ""
+	}
+	return "（警告：金额无效，包含该金额的交易会被拒绝）"
+}
+
+/**
+ *使用JSON格式的输入和输出构建未签名的原始交易，输出十六进制编码的交易数据
+ *-inputs：[{"txid":"..","vout":0}]，-outputs：[{"address":"..","amount":1.5},{"data":"十六进制数据"}]
+ */
+func (cmd *CmdClient) CreateRawTransaction() {
+	createRaw := flag.NewFlagSet(CREATERAWTRANSACTION, flag.ExitOnError)
+	inputs := createRaw.String("inputs", "", "JSON格式的交易输入列表")
+	outputs := createRaw.String("outputs", "", "JSON格式的交易输出列表")
+	lockTime := createRaw.Int64("locktime", 0, "交易的锁定时间")
+	replaceable := createRaw.Bool("replaceable", false, "是否允许交易在确认前被更高手续费的交易替换")
+	createRaw.Parse(os.Args[2:])
+	var rawInputs []chain.RawTxInput
+	var rawOutputs []chain.RawTxOutput
+	if json.Unmarshal([]byte(*inputs), &rawInputs) != nil || json.Unmarshal([]byte(*outputs), &rawOutputs) != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	//未指定sequence的输入使用声明允许替换的sequence
+	if *replaceable {
+		sequence := uint32(transaction.REPLACEABLESEQUENCE)
+		for index := range rawInputs {
+			if rawInputs[index].Sequence == nil {
+				rawInputs[index].Sequence = &sequence
+			}
+		}
+	}
+	tx, err := cmd.Chain.CreateRawTransaction(rawInputs, rawOutputs, *lockTime)
+	if err != nil {
+		fmt.Println("构建原始交易时遇到错误：", err.Error())
+		return
+	}
+	cmd.printRawTransactionHex(tx)
+}
+
+/**
+ *解码十六进制的原始交易并输出交易的详细信息
+ */
+func (cmd *CmdClient) DecodeRawTransaction() {
+	decodeRaw := flag.NewFlagSet(DECODERAWTRANSACTION, flag.ExitOnError)
+	txHex := decodeRaw.String("hex", "", "十六进制编码的原始交易")
+	decodeRaw.Parse(os.Args[2:])
+	tx, err := cmd.Chain.DecodeRawTransaction(*txHex)
+	if err != nil {
+		fmt.Println("解码原始交易时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("交易哈希：%x\n", tx.TxHash)
+	fmt.Println("版本号：", tx.Version)
+	fmt.Println("锁定时间：", tx.LockedTime)
+	for index, input := range tx.Inputs {
+		fmt.Printf("输入[%d]：%x:%d sequence=%d\n", index, input.TxId, input.Vout, input.Sequence)
+		if len(input.Sig) > 0 || len(input.ScriptSig) > 0 {
+			fmt.Println("    解锁脚本：", script.Disasm(input.GetScriptSig()))
+		}
+	}
+	invalid := false
+	for index, output := range tx.Outputs {
+		fmt.Printf("输出[%d]：%f%s", index, output.Value, amountWarning(output.Value))
+		if len(output.PubkHash) > 0 {
+			fmt.Printf(" -> %s", cmd.Chain.Wallet.GetAddressByPubkHash(output.PubkHash))
+		}
+		fmt.Println()
+		fmt.Println("    锁定脚本：", script.Disasm(output.GetScriptPub()))
+		invalid = invalid || !transaction.IsValidAmount(output.Value)
+	}
+	if invalid {
+		fmt.Println("警告：交易包含金额无效的输出，该交易会被拒绝，请不要签名")
+	}
+}
+
+/**
+ *使用本地钱包对原始交易签名，-prevtxs可以提供本地链上查询不到的utxo信息
+ */
+func (cmd *CmdClient) SignRawTransactionWithWallet() {
+	signRaw := flag.NewFlagSet(SIGNRAWTRANSACTIONWITHWALLET, flag.ExitOnError)
+	txHex := signRaw.String("hex", "", "十六进制编码的原始交易")
+	prevTxs := signRaw.String("prevtxs", "", "JSON格式的所花费utxo信息：[{\"txid\":\"..\",\"vout\":0,\"scriptpubkey\":\"..\",\"amount\":1}]")
+	sigHashType := signRaw.String("sighashtype", "ALL", "签名哈希类型，可选ALL、NONE、SINGLE，以及ALL|ANYONECANPAY等组合")
+	signRaw.Parse(os.Args[2:])
+	hashType, err := transaction.ParseHashType(*sigHashType)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	tx, err := cmd.Chain.DecodeRawTransaction(*txHex)
+	if err != nil {
+		fmt.Println("解码原始交易时遇到错误：", err.Error())
+		return
+	}
+	prevs, ok := parsePrevTxs(*prevTxs)
+	if !ok {
+		return
+	}
+	complete, err := cmd.Chain.SignRawTransactionWithWallet(tx, prevs, hashType)
+	if err != nil {
+		fmt.Println("签名原始交易时遇到错误：", err.Error())
+		return
+	}
+	cmd.printRawTransactionHex(tx)
+	fmt.Println("签名是否完成：", complete)
+}
+
+/**
+ *使用给定的十六进制私钥对原始交易签名，配合-prevtxs可以在离线的机器上完成签名
+ */
+func (cmd *CmdClient) SignRawTransactionWithKey() {
+	signRaw := flag.NewFlagSet(SIGNRAWTRANSACTIONWITHKEY, flag.ExitOnError)
+	txHex := signRaw.String("hex", "", "十六进制编码的原始交易")
+	privKeys := signRaw.String("privkeys", "", "JSON格式的十六进制私钥列表")
+	curve := signRaw.String("curve", "p256", "私钥所使用的曲线，可选p256、secp256k1或schnorr")
+	prevTxs := signRaw.String("prevtxs", "", "JSON格式的所花费utxo信息，花费P2SH输出时需要提供redeemscript")
+	sigHashType := signRaw.String("sighashtype", "ALL", "签名哈希类型，可选ALL、NONE、SINGLE，以及ALL|ANYONECANPAY等组合")
+	signRaw.Parse(os.Args[2:])
+	hashType, err := transaction.ParseHashType(*sigHashType)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	tx, err := cmd.Chain.DecodeRawTransaction(*txHex)
+	if err != nil {
+		fmt.Println("解码原始交易时遇到错误：", err.Error())
+		return
+	}
+	keys, err := utils.JSONArray2String(*privKeys)
+	if err != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	prevs, ok := parsePrevTxs(*prevTxs)
+	if !ok {
+		return
+	}
+	complete, err := cmd.Chain.SignRawTransactionWithKey(tx, keys, *curve, prevs, hashType)
+	if err != nil {
+		fmt.Println("签名原始交易时遇到错误：", err.Error())
+		return
+	}
+	cmd.printRawTransactionHex(tx)
+	fmt.Println("签名是否完成：", complete)
+}
+
+/**
+ *发送已签名的原始交易，交易通过验证后被打包进新区块
+ */
+func (cmd *CmdClient) SendRawTransaction() {
+	sendRaw := flag.NewFlagSet(SENDRAWTRANSACTION, flag.ExitOnError)
+	txHex := sendRaw.String("hex", "", "十六进制编码的已签名交易")
+	toMemPool := sendRaw.Bool("mempool", false, "提交到交易池等待打包，而不是立即打包进新区块")
+	sendRaw.Parse(os.Args[2:])
+	tx, err := cmd.Chain.DecodeRawTransaction(*txHex)
+	if err != nil {
+		fmt.Println("解码原始交易时遇到错误：", err.Error())
+		return
+	}
+	var txHash [32]byte
+	if *toMemPool {
+		txHash, err = cmd.Chain.AcceptToMemPool(tx)
+	} else {
+		txHash, err = cmd.Chain.SendRawTransaction(tx)
+	}
+	if err != nil {
+		fmt.Println("抱歉，发送交易出现错误:", err.Error())
+		return
+	}
+	fmt.Printf("交易发送成功，交易哈希：%x\n", txHash)
+}
+
+/**
+ *输出交易池中所有未确认交易的手续费信息
+ */
+func (cmd *CmdClient) GetMemPool() {
+	entries, err := cmd.Chain.GetMemPool()
+	if err != nil {
+		fmt.Println("查询交易池时遇到错误：", err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("交易池中没有未确认的交易")
+		return
+	}
+	for _, entry := range entries {
+		fmt.Printf("txid：%x fee：%.8f size：%d feerate：%.8f replaceable：%t\n",
+			entry.Tx.TxHash, entry.Fee, entry.Size, entry.FeeRate(), entry.Tx.SignalsReplacement())
+	}
+}
+
+/**
+ *估算交易在指定个数的区块内被确认所需的手续费率
+ */
+func (cmd *CmdClient) EstimateFee() {
+	estimateFee := flag.NewFlagSet(ESTIMATEFEE, flag.ExitOnError)
+	blocks := estimateFee.Int64("blocks", chain.DEFAULTCONFIRMTARGET, "希望交易被确认所经过的区块个数")
+	estimateFee.Parse(os.Args[2:])
+	feeRate, estimated, err := cmd.Chain.EstimateFee(*blocks)
+	if err != nil {
+		fmt.Println("估算手续费时遇到错误：", err.Error())
+		return
+	}
+	if !estimated {
+		fmt.Printf("历史数据不足，使用默认手续费率：%.8f\n", feeRate)
+		return
+	}
+	fmt.Printf("预计在%d个区块内确认的手续费率：%.8f\n", *blocks, feeRate)
+}
+
+/**
+ *从交易池中挑选手续费率最高的交易包打包进新区块
+ */
+func (cmd *CmdClient) GenerateBlock() {
+	count, err := cmd.Chain.GenerateBlock()
+	if err != nil {
+		fmt.Println("打包区块时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("新区块已生成，打包了交易池中的%d笔交易\n", count)
+}
+
+/**
+ *提高交易池中一笔允许替换的钱包交易的手续费
+ */
+func (cmd *CmdClient) BumpFee() {
+	bumpFee := flag.NewFlagSet(BUMPFEE, flag.ExitOnError)
+	txIdHex := bumpFee.String("txid", "", "要提高手续费的交易哈希")
+	feeRate := bumpFee.Float64("feerate", 0, "替换交易的手续费率（每字节），默认使用满足替换规则的最低手续费")
+	bumpFee.Parse(os.Args[2:])
+	txId, err := hex.DecodeString(*txIdHex)
+	if err != nil || len(txId) != 32 {
+		fmt.Println("交易哈希格式不正确，请检查后重试")
+		return
+	}
+	var hash [32]byte
+	copy(hash[:], txId)
+	newHash, err := cmd.Chain.BumpFee(hash, *feeRate)
+	if err != nil {
+		fmt.Println("提高手续费时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("替换交易已进入交易池，交易哈希：%x\n", newHash)
+}
+
+/**
+ *创建部分签名交易并保存到文件，输入和输出的格式与createrawtransaction相同
+ */
+func (cmd *CmdClient) CreatePSBT() {
+	createPSBT := flag.NewFlagSet(CREATEPSBT, flag.ExitOnError)
+	inputs := createPSBT.String("inputs", "", "JSON格式的交易输入列表")
+	outputs := createPSBT.String("outputs", "", "JSON格式的交易输出列表")
+	lockTime := createPSBT.Int64("locktime", 0, "交易的锁定时间")
+	out := createPSBT.String("out", "", "保存部分签名交易的文件")
+	createPSBT.Parse(os.Args[2:])
+	var rawInputs []chain.RawTxInput
+	var rawOutputs []chain.RawTxOutput
+	if json.Unmarshal([]byte(*inputs), &rawInputs) != nil || json.Unmarshal([]byte(*outputs), &rawOutputs) != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	psbt, err := cmd.Chain.CreatePSBT(rawInputs, rawOutputs, *lockTime)
+	if err != nil {
+		fmt.Println("创建部分签名交易时遇到错误：", err.Error())
+		return
+	}
+	if writePSBT(*out, psbt) {
+		fmt.Println("部分签名交易已保存到", *out)
+	}
+}
+
+/**
+ *查看部分签名交易的交易信息以及每个输入的签名进度
+ */
+func (cmd *CmdClient) DecodePSBT() {
+	decodePSBT := flag.NewFlagSet(DECODEPSBT, flag.ExitOnError)
+	file := decodePSBT.String("file", "", "部分签名交易文件")
+	decodePSBT.Parse(os.Args[2:])
+	psbt, ok := readPSBT(*file)
+	if !ok {
+		return
+	}
+	fmt.Printf("交易哈希：%x\n", psbt.Tx.TxHash)
+	fmt.Println("锁定时间：", psbt.Tx.LockedTime)
+	for index, input := range psbt.Tx.Inputs {
+		fmt.Printf("输入[%d]：%x:%d sequence=%d\n", index, input.TxId, input.Vout, input.Sequence)
+		psbtInput := psbt.Inputs[index]
+		if psbtInput.UTXO == nil {
+			fmt.Println("    缺少所花费的utxo")
+			continue
+		}
+		fmt.Printf("    花费：%f%s <- %s\n", psbtInput.UTXO.Value, amountWarning(psbtInput.UTXO.Value), cmd.Chain.Wallet.GetAddressByPubkHash(psbtInput.UTXO.PubkHash))
+		if len(psbtInput.RedeemScript) > 0 {
+			fmt.Println("    赎回脚本：", script.Disasm(psbtInput.RedeemScript))
+		}
+		count, required, err := psbt.CountSigs(index)
+		if err != nil {
+			fmt.Println("    ", err.Error())
+			continue
+		}
+		fmt.Printf("    签名：%d/%d\n", count, required)
+		if nonces, partialSigs, total := psbt.MuSigProgress(index); total > 0 && count < required {
+			fmt.Printf("    MuSig nonce：%d/%d 部分签名：%d/%d\n", nonces, total, partialSigs, total)
+		}
+	}
+	for index, output := range psbt.Tx.Outputs {
+		fmt.Printf("输出[%d]：%f%s", index, output.Value, amountWarning(output.Value))
+		if len(output.PubkHash) > 0 {
+			fmt.Printf(" -> %s", cmd.Chain.Wallet.GetAddressByPubkHash(output.PubkHash))
+		}
+		fmt.Println()
+	}
+}
+
+/**
+ *使用本地链和钱包为部分签名交易补充utxo和赎回脚本
+ */
+func (cmd *CmdClient) UpdatePSBT() {
+	updatePSBT := flag.NewFlagSet(UPDATEPSBT, flag.ExitOnError)
+	file := updatePSBT.String("file", "", "部分签名交易文件")
+	updatePSBT.Parse(os.Args[2:])
+	psbt, ok := readPSBT(*file)
+	if !ok {
+		return
+	}
+	if err := cmd.Chain.UpdatePSBT(psbt); err != nil {
+		fmt.Println("更新部分签名交易时遇到错误：", err.Error())
+		return
+	}
+	if writePSBT(*file, psbt) {
+		fmt.Println("部分签名交易已更新")
+	}
+}
+
+/**
+ *使用本地钱包对部分签名交易签名，-out为空时覆盖原文件
+ */
+func (cmd *CmdClient) SignPSBT() {
+	signPSBT := flag.NewFlagSet(SIGNPSBT, flag.ExitOnError)
+	file := signPSBT.String("file", "", "部分签名交易文件")
+	out := signPSBT.String("out", "", "保存签名结果的文件，默认覆盖原文件")
+	sigHashType := signPSBT.String("sighashtype", "ALL", "签名哈希类型，可选ALL、NONE、SINGLE，以及ALL|ANYONECANPAY等组合")
+	signPSBT.Parse(os.Args[2:])
+	hashType, err := transaction.ParseHashType(*sigHashType)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	psbt, ok := readPSBT(*file)
+	if !ok {
+		return
+	}
+	if err := cmd.Chain.UpdatePSBT(psbt); err != nil {
+		fmt.Println("更新部分签名交易时遇到错误：", err.Error())
+		return
+	}
+	signed, nonces, err := cmd.Chain.SignPSBT(psbt, hashType)
+	if err != nil {
+		fmt.Println("签名部分签名交易时遇到错误：", err.Error())
+		return
+	}
+	if len(*out) == 0 {
+		*out = *file
+	}
+	if writePSBT(*out, psbt) {
+		fmt.Printf("新增%d个签名，已保存到%s\n", signed, *out)
+	}
+	if nonces > 0 {
+		fmt.Printf("新增%d个MuSig nonce，收集到所有参与方的nonce后请再次签名\n", nonces)
+	}
+}
+
+/**
+ *合并多个参与方分别签名后的部分签名交易
+ */
+func (cmd *CmdClient) CombinePSBT() {
+	combinePSBT := flag.NewFlagSet(COMBINEPSBT, flag.ExitOnError)
+	files := combinePSBT.String("files", "", "JSON格式的部分签名交易文件列表")
+	out := combinePSBT.String("out", "", "保存合并结果的文件")
+	combinePSBT.Parse(os.Args[2:])
+	fileSlice, err := utils.JSONArray2String(*files)
+	if err != nil || len(fileSlice) == 0 {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	combined, ok := readPSBT(fileSlice[0])
+	if !ok {
+		return
+	}
+	for _, file := range fileSlice[1:] {
+		psbt, ok := readPSBT(file)
+		if !ok {
+			return
+		}
+		if err = combined.Combine(psbt); err != nil {
+			fmt.Println(file, err.Error())
+			return
+		}
+	}
+	if writePSBT(*out, combined) {
+		fmt.Println("合并后的部分签名交易已保存到", *out)
+	}
+}
+
 /**
- *该方法用于获取当前节点已经生成的地址列表
+ *使用部分签名交易中的签名生成完整签名的交易，-send表示直接发送该交易
  */
-func (cmd *CmdClient) ListAddress() {
-	listAddress := flag.NewFlagSet(LISTADDRESS, flag.ExitOnError)
-	listAddress.Parse(os.Args[2:])
-    if len(os.Args[2:])	> 0 {
-    	fmt.Println("无法解析参数，请检查后重试")
+func (cmd *CmdClient) FinalizePSBT() {
+	finalizePSBT := flag.NewFlagSet(FINALIZEPSBT, flag.ExitOnError)
+	file := finalizePSBT.String("file", "", "部分签名交易文件")
+	send := finalizePSBT.Bool("send", false, "是否直接发送完成签名的交易")
+	finalizePSBT.Parse(os.Args[2:])
+	psbt, ok := readPSBT(*file)
+	if !ok {
 		return
 	}
-	addList, err := cmd.Chain.GetAddressList()
-    if err != nil {
-    	fmt.Println(err.Error())
+	tx, err := psbt.Finalize()
+	if err != nil {
+		fmt.Println("部分签名交易尚未完成签名：", err.Error())
 		return
 	}
-	//如果本地节点暂时还没有地址，需要给用户提示
-	if len(addList) == 0 {
-		fmt.Println("暂无地址，可以使用go run main.go getnewaddress命令生成新地址")
+	if !*send {
+		cmd.printRawTransactionHex(tx)
 		return
 	}
-	fmt.Println("获取地址列表成功，地址信息如下：")
-	for index, add := range addList {
-		fmt.Printf("[%d]:%s\n", index + 1, add)
+	txHash, err := cmd.Chain.SendRawTransaction(tx)
+	if err != nil {
+		fmt.Println("抱歉，发送交易出现错误:", err.Error())
+		return
 	}
+	fmt.Printf("交易发送成功，交易哈希：%x\n", txHash)
 }
 
 /**
- *定义新的方法，用于生成新的地址
+ *从文件中读取十六进制编码的部分签名交易
  */
-func (cmd *CmdClient) GetNewAddress() {
-	getNewAddress := flag.NewFlagSet(GETNEWADDRESS, flag.ExitOnError)
-	getNewAddress.Parse(os.Args[2:])
-	if len(os.Args[2:]) > 0 {
-		fmt.Println("抱歉，生成新地址功能无法解析参数，请重试")
+func readPSBT(file string) (*transaction.PSBT, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Println("读取部分签名交易文件时遇到错误：", err.Error())
+		return nil, false
+	}
+	psbtBytes, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		fmt.Println("部分签名交易文件的内容不是合法的十六进制编码")
+		return nil, false
+	}
+	psbt, err := transaction.DeserializePSBT(psbtBytes)
+	if err != nil {
+		fmt.Println("解析部分签名交易时遇到错误：", err.Error())
+		return nil, false
+	}
+	return psbt, true
+}
+
+/**
+ *把部分签名交易以十六进制编码写入文件
+ */
+func writePSBT(file string, psbt *transaction.PSBT) bool {
+	if len(file) == 0 {
+		fmt.Println("请使用-out参数指定保存部分签名交易的文件")
+		return false
+	}
+	data, err := psbt.Serialize()
+	if err != nil {
+		fmt.Println("序列化部分签名交易时遇到错误：", err.Error())
+		return false
+	}
+	if err = os.WriteFile(file, []byte(hex.EncodeToString(data)), 0600); err != nil {
+		fmt.Println("保存部分签名交易文件时遇到错误：", err.Error())
+		return false
+	}
+	return true
+}
+
+/**
+ *输出十六进制编码的交易数据
+ */
+func (cmd *CmdClient) printRawTransactionHex(tx *transaction.Transaction) {
+	txHex, err := cmd.Chain.EncodeRawTransaction(tx)
+	if err != nil {
+		fmt.Println("序列化交易时遇到错误：", err.Error())
 		return
 	}
-	address, err := cmd.Chain.GetNewAddress()
+	fmt.Println("交易数据：", txHex)
+}
+
+/**
+ *解析JSON格式的所花费utxo信息，参数为空时返回空列表
+ */
+func parsePrevTxs(prevTxs string) ([]chain.PrevTx, bool) {
+	if len(prevTxs) == 0 {
+		return nil, true
+	}
+	var prevs []chain.PrevTx
+	if json.Unmarshal([]byte(prevTxs), &prevs) != nil {
+		fmt.Println("prevtxs参数格式不正确，清检查后重试！")
+		return nil, false
+	}
+	return prevs, true
+}
+
+/**
+ *判断数据是否是可以直接显示的文本
+ */
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ *列出钱包的交易记录，每条收支明细输出一行，找零不单独列出
+ */
+func (cmd *CmdClient) ListTransactions() {
+	listTransactions := flag.NewFlagSet(LISTTRANSACTIONS, flag.ExitOnError)
+	count := listTransactions.Int("count", 10, "最多列出的交易个数")
+	skip := listTransactions.Int("skip", 0, "跳过最新的交易个数")
+	listTransactions.Parse(os.Args[2:])
+	walletTxs, err := cmd.Chain.ListTransactions(*count, *skip)
 	if err != nil {
-		fmt.Println("生成新地址时遇到错误，请重试", err.Error())
+		fmt.Println("查询钱包交易时遇到错误：", err.Error())
 		return
 	}
-	fmt.Println("生成新的地址：", address)
+	if len(walletTxs) == 0 {
+		fmt.Println("钱包暂无交易记录")
+		return
+	}
+	for _, walletTx := range walletTxs {
+		confirmations := walletTx.Confirmations(cmd.Chain.LastBlock.Height)
+		txTime := time.Unix(walletTx.Time, 0).Format("2006-01-02 15:04:05")
+		details := walletTx.Details
+		//只转给自己的找零地址时，以金额为0的支出显示手续费
+		if len(details) == 0 {
+			details = []wallettx.TxDetail{{Category: wallettx.CATEGORYSEND, Vout: -1}}
+		}
+		for _, detail := range details {
+			category := detail.Category
+			if category == wallettx.CATEGORYGENERATE && walletTx.IsImmature(cmd.Chain.LastBlock.Height) {
+				category = "immature"
+			}
+			if detail.WatchOnly {
+				category += "(watch-only)"
+			}
+			fmt.Printf("txid：%x category：%s address：%s amount：%.8f", walletTx.TxHash, category, detail.Address, detail.Amount)
+			if label := cmd.Chain.Wallet.GetAddressMeta(detail.Address).Label; len(label) > 0 {
+				fmt.Printf(" label：%s", label)
+			}
+			if detail.Category == wallettx.CATEGORYSEND {
+				fmt.Printf(" fee：%.8f", -walletTx.Fee)
+			}
+			fmt.Printf(" confirmations：%d time：%s\n", confirmations, txTime)
+		}
+	}
+}
+
+/**
+ *查看钱包的余额和状态
+ */
+func (cmd *CmdClient) GetWalletInfo() {
+	info, err := cmd.Chain.GetWalletInfo()
+	if err != nil {
+		fmt.Println("查询钱包信息时遇到错误：", err.Error())
+		return
+	}
+	if cmd.Chain.Wallet.Name == wallet.DEFAULTWALLET {
+		fmt.Println("钱包名称：\"\" (默认钱包)")
+	} else {
+		fmt.Println("钱包名称：", cmd.Chain.Wallet.Name)
+	}
+	fmt.Printf("已确认余额：%f\n", info.Confirmed)
+	fmt.Printf("未确认余额：%f\n", info.Unconfirmed)
+	fmt.Printf("未成熟余额：%f\n", info.Immature)
+	fmt.Printf("观察地址余额：%f\n", info.WatchOnly)
+	fmt.Printf("交易个数：%d\n", info.TxCount)
+	fmt.Printf("地址个数：%d 观察地址个数：%d\n", info.AddressCount, info.WatchOnlyCount)
+	fmt.Printf("HD钱包：%t 已加密：%t 已锁定：%t\n", info.HDEnabled, info.Encrypted, info.Locked)
+	fmt.Printf("交易记录同步高度：%d\n", info.SyncHeight)
+}
+
+/**
+ *把锁定时间格式化为可读的区块高度或时间
+ */
+func formatLockTime(lockTime int64) string {
+	if lockTime < script.LOCKTIMETHRESHOLD {
+		return fmt.Sprintf("区块高度%d", lockTime)
+	}
+	return time.Unix(lockTime, 0).Format("2006-01-02 15:04:05")
+}
+
+/**
+ *导出某个地址的原始公钥，供其他钱包创建多重签名地址使用
+ */
+func (cmd *CmdClient) GetPubKey() {
+	getPubKey := flag.NewFlagSet(GETPUBKEY, flag.ExitOnError)
+	address := getPubKey.String("address", "", "要导出公钥的地址")
+	getPubKey.Parse(os.Args[2:])
+	pubk, err := cmd.Chain.GetPubKey(*address)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Printf("公钥是：%x\n", pubk)
 }
 
 /**
@@ -71,12 +1074,326 @@ func (cmd *CmdClient) DumpPrivkey() {
 		fmt.Println("无法解析输入参数，请检查后重试")
 		return
 	}
-	pri, err := cmd.Chain.DumpPrivkey(*address)
+	wif, err := cmd.Chain.DumpPrivkey(*address)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Printf("私钥是：%s\n", wif)
+}
+
+/**
+ *导入WIF格式的私钥
+ */
+func (cmd *CmdClient) ImportPrivKey() {
+	importPrivKey := flag.NewFlagSet(IMPORTPRIVKEY, flag.ExitOnError)
+	privKey := importPrivKey.String("privkey", "", "WIF格式的私钥")
+	rescan := importPrivKey.Bool("rescan", true, "是否扫描区块链查询该地址的余额")
+	importPrivKey.Parse(os.Args[2:])
+	address, balance, err := cmd.Chain.ImportPrivKey(*privKey, *rescan)
+	if err != nil {
+		fmt.Println("导入私钥时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("私钥导入成功，对应的地址是：", address)
+	if *rescan {
+		fmt.Printf("地址%s的余额是：%f\n", address, balance)
+	}
+}
+
+/**
+ *导入观察地址，观察地址只能查询余额，无法花费
+ */
+func (cmd *CmdClient) ImportAddress() {
+	importAddress := flag.NewFlagSet(IMPORTADDRESS, flag.ExitOnError)
+	address := importAddress.String("address", "", "要观察的地址")
+	rescan := importAddress.Bool("rescan", true, "是否扫描区块链查询该地址的余额")
+	importAddress.Parse(os.Args[2:])
+	balance, err := cmd.Chain.ImportAddress(*address, *rescan)
+	if err != nil {
+		fmt.Println("导入观察地址时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("观察地址导入成功：", *address)
+	if *rescan {
+		fmt.Printf("地址%s的余额是：%f\n", *address, balance)
+	}
+}
+
+/**
+ *导出HD钱包的助记词
+ */
+func (cmd *CmdClient) DumpMnemonic() {
+	mnemonic, err := cmd.Chain.Wallet.DumpMnemonic()
+	if err != nil {
+		fmt.Println("导出助记词时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("助记词是：", mnemonic)
+	fmt.Println("请抄写并妥善保管助记词，使用restorewallet命令可以恢复钱包中的所有HD地址")
+}
+
+/**
+ *使用助记词恢复HD钱包，并列出恢复的地址及其余额
+ */
+func (cmd *CmdClient) RestoreWallet() {
+	restoreWallet := flag.NewFlagSet(RESTOREWALLET, flag.ExitOnError)
+	mnemonic := restoreWallet.String("mnemonic", "", "钱包的助记词，单词之间使用空格分隔")
+	name := restoreWallet.String("name", "", "创建一个该名称的新钱包并恢复到新钱包中")
+	replace := restoreWallet.Bool("replace", false, "确认后替换当前钱包已有的HD种子")
+	restoreWallet.Parse(os.Args[2:])
+	if *replace && len(*name) == 0 && cmd.Chain.Wallet.HDChain != nil {
+		fmt.Print("替换后当前钱包的新地址由新的助记词派生，原助记词派生的地址仍保留在钱包中，请确认已经备份原助记词，输入yes继续：")
+		line, _ := stdinReader.ReadString('\n')
+		if strings.TrimSpace(line) != "yes" {
+			fmt.Println("已取消替换HD种子")
+			return
+		}
+	}
+	addresses, err := cmd.Chain.RestoreWallet(*mnemonic, *name, *replace)
+	if err != nil {
+		fmt.Println("恢复钱包时遇到错误：", err.Error())
+		return
+	}
+	if len(*name) > 0 {
+		fmt.Printf("钱包%s创建成功，已加载，可以使用-wallet %s参数操作该钱包\n", *name, *name)
+	}
+	if len(addresses) == 0 {
+		fmt.Println("HD种子已恢复，链上没有找到该钱包使用过的地址")
+		return
+	}
+	fmt.Printf("钱包已恢复，找到%d个地址：\n", len(addresses))
+	for _, address := range addresses {
+		balance, _ := cmd.Chain.GetBalance(address)
+		keyPair := cmd.Chain.Wallet.GetKeyPairByAddress(address)
+		fmt.Printf("%s %s 余额：%f\n", address, wallet.FormatHDPath(keyPair.HDPath), balance)
+	}
+}
+
+/**
+ *使用密码加密钱包中的私钥，加密后钱包处于锁定状态，没有指定-passphrase参数时从标准输入读取密码
+ */
+func (cmd *CmdClient) EncryptWallet() {
+	encryptWallet := flag.NewFlagSet(ENCRYPTWALLET, flag.ExitOnError)
+	passphrase := encryptWallet.String("passphrase", "", "加密钱包所使用的密码，不指定时从标准输入读取")
+	encryptWallet.Parse(os.Args[2:])
+	if len(*passphrase) == 0 {
+		var err error
+		if *passphrase, err = readPassphrase(); err != nil {
+			fmt.Println("读取钱包密码时遇到错误：", err.Error())
+			return
+		}
+	}
+	err := cmd.Chain.Wallet.EncryptWallet(*passphrase)
+	if err != nil {
+		fmt.Println("加密钱包时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("钱包已加密并锁定，需要私钥的命令请通过" + PASSPHRASEENV + "环境变量或-stdinpassphrase参数提供密码，请牢记密码")
+}
+
+/**
+ *修改钱包的密码，没有指定-old和-new参数时从标准输入依次读取原来的密码和新的密码
+ */
+func (cmd *CmdClient) WalletPassphraseChange() {
+	passphraseChange := flag.NewFlagSet(WALLETPASSPHRASECHANGE, flag.ExitOnError)
+	oldPassphrase := passphraseChange.String("old", "", "钱包原来的密码，不指定时从标准输入读取")
+	newPassphrase := passphraseChange.String("new", "", "钱包新的密码，不指定时从标准输入读取")
+	passphraseChange.Parse(os.Args[2:])
+	for _, passphrase := range []*string{oldPassphrase, newPassphrase} {
+		if len(*passphrase) > 0 {
+			continue
+		}
+		var err error
+		if *passphrase, err = readPassphrase(); err != nil {
+			fmt.Println("读取钱包密码时遇到错误：", err.Error())
+			return
+		}
+	}
+	err := cmd.Chain.Wallet.ChangePassphrase(*oldPassphrase, *newPassphrase)
+	if err != nil {
+		fmt.Println("修改钱包密码时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("钱包密码修改成功，钱包已锁定")
+}
+
+/**
+ *作用于钱包的命令，这些命令可以使用-wallet参数指定所操作的钱包，不指定时操作默认钱包
+ */
+var walletCommands = map[string]bool{
+	GENERATEGENSIS: true, SENDTRANSACTION: true, SENDMANY: true, GETBALANCE: true,
+	GETNEWADDRESS: true, LISTADDRESS: true, DUMPPRIVKEY: true, SETCOINBASE: true, GETCOINBASE: true,
+	CREATEMUSIGADDRESS: true, CREATEMULTISIG: true, GETPUBKEY: true, ADDREDEEMSCRIPT: true,
+	CREATETIMELOCKADDRESS: true, INITIATESWAP: true, REDEEMSWAP: true, REFUNDSWAP: true, AUDITSWAP: true,
+	SENDDATA: true, SIGNRAWTRANSACTIONWITHWALLET: true, SENDRAWTRANSACTION: true, GENERATEBLOCK: true,
+	BUMPFEE: true, UPDATEPSBT: true, SIGNPSBT: true, FINALIZEPSBT: true,
+	ENCRYPTWALLET: true, WALLETPASSPHRASECHANGE: true,
+	DUMPMNEMONIC: true, RESTOREWALLET: true, IMPORTPRIVKEY: true, IMPORTADDRESS: true,
+	LISTTRANSACTIONS: true, GETWALLETINFO: true, SETLABEL: true, GETADDRESSESBYLABEL: true, GETADDRESSINFO: true,
+}
+
+/**
+ *从命令参数中取出全局参数name，剩余的参数交给各个命令解析
+ *hasValue为true时参数带有取值，支持-name value、-name=value以及两个中划线的写法；为false时参数为开关
+ */
+func takeGlobalArg(name string, hasValue bool) (string, bool, error) {
+	args := []string{os.Args[0], os.Args[1]}
+	value, found := "", false
+	for i := 2; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		switch {
+		case arg == "-"+name || arg == "--"+name:
+			found = true
+			if !hasValue {
+				continue
+			}
+			if i+1 >= len(os.Args) {
+				return "", false, errors.New("-" + name + "参数缺少取值")
+			}
+			value = os.Args[i+1]
+			i++
+		case hasValue && (strings.HasPrefix(arg, "-"+name+"=") || strings.HasPrefix(arg, "--"+name+"=")):
+			value, found = arg[strings.Index(arg, "=")+1:], true
+		default:
+			args = append(args, arg)
+		}
+	}
+	if found && !walletCommands[os.Args[1]] {
+		return "", false, errors.New("该命令不作用于钱包，不支持-" + name + "参数")
+	}
+	os.Args = args
+	return value, found, nil
+}
+
+/**
+ *从命令参数中取出-wallet参数并切换到对应的钱包
+ */
+func (cmd *CmdClient) selectWallet() bool {
+	name, found, err := takeGlobalArg("wallet", true)
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if !found {
+		return true
+	}
+	if err = cmd.Chain.UseWallet(name); err != nil {
+		fmt.Println("切换钱包时遇到错误：", err.Error())
+		return false
+	}
+	return true
+}
+
+/**
+ *在本条命令的进程内存中解锁加密钱包，钱包不会在命令之间保持解锁状态，需要私钥的命令每次都要提供密码
+ *密码从PASSPHRASEENV环境变量读取，指定-stdinpassphrase参数时从标准输入的第一行读取，
+ *密码不通过命令参数传递，避免出现在shell历史记录和进程列表中
+ */
+func (cmd *CmdClient) unlockWallet() bool {
+	_, fromStdin, err := takeGlobalArg("stdinpassphrase", false)
 	if err != nil {
 		fmt.Println(err.Error())
+		return false
+	}
+	passphrase, found := os.LookupEnv(PASSPHRASEENV)
+	if fromStdin {
+		if passphrase, err = readPassphrase(); err != nil {
+			fmt.Println("读取钱包密码时遇到错误：", err.Error())
+			return false
+		}
+	} else if !found || !walletCommands[os.Args[1]] || !cmd.Chain.Wallet.IsEncrypted() {
+		return true
+	}
+	if err = cmd.Chain.Wallet.Unlock(passphrase); err != nil {
+		fmt.Println("解锁钱包时遇到错误：", err.Error())
+		return false
+	}
+	return true
+}
+
+/**
+ *从标准输入读取一行作为钱包密码，去掉末尾的换行符
+ */
+func readPassphrase() (string, error) {
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return "", errors.New("标准输入中没有钱包密码")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+/**
+ *创建一个新的命名钱包
+ */
+func (cmd *CmdClient) CreateWallet() {
+	createWallet := flag.NewFlagSet(CREATEWALLET, flag.ExitOnError)
+	name := createWallet.String("name", "", "新钱包的名称")
+	createWallet.Parse(os.Args[2:])
+	if len(createWallet.Args()) > 0 {
+		fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	if err := cmd.Chain.CreateWallet(*name); err != nil {
+		fmt.Println("创建钱包时遇到错误：", err.Error())
 		return
 	}
-	fmt.Printf("私钥是：%x\n", pri.D.Bytes())
+	fmt.Printf("钱包%s创建成功，已加载，可以使用-wallet %s参数操作该钱包\n", *name, *name)
+}
+
+/**
+ *加载一个已经存在的钱包
+ */
+func (cmd *CmdClient) LoadWallet() {
+	loadWallet := flag.NewFlagSet(LOADWALLET, flag.ExitOnError)
+	name := loadWallet.String("name", "", "要加载的钱包名称")
+	loadWallet.Parse(os.Args[2:])
+	if len(loadWallet.Args()) > 0 {
+		fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	if err := cmd.Chain.LoadWallet(*name); err != nil {
+		fmt.Println("加载钱包时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("钱包%s加载成功\n", *name)
+}
+
+/**
+ *卸载一个已经加载的钱包
+ */
+func (cmd *CmdClient) UnloadWallet() {
+	unloadWallet := flag.NewFlagSet(UNLOADWALLET, flag.ExitOnError)
+	name := unloadWallet.String("name", "", "要卸载的钱包名称")
+	unloadWallet.Parse(os.Args[2:])
+	if len(unloadWallet.Args()) > 0 {
+		fmt.Println("无法解析参数，请检查后重试")
+		return
+	}
+	if err := cmd.Chain.UnloadWallet(*name); err != nil {
+		fmt.Println("卸载钱包时遇到错误：", err.Error())
+		return
+	}
+	fmt.Printf("钱包%s已卸载\n", *name)
+}
+
+/**
+ *列出所有已经加载的钱包
+ */
+func (cmd *CmdClient) ListWallets() {
+	names, err := cmd.Chain.ListWallets()
+	if err != nil {
+		fmt.Println("查询钱包列表时遇到错误：", err.Error())
+		return
+	}
+	fmt.Println("已加载的钱包：")
+	for _, name := range names {
+		if name == wallet.DEFAULTWALLET {
+			fmt.Println("  \"\" (默认钱包)")
+			continue
+		}
+		fmt.Println(" ", name)
+	}
 }
 
 /**
@@ -90,12 +1407,19 @@ func (cmd *CmdClient) Run() {
 		return
 	}
 
+	//钱包命令可以使用-wallet参数指定所操作的钱包，通过环境变量或者标准输入提供密码解锁加密钱包
+	if !cmd.selectWallet() || !cmd.unlockWallet() {
+		return
+	}
+
 	//解析用户输入的第一个参数，作为功能命令进行解析
 	switch os.Args[1] {
 	case GENERATEGENSIS:
 		cmd.GenerateGensis()
 	case SENDTRANSACTION:
 		cmd.SendTransaction()
+	case SENDMANY:
+		cmd.SendMany()
 	case GETBALANCE:
 		cmd.GetBalance()
 	case GETLASTBLOCK:
@@ -112,16 +1436,95 @@ func (cmd *CmdClient) Run() {
 		cmd.SetCoinbase()//设置挖矿矿工的地址
 	case GETCOINBASE:
 		cmd.GetCoinbase()//查看当前节点所设置的矿工地址
+	case CREATEMUSIGADDRESS:
+		cmd.CreateMuSigAddress()
+	case CREATEMULTISIG:
+		cmd.CreateMultiSig()
+	case GETPUBKEY:
+		cmd.GetPubKey()
+	case ADDREDEEMSCRIPT:
+		cmd.AddRedeemScript()
+	case CREATETIMELOCKADDRESS:
+		cmd.CreateTimeLockAddress()
+	case INITIATESWAP:
+		cmd.InitiateSwap()
+	case REDEEMSWAP:
+		cmd.RedeemSwap()
+	case REFUNDSWAP:
+		cmd.RefundSwap()
+	case AUDITSWAP:
+		cmd.AuditSwap()
+	case SENDDATA:
+		cmd.SendData()
+	case GETBLOCKDATA:
+		cmd.GetBlockData()
+	case LISTUNSPENT:
+		cmd.ListUnspent()
+	case CREATERAWTRANSACTION:
+		cmd.CreateRawTransaction()
+	case DECODERAWTRANSACTION:
+		cmd.DecodeRawTransaction()
+	case SIGNRAWTRANSACTIONWITHWALLET:
+		cmd.SignRawTransactionWithWallet()
+	case SIGNRAWTRANSACTIONWITHKEY:
+		cmd.SignRawTransactionWithKey()
+	case SENDRAWTRANSACTION:
+		cmd.SendRawTransaction()
+	case GETMEMPOOL:
+		cmd.GetMemPool()
+	case GENERATEBLOCK:
+		cmd.GenerateBlock()
+	case BUMPFEE:
+		cmd.BumpFee()
+	case ESTIMATEFEE:
+		cmd.EstimateFee()
+	case CREATEPSBT:
+		cmd.CreatePSBT()
+	case DECODEPSBT:
+		cmd.DecodePSBT()
+	case UPDATEPSBT:
+		cmd.UpdatePSBT()
+	case SIGNPSBT:
+		cmd.SignPSBT()
+	case COMBINEPSBT:
+		cmd.CombinePSBT()
+	case FINALIZEPSBT:
+		cmd.FinalizePSBT()
+	case ENCRYPTWALLET:
+		cmd.EncryptWallet()
+	case WALLETPASSPHRASECHANGE:
+		cmd.WalletPassphraseChange()
+	case DUMPMNEMONIC:
+		cmd.DumpMnemonic()
+	case RESTOREWALLET:
+		cmd.RestoreWallet()
+	case IMPORTPRIVKEY:
+		cmd.ImportPrivKey()
+	case IMPORTADDRESS:
+		cmd.ImportAddress()
+	case LISTTRANSACTIONS:
+		cmd.ListTransactions()
+	case GETWALLETINFO:
+		cmd.GetWalletInfo()
+	case CREATEWALLET:
+		cmd.CreateWallet()
+	case LOADWALLET:
+		cmd.LoadWallet()
+	case UNLOADWALLET:
+		cmd.UnloadWallet()
+	case LISTWALLETS:
+		cmd.ListWallets()
+	case SETLABEL:
+		cmd.SetLabel()
+	case GETADDRESSESBYLABEL:
+		cmd.GetAddressesByLabel()
+	case GETADDRESSINFO:
+		cmd.GetAddressInfo()
 	case HELP:
 		cmd.Help()
 	default:
 		cmd.Default()
 	}
-	//createBlock := flag.NewFlagSet("createblock", flag.ExitOnError)
-	//data := createBlock.String("data", "默认值", "新区块的区块数据")
-	//createBlock.Parse(os.Args[2:])
-	//cmd.Chain.CreateNewBlock([]byte(*data))
-
 }
 
 func (cmd *CmdClient) GenerateGensis() {
@@ -158,9 +1561,13 @@ func (cmd *CmdClient) SendTransaction() {
 	from := createBlock.String("from", "", "交易发起人")
     to := createBlock.String("to", "", "交易接受者地址")
     amount := createBlock.String("amount", "", "转账的数量")
+    lockTime := createBlock.Int64("locktime", 0, "交易的锁定时间，小于500000000为区块高度，否则为unix时间戳")
+    strategy := createBlock.String("strategy", "", "选币策略：bnb、largest、smallest、random或privacy，默认优先精确匹配")
+    feeRate := createBlock.Float64("feerate", 0, "手续费率（每字节），默认使用估算的手续费率")
+    memPoolOnly := createBlock.Bool("mempool", false, "只把交易提交到交易池，不立即打包新区块")
 
-    if len(os.Args[2:]) > 6 {
-		fmt.Println("SENDTRANSACTION命令只支持三个参数和参数值，请重试")
+    if len(os.Args[2:]) > 13 {
+		fmt.Println("SENDTRANSACTION命令只支持七个参数和参数值，请重试")
 		return
 	}
 
@@ -202,14 +1609,63 @@ func (cmd *CmdClient) SendTransaction() {
 		return
 	}
 
-	err = cmd.Chain.SendTransaction(fromSlice, toSlice, amountSlice)
+	selector, err := transaction.GetCoinSelector(*strategy)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	err = cmd.Chain.SendTransaction(fromSlice, toSlice, amountSlice, *lockTime, selector, *feeRate, *memPoolOnly)
 	if err != nil {
 		fmt.Println("抱歉，发送交易出现错误:", err.Error())
 		return
 	}
+	if *memPoolOnly {
+		fmt.Println("交易已提交到交易池，等待打包")
+		return
+	}
 	fmt.Println("交易发送成功")
 }
 
+/**
+ *批量转账：使用一个或多个钱包地址的资金，在一笔交易中向多个地址转账
+ */
+func (cmd *CmdClient) SendMany() {
+	sendMany := flag.NewFlagSet(SENDMANY, flag.ExitOnError)
+	from := sendMany.String("from", "", "JSON格式的付款地址列表")
+	to := sendMany.String("to", "", "JSON格式的收款地址到转账金额的映射")
+	change := sendMany.String("change", "", "找零地址，默认为一个新的找零地址")
+	strategy := sendMany.String("strategy", "", "选币策略：bnb、largest、smallest、random或privacy，默认优先精确匹配")
+	feeRate := sendMany.Float64("feerate", 0, "手续费率（每字节），默认使用估算的手续费率")
+	memPoolOnly := sendMany.Bool("mempool", false, "只把交易提交到交易池，不立即打包新区块")
+	sendMany.Parse(os.Args[2:])
+
+	fromSlice, err := utils.JSONArray2String(*from)
+	if err != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	payments, err := utils.JSONObject2FloatMap(*to)
+	if err != nil {
+		fmt.Println("抱歉，参数格式不正确，清检查后重试！")
+		return
+	}
+	selector, err := transaction.GetCoinSelector(*strategy)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	txHash, err := cmd.Chain.SendMany(fromSlice, payments, *change, selector, *feeRate, *memPoolOnly)
+	if err != nil {
+		fmt.Println("抱歉，发送交易出现错误:", err.Error())
+		return
+	}
+	if *memPoolOnly {
+		fmt.Printf("交易已提交到交易池，交易哈希：%x\n", txHash)
+		return
+	}
+	fmt.Printf("交易发送成功，交易哈希：%x\n", txHash)
+}
+
 /**
  *获取地址的余额
  */
@@ -228,6 +1684,20 @@ func (Cmd *CmdClient) GetBalance() {
 		return
 	}
 
+	//未指定地址时查询整个钱包的余额
+	if len(addr) == 0 {
+		balance, err := blockChain.GetWalletBalance()
+		if err != nil {
+			fmt.Println("查询钱包余额时遇到错误：", err.Error())
+			return
+		}
+		fmt.Printf("钱包已确认余额：%f 未确认余额：%f 未成熟余额：%f\n", balance.Confirmed, balance.Unconfirmed, balance.Immature)
+		if len(blockChain.Wallet.WatchOnly) > 0 {
+			fmt.Printf("观察地址余额：%f\n", balance.WatchOnly)
+		}
+		return
+	}
+
 	//调用余额查询功能
 	balance, err := blockChain.GetBalance(addr)
 	if err != nil {
@@ -249,7 +1719,7 @@ func (cmd *CmdClient) GetLastBlock() {
 	}
 	fmt.Println("恭喜获取到最新区块")
 	fmt.Printf("区块高度：%d\n", lasBlock.Height)
-	fmt.Printf("区块哈希：%f\n", lasBlock.Hash)
+	fmt.Printf("区块哈希：%x\n", lasBlock.Hash)
 	for index, tx := range lasBlock.Transactions {
 		fmt.Printf("最新区块交易：%d, 交易：%v\n", index, tx)
 	}
@@ -269,10 +1739,15 @@ func (cmd *CmdClient) GetAllBlocks() {
 		for index, tx := range block.Transactions {
 			fmt.Printf("     第%d笔交易，交易hash：%x\n", index, tx.TxHash)
 		    for inputIndex, input := range tx.Inputs {
+		    	if input.IsCoinbaseInput() {
+		    		height, _ := input.GetCoinbaseHeight()
+		    		fmt.Printf("           第%d笔交易输入,coinbase奖励,区块高度%d,附加数据%x\n", inputIndex, height, input.Coinbase[8:])
+		    		continue
+				}
 		    	fmt.Printf("           第%d笔交易输入,花了%x的%d的钱\n", inputIndex, input.TxId, input.Vout)
 			}
 			for outputIndex, output := range tx.Outputs {
-				fmt.Printf("      第%d笔交易输出，实现收入%f\n", outputIndex, output.Value)
+				fmt.Printf("      第%d笔交易输出，实现收入%f，锁定脚本：%s\n", outputIndex, output.Value, script.Disasm(output.GetScriptPub()))
 			}
 		}
 		fmt.Println()
@@ -328,12 +1803,58 @@ func (cmd *CmdClient) Help() {
 	fmt.Println()
 	fmt.Println("AVAILABLE COMMANDS")
 	fmt.Println("    generategensis    use the command can create a gensis block and save to the boltdb file. use the gensis argument to set the custom data.")
-	fmt.Println("    sendtransaction   this command used to send a new transaction, that can specified a data an argument named data.")
-	fmt.Println("    getbalance        this is a comand that can get the balance of specified address.")
+	fmt.Println("    sendmany          pay many addresses in one transaction with a single change output sent to a new change address unless -change is given. use -from(JSON array), -to(JSON object of address to amount), optional -change, -strategy and -feerate. add -mempool to leave the replaceable transaction in the mempool instead of mining it.")
+	fmt.Println("    sendtransaction   this command used to send a new transaction, that can specified a data an argument named data. use the locktime argument to lock the transaction until a height or time, the strategy argument(bnb, largest, smallest, random or privacy) to choose how utxos are selected, and the feerate argument to override the estimated fee rate. the change goes to a new change address. wallet transactions are replaceable, add -mempool to leave them in the mempool for bumpfee instead of mining them.")
+	fmt.Println("    getbalance        this is a comand that can get the balance of specified address. without the address argument it prints the confirmed, unconfirmed and immature balance of the whole wallet.")
+	fmt.Println("    listtransactions  list the transactions received and sent by the wallet. use -count and -skip.")
+	fmt.Println("    getwalletinfo     print the balances, transaction count and status of the wallet.")
 	fmt.Println("    getlastblock      get the lastest block data.")
 	fmt.Println("    getallblock       return all blocks data to user.")
-	fmt.Println("    getnewaddress     this command use to create a new address by bition algorithm.")
+	fmt.Println("    getnewaddress     this command use to create a new address by bition algorithm. use the curve argument to choose p256, secp256k1 or schnorr, and the label argument to label it.")
+	fmt.Println("    createmusigaddress  create a MuSig aggregate address from schnorr addresses in the wallet(-addresses) and hex schnorr pubkeys of other participants(-pubkeys). participants outside the wallet sign through a psbt: signpsbt adds nonces first and partial signatures once every nonce is present.")
+	fmt.Println("    createmultisig    create an M-of-N multisig address. use the m argument and the pubkeys argument(hex pubkeys or wallet addresses), add -p2sh for a P2SH address.")
+	fmt.Println("    addredeemscript   import a hex redeem script into the wallet and print its P2SH address.")
+	fmt.Println("    createtimelockaddress  create a P2SH address that locks funds of an address. use -locktime for an absolute lock or -sequence for a relative lock in blocks.")
+	fmt.Println("    initiateswap      create an atomic swap contract and fund it. use -from, -to and -amount, add -secrethash to participate in a swap started on another chain.")
+	fmt.Println("    redeemswap        redeem an atomic swap contract with the secret. use -contract and -secret.")
+	fmt.Println("    refundswap        refund an atomic swap contract after its lock time. use -contract.")
+	fmt.Println("    auditswap         print the details and balance of an atomic swap contract, and the secret once it has been redeemed.")
+	fmt.Println("    senddata          anchor data on the chain in an unspendable output. use -from and -data, add -hex when the data is hex encoded and -mempool to leave the transaction in the mempool.")
+	fmt.Println("    getblockdata      list the data carried by the outputs of a block. use the height argument.")
+	fmt.Println("    listunspent       list the spendable outputs of an address. use the address argument.")
+	fmt.Println("    createrawtransaction  create an unsigned raw transaction. use -inputs [{\"txid\",\"vout\"}] and -outputs [{\"address\",\"amount\"} or {\"data\"}], add -replaceable to allow fee bumping.")
+	fmt.Println("    decoderawtransaction  print the details of a hex encoded raw transaction. use the hex argument.")
+	fmt.Println("    signrawtransactionwithwallet  sign a raw transaction with the keys in the wallet. use -hex and optional -prevtxs and -sighashtype(ALL, NONE, SINGLE, optionally |ANYONECANPAY).")
+	fmt.Println("    signrawtransactionwithkey     sign a raw transaction with hex private keys, works offline with -prevtxs. use -hex, -privkeys, -curve and optional -sighashtype.")
+	fmt.Println("    sendrawtransaction    send a signed raw transaction and pack it into a new block. use the hex argument, add -mempool to leave it unconfirmed in the mempool.")
+	fmt.Println("    getmempool        list the unconfirmed transactions in the mempool with their fees.")
+	fmt.Println("    generateblock     pack the mempool transactions with the highest package fee rates into a new block.")
+	fmt.Println("    estimatefee       estimate the fee rate needed to confirm within the given number of blocks. use the blocks argument.")
+	fmt.Println("    bumpfee           replace a replaceable wallet transaction in the mempool with a higher fee version. use -txid and optional -feerate.")
+	fmt.Println("    createpsbt        create a partially signed transaction file. use -inputs, -outputs and -out like createrawtransaction.")
+	fmt.Println("    decodepsbt        print a partially signed transaction and the signing progress of each input. use the file argument.")
+	fmt.Println("    updatepsbt        add the spent outputs and redeem scripts known to this node. use the file argument.")
+	fmt.Println("    signpsbt          sign a partially signed transaction with the keys in the wallet. use -file and optional -out and -sighashtype.")
+	fmt.Println("    combinepsbt       merge partially signed transactions signed by different wallets. use -files and -out.")
+	fmt.Println("    finalizepsbt      build the fully signed transaction, add -send to send it. use the file argument.")
+	fmt.Println("    encryptwallet     encrypt the private keys in the wallet with a passphrase, the wallet is locked afterwards. use the passphrase argument or type it on standard input.")
+	fmt.Println("    walletpassphrasechange  change the passphrase of the wallet. use -old and -new, or type both lines on standard input.")
+	fmt.Println("    dumpmnemonic      print the mnemonic of the HD wallet, write it down to back up all addresses.")
+	fmt.Println("    restorewallet     restore the HD wallet from a mnemonic and find the addresses that received funds. use -mnemonic, add -name to restore into a new wallet or -replace to replace the seed of the current wallet after confirmation.")
+	fmt.Println("    importprivkey     import a WIF private key into the wallet. use the privkey argument, add -rescan=false to skip the balance scan.")
+	fmt.Println("    importaddress     watch an address without its private key, listaddress shows its balance. use the address argument and optional -rescan.")
+	fmt.Println("    getpubkey         print the public key of an address in the wallet, used to build multisig addresses.")
+	fmt.Println("    createwallet      create a new named wallet stored apart from the other wallets, the wallet is loaded afterwards. use the name argument.")
+	fmt.Println("    loadwallet        load an existing wallet so that it can be used and follows new blocks. use the name argument.")
+	fmt.Println("    unloadwallet      unload a wallet, it can not be used until it is loaded again. use the name argument.")
+	fmt.Println("    listwallets       list the loaded wallets.")
+	fmt.Println("    setlabel          set the label of an address, an empty label removes it. use -address and -label.")
+	fmt.Println("    getaddressesbylabel  list the addresses with the given label. use the label argument.")
+	fmt.Println("    getaddressinfo    print the label, purpose(receive or change), creation time, key and balance of an address. use the address argument.")
 	fmt.Println("    help              use the command can print usage infomation.")
 	fmt.Println()
+	fmt.Println("Wallet commands accept -wallet name to choose the wallet, the default wallet is used without it.")
+	fmt.Println("Wallet commands unlock an encrypted wallet for that command only, with the passphrase from the " + PASSPHRASEENV + " environment variable, or from the first line of standard input with -stdinpassphrase.")
+	fmt.Println()
 	fmt.Println("Use go run main.go help [command] for more information about a command.")
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package client
 const (
 	GENERATEGENSIS = "generategensis"
     SENDTRANSACTION = "sendtransaction"
+    SENDMANY = "sendmany"//在一笔交易中向多个地址批量转账
     GETBALANCE = "getbalance"
     GETLASTBLOCK = "getlastblock"
     GETALLBLOKCS = "getallblocks"
@@ -11,6 +12,49 @@ const (
     DUMPPRIVKEY = "dumpprivkey"//导出某个地址的私钥
     SETCOINBASE = "setcoinbase"//设置挖矿矿工的地址
     GETCOINBASE = "getcoinbase"//查看当前节点所设置的矿工地址
+    CREATEMUSIGADDRESS = "createmusigaddress"//使用多个schnorr地址生成MuSig聚合地址
+    CREATEMULTISIG = "createmultisig"//使用多个公钥生成M-of-N多重签名地址
+    GETPUBKEY = "getpubkey"//导出某个地址的原始公钥
+    ADDREDEEMSCRIPT = "addredeemscript"//导入赎回脚本并生成对应的P2SH地址
+    CREATETIMELOCKADDRESS = "createtimelockaddress"//生成带时间锁的P2SH地址
+    INITIATESWAP = "initiateswap"//创建原子交换合约并转入资金
+    REDEEMSWAP = "redeemswap"//使用秘密值领取原子交换合约中的资金
+    REFUNDSWAP = "refundswap"//锁定时间到期后取回原子交换合约中的资金
+    AUDITSWAP = "auditswap"//审计原子交换合约
+    SENDDATA = "senddata"//把数据写入链上的数据输出中
+    GETBLOCKDATA = "getblockdata"//查询某个区块中数据输出所携带的数据
+    LISTUNSPENT = "listunspent"//列出某个地址可以花费的utxo
+    CREATERAWTRANSACTION = "createrawtransaction"//使用指定的输入和输出构建未签名的原始交易
+    DECODERAWTRANSACTION = "decoderawtransaction"//解码十六进制的原始交易
+    SIGNRAWTRANSACTIONWITHWALLET = "signrawtransactionwithwallet"//使用本地钱包对原始交易签名
+    SIGNRAWTRANSACTIONWITHKEY = "signrawtransactionwithkey"//使用给定的私钥对原始交易签名
+    SENDRAWTRANSACTION = "sendrawtransaction"//发送已签名的原始交易
+    GETMEMPOOL = "getmempool"//列出交易池中的未确认交易
+    GENERATEBLOCK = "generateblock"//把交易池中的交易打包进新区块
+    BUMPFEE = "bumpfee"//提高交易池中钱包交易的手续费
+    ESTIMATEFEE = "estimatefee"//估算交易在指定区块个数内被确认所需的手续费率
+    CREATEPSBT = "createpsbt"//创建部分签名交易并保存到文件
+    DECODEPSBT = "decodepsbt"//查看部分签名交易的详细信息
+    UPDATEPSBT = "updatepsbt"//为部分签名交易补充utxo和赎回脚本
+    SIGNPSBT = "signpsbt"//使用本地钱包对部分签名交易签名
+    COMBINEPSBT = "combinepsbt"//合并多个参与方签名后的部分签名交易
+    FINALIZEPSBT = "finalizepsbt"//生成完整签名的交易，可以直接发送
+    ENCRYPTWALLET = "encryptwallet"//使用密码加密钱包中的私钥
+    WALLETPASSPHRASECHANGE = "walletpassphrasechange"//修改钱包的密码
+    DUMPMNEMONIC = "dumpmnemonic"//导出HD钱包的助记词
+    RESTOREWALLET = "restorewallet"//使用助记词恢复HD钱包
+    IMPORTPRIVKEY = "importprivkey"//导入WIF格式的私钥
+    IMPORTADDRESS = "importaddress"//导入没有私钥的观察地址
+    LISTTRANSACTIONS = "listtransactions"//列出钱包的交易记录
+    GETWALLETINFO = "getwalletinfo"//查看钱包的余额和状态
+    CREATEWALLET = "createwallet"//创建一个新的命名钱包
+    LOADWALLET = "loadwallet"//加载一个已经存在的钱包
+    UNLOADWALLET = "unloadwallet"//卸载一个已经加载的钱包
+    LISTWALLETS = "listwallets"//列出所有已经加载的钱包
+    SETLABEL = "setlabel"//为地址设置标签
+    GETADDRESSESBYLABEL = "getaddressesbylabel"//列出使用某个标签的所有地址
+    GETADDRESSINFO = "getaddressinfo"//查看地址的标签、用途、创建时间等信息
     HELP = "help"
 )
 
+const PASSPHRASEENV = "XFWALLETPASSPHRASE"//提供加密钱包密码的环境变量名
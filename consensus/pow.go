@@ -58,8 +58,8 @@ func CalculateHash(block BlockInterface, nonce int64) [32]byte {
 	txs := block.GetTransaction()
 	txsBytes := make([]byte, 0)
 	for _, tx := range txs {
-		//struct -> []byte
-		txData, err := utils.Encoder(tx)
+		//struct -> []byte，使用交易的确定性二进制序列化格式
+		txData, err := tx.Serialize()
 		if err != nil {
 			break
 		}
@@ -23,7 +23,7 @@ func main() {
     	fmt.Println(err.Error())
 		return
 	}
-    cmdClient := client.CmdClient{*blockChain}
+    cmdClient := client.CmdClient{Chain: *blockChain}
 
     //cmdClient.Help()
     cmdClient.Run()
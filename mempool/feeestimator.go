@@ -0,0 +1,152 @@
+package mempool
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"encoding/gob"
+	"github.com/boltdb/bolt"
+)
+
+const FEESTATS = "feestats"           //存放手续费统计样本的桶名
+const FEESAMPLES = "samples"          //手续费统计样本的键名
+const FEESTATSMAXAGE = 1008           //只保留最近多少个区块内确认的交易样本
+const FEEBUCKETMIN = 0.000001         //最低一档手续费率区间的下限（每字节）
+const FEEBUCKETSPACING = 1.1          //相邻两档手续费率区间下限的倍数
+const FEEBUCKETCOUNT = 100            //手续费率区间的个数
+const SUFFICIENTFEESAMPLES = 4        //判断一组手续费率区间的确认成功率时至少需要的样本数
+const FEESUCCESSTHRESHOLD = 0.85      //在目标区块数内被确认的交易比例不低于该值时，认为该手续费率足够
+
+/**
+ *手续费统计样本：交易的手续费率以及从进入交易池到被确认所经过的区块个数
+ */
+type FeeSample struct {
+	FeeRate float64
+	Blocks  int64 //交易从进入交易池到被确认所经过的区块个数
+	Height  int64 //交易被确认时所在区块的高度
+}
+
+/**
+ *保存新确认交易的手续费统计样本，并删除height之前FEESTATSMAXAGE个区块以前的旧样本
+ */
+func (pool *MemPool) RecordFeeSamples(samples []FeeSample, height int64) error {
+	return pool.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(FEESTATS))
+		if err != nil {
+			return err
+		}
+		existSamples, err := decodeFeeSamples(bucket.Get([]byte(FEESAMPLES)))
+		if err != nil {
+			return err
+		}
+		remainSamples := make([]FeeSample, 0, len(existSamples)+len(samples))
+		for _, sample := range append(existSamples, samples...) {
+			if sample.Height > height-FEESTATSMAXAGE {
+				remainSamples = append(remainSamples, sample)
+			}
+		}
+		samplesBytes, err := utils.Encoder(remainSamples)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(FEESAMPLES), samplesBytes)
+	})
+}
+
+/**
+ *获取所有的手续费统计样本
+ */
+func (pool *MemPool) GetFeeSamples() ([]FeeSample, error) {
+	var samples []FeeSample
+	err := pool.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(FEESTATS))
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		samples, err = decodeFeeSamples(bucket.Get([]byte(FEESAMPLES)))
+		return err
+	})
+	return samples, err
+}
+
+/**
+ *根据已确认交易的样本和交易池中仍未确认的交易，估算在target个区块内被确认所需的手续费率：
+ *把样本按手续费率分到不同的区间，从最高的区间开始向低处合并，每凑够SUFFICIENTFEESAMPLES个样本检查一次
+ *在target个区块内被确认的比例，比例不低于FEESUCCESSTHRESHOLD时记录这组区间中最低的手续费率并继续向低处检查，
+ *否则停止。在交易池中等待超过target个区块的交易视为未能按时确认。数据不足时返回false
+ */
+func EstimateFeeRate(samples []FeeSample, pending []TxEntry, height int64, target int64) (float64, bool) {
+	confirmed := make([]int, FEEBUCKETCOUNT)
+	total := make([]int, FEEBUCKETCOUNT)
+	lowest := make([]float64, FEEBUCKETCOUNT)
+	add := func(feeRate float64, inTime bool) {
+		index := feeBucketIndex(feeRate)
+		if index < 0 {
+			return
+		}
+		if total[index] == 0 || feeRate < lowest[index] {
+			lowest[index] = feeRate
+		}
+		total[index]++
+		if inTime {
+			confirmed[index]++
+		}
+	}
+	for _, sample := range samples {
+		add(sample.FeeRate, sample.Blocks <= target)
+	}
+	for _, entry := range pending {
+		if height-entry.Height >= target {
+			add(entry.FeeRate(), false)
+		}
+	}
+
+	var estimate float64
+	found := false
+	groupConfirmed, groupTotal := 0, 0
+	var groupLowest float64
+	for index := FEEBUCKETCOUNT - 1; index >= 0; index-- {
+		if total[index] == 0 {
+			continue
+		}
+		groupConfirmed += confirmed[index]
+		groupTotal += total[index]
+		groupLowest = lowest[index]
+		if groupTotal < SUFFICIENTFEESAMPLES {
+			continue
+		}
+		if float64(groupConfirmed)/float64(groupTotal) < FEESUCCESSTHRESHOLD {
+			break
+		}
+		estimate, found = groupLowest, true
+		groupConfirmed, groupTotal = 0, 0
+	}
+	return estimate, found
+}
+
+/**
+ *计算手续费率所在的区间下标，低于最低区间时返回-1，高于最高区间时归入最高区间
+ */
+func feeBucketIndex(feeRate float64) int {
+	if feeRate < FEEBUCKETMIN {
+		return -1
+	}
+	index := 0
+	for bound := FEEBUCKETMIN * FEEBUCKETSPACING; feeRate >= bound && index < FEEBUCKETCOUNT-1; bound *= FEEBUCKETSPACING {
+		index++
+	}
+	return index
+}
+
+/**
+ *反序列化手续费统计样本
+ */
+func decodeFeeSamples(data []byte) ([]FeeSample, error) {
+	samples := make([]FeeSample, 0)
+	if len(data) == 0 {
+		return samples, nil
+	}
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&samples)
+	return samples, err
+}
@@ -0,0 +1,68 @@
+package mempool
+
+import "testing"
+
+/**
+ *构建count个在高度10被确认的相同手续费样本
+ */
+func newTestSamples(count int, feeRate float64, blocks int64) []FeeSample {
+	samples := make([]FeeSample, 0, count)
+	for i := 0; i < count; i++ {
+		samples = append(samples, FeeSample{FeeRate: feeRate, Blocks: blocks, Height: 10})
+	}
+	return samples
+}
+
+func TestEstimateFeeRateGroupsBuckets(t *testing.T) {
+	//每个区间只有两个样本，合并相邻区间凑够SUFFICIENTFEESAMPLES个样本后取其中最低的手续费率
+	samples := append(newTestSamples(2, 0.0001, 1), newTestSamples(2, 0.00005, 1)...)
+	feeRate, ok := EstimateFeeRate(samples, nil, 10, 1)
+	if !ok || feeRate != 0.00005 {
+		t.Fatalf("合并区间后的估算结果不正确：%.8f %t", feeRate, ok)
+	}
+	if _, ok = EstimateFeeRate(samples[:SUFFICIENTFEESAMPLES-1], nil, 10, 1); ok {
+		t.Fatal("样本不足时不应当给出估算结果")
+	}
+	//低于最低区间的样本不参与统计
+	if _, ok = EstimateFeeRate(newTestSamples(10, FEEBUCKETMIN/2, 1), nil, 10, 1); ok {
+		t.Fatal("低于最低区间的样本不应当参与估算")
+	}
+}
+
+func TestEstimateFeeRateThreshold(t *testing.T) {
+	//0.00002一档只有3/4的交易在1个区块内被确认，低于FEESUCCESSTHRESHOLD
+	samples := newTestSamples(10, 0.0001, 1)
+	samples = append(samples, newTestSamples(3, 0.00002, 1)...)
+	samples = append(samples, newTestSamples(1, 0.00002, 5)...)
+	feeRate, ok := EstimateFeeRate(samples, nil, 10, 1)
+	if !ok || feeRate != 0.0001 {
+		t.Fatalf("确认比例不足的区间不应当被采用：%.8f %t", feeRate, ok)
+	}
+	//目标区块数放宽到5后所有交易都按时确认
+	feeRate, ok = EstimateFeeRate(samples, nil, 10, 5)
+	if !ok || feeRate != 0.00002 {
+		t.Fatalf("放宽目标区块数后的估算结果不正确：%.8f %t", feeRate, ok)
+	}
+
+	//在交易池中等待超过目标区块数的交易视为未能按时确认
+	samples = append(newTestSamples(10, 0.0001, 1), newTestSamples(4, 0.00002, 1)...)
+	pending := []TxEntry{{Fee: 0.002, Size: 100, Height: 2}}
+	if feeRate, ok = EstimateFeeRate(samples, nil, 10, 1); !ok || feeRate != 0.00002 {
+		t.Fatalf("没有等待中的交易时估算结果不正确：%.8f %t", feeRate, ok)
+	}
+	if feeRate, ok = EstimateFeeRate(samples, pending, 10, 1); !ok || feeRate != 0.0001 {
+		t.Fatalf("长时间未确认的交易应当降低该区间的确认比例：%.8f %t", feeRate, ok)
+	}
+}
+
+func TestFeeBucketIndex(t *testing.T) {
+	if feeBucketIndex(FEEBUCKETMIN/2) != -1 || feeBucketIndex(FEEBUCKETMIN) != 0 {
+		t.Fatal("最低区间的下标不正确")
+	}
+	if feeBucketIndex(1) != FEEBUCKETCOUNT-1 {
+		t.Fatal("过高的手续费率应当归入最高区间")
+	}
+	if feeBucketIndex(0.0001) == feeBucketIndex(0.00005) {
+		t.Fatal("相差一倍的手续费率应当位于不同的区间")
+	}
+}
@@ -0,0 +1,180 @@
+package mempool
+
+import (
+	"XianfengChain04/transaction"
+	"XianfengChain04/utils"
+	"bytes"
+	"errors"
+	"github.com/boltdb/bolt"
+)
+
+const MEMPOOL = "mempool" //存放未确认交易的桶名：txid -> 交易条目
+
+/**
+ *交易池中的一个未确认交易条目，记录交易的手续费、大小以及进入交易池的时间
+ */
+type TxEntry struct {
+	Tx     transaction.Transaction
+	Fee    float64 //交易的手续费
+	Size   int     //交易序列化后的字节数
+	Time   int64   //交易进入交易池的时间
+	Height int64   //交易进入交易池时区块链的高度，用于统计交易经过多少个区块才被确认
+}
+
+/**
+ *计算交易条目的手续费率，即每字节支付的手续费
+ */
+func (entry TxEntry) FeeRate() float64 {
+	if entry.Size == 0 {
+		return 0
+	}
+	return entry.Fee / float64(entry.Size)
+}
+
+/**
+ *序列化交易条目：交易数据 + 手续费 + 进入交易池的时间 + 进入交易池时的高度，交易大小由交易数据的长度得到
+ */
+func (entry TxEntry) Serialize() ([]byte, error) {
+	txBytes, err := entry.Tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	buff := new(bytes.Buffer)
+	utils.WriteVarBytes(buff, txBytes)
+	utils.WriteFloat64(buff, entry.Fee)
+	utils.WriteInt64(buff, entry.Time)
+	utils.WriteInt64(buff, entry.Height)
+	return buff.Bytes(), nil
+}
+
+/**
+ *反序列化交易条目
+ */
+func DeserializeEntry(data []byte) (TxEntry, error) {
+	var entry TxEntry
+	reader := bytes.NewReader(data)
+	txBytes, err := utils.ReadVarBytes(reader)
+	if err != nil {
+		return entry, err
+	}
+	tx, err := transaction.DeserializeTransaction(txBytes)
+	if err != nil {
+		return entry, err
+	}
+	entry.Tx = *tx
+	entry.Size = len(txBytes)
+	if entry.Fee, err = utils.ReadFloat64(reader); err != nil {
+		return entry, err
+	}
+	if entry.Time, err = utils.ReadInt64(reader); err != nil {
+		return entry, err
+	}
+	if entry.Height, err = utils.ReadInt64(reader); err != nil {
+		return entry, err
+	}
+	if reader.Len() != 0 {
+		return entry, errors.New("交易条目数据中存在多余的字节")
+	}
+	return entry, nil
+}
+
+/**
+ *交易池，保存已通过验证但还未被打包进区块的交易
+ */
+type MemPool struct {
+	Engine *bolt.DB //bolt.db对象
+}
+
+/**
+ *构建一个交易池结构体实例并返回
+ */
+func NewMemPool(db *bolt.DB) MemPool {
+	return MemPool{
+		Engine: db,
+	}
+}
+
+/**
+ *把交易条目保存到交易池中
+ */
+func (pool *MemPool) AddEntry(entry TxEntry) error {
+	entryBytes, err := entry.Serialize()
+	if err != nil {
+		return err
+	}
+	return pool.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(MEMPOOL))
+		if err != nil {
+			return err
+		}
+		return bucket.Put(entry.Tx.TxHash[:], entryBytes)
+	})
+}
+
+/**
+ *根据交易哈希查询交易池中的交易条目，未找到时返回nil
+ */
+func (pool *MemPool) GetEntry(txId [32]byte) (*TxEntry, error) {
+	var entry *TxEntry
+	err := pool.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(MEMPOOL))
+		if bucket == nil {
+			return nil
+		}
+		entryBytes := bucket.Get(txId[:])
+		if len(entryBytes) == 0 {
+			return nil
+		}
+		found, err := DeserializeEntry(entryBytes)
+		if err != nil {
+			return err
+		}
+		entry = &found
+		return nil
+	})
+	return entry, err
+}
+
+/**
+ *获取交易池中的所有交易条目，按进入交易池的时间排列
+ */
+func (pool *MemPool) GetEntries() ([]TxEntry, error) {
+	entries := make([]TxEntry, 0)
+	err := pool.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(MEMPOOL))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			entry, err := DeserializeEntry(v)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	sortEntriesByTime(entries)
+	return entries, err
+}
+
+/**
+ *从交易池中删除指定的交易
+ */
+func (pool *MemPool) RemoveEntries(txIds [][32]byte) error {
+	if len(txIds) == 0 {
+		return nil
+	}
+	return pool.Engine.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(MEMPOOL))
+		if bucket == nil {
+			return nil
+		}
+		for _, txId := range txIds {
+			if err := bucket.Delete(txId[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package mempool
+
+import (
+	"XianfengChain04/transaction"
+	"sort"
+)
+
+/**
+ *找出交易池中与tx花费了同一个utxo的交易条目
+ */
+func Conflicts(entries []TxEntry, tx transaction.Transaction) []TxEntry {
+	conflicts := make([]TxEntry, 0)
+	for _, entry := range entries {
+		if entry.Tx.TxHash == tx.TxHash {
+			continue
+		}
+		if spendsSameUTXO(entry.Tx, tx) {
+			conflicts = append(conflicts, entry)
+		}
+	}
+	return conflicts
+}
+
+/**
+ *找出交易池中直接或间接花费了txIds所产生的输出的所有后代交易条目，不包含txIds本身
+ */
+func Descendants(entries []TxEntry, txIds [][32]byte) []TxEntry {
+	found := make(map[[32]byte]bool)
+	for _, txId := range txIds {
+		found[txId] = true
+	}
+	descendants := make([]TxEntry, 0)
+	//每一轮把花费了已找到交易的输出的交易加入结果，直到没有新的后代为止
+	for changed := true; changed; {
+		changed = false
+		for _, entry := range entries {
+			if found[entry.Tx.TxHash] {
+				continue
+			}
+			for _, input := range entry.Tx.Inputs {
+				if found[input.TxId] {
+					found[entry.Tx.TxHash] = true
+					descendants = append(descendants, entry)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return descendants
+}
+
+/**
+ *按照交易包的手续费率从交易池中挑选要打包进区块的交易：
+ *一笔交易与它在交易池中尚未被挑选的所有祖先交易组成一个交易包，每次挑选手续费率最高的交易包，
+ *因此手续费率高的子交易可以带动手续费率低的父交易一起被打包。
+ *交易包的手续费率低于minFeeRate时停止挑选，放不进剩余区块空间的交易包被跳过，
+ *返回的交易中父交易总是排在子交易的前面
+ */
+func SelectPackages(entries []TxEntry, maxSize int, minFeeRate float64) []TxEntry {
+	byId := make(map[[32]byte]TxEntry)
+	for _, entry := range entries {
+		byId[entry.Tx.TxHash] = entry
+	}
+	selected := make(map[[32]byte]bool)
+	skipped := make(map[[32]byte]bool)
+	result := make([]TxEntry, 0)
+	blockSize := 0
+	for {
+		var best []TxEntry
+		var bestRate float64
+		for _, entry := range entries {
+			if selected[entry.Tx.TxHash] || skipped[entry.Tx.TxHash] {
+				continue
+			}
+			pkg := ancestorPackage(entry, byId, selected)
+			fee, size := packageFeeAndSize(pkg)
+			rate := fee / float64(size)
+			if best == nil || rate > bestRate {
+				best, bestRate = pkg, rate
+			}
+		}
+		if best == nil || bestRate < minFeeRate {
+			break
+		}
+		_, size := packageFeeAndSize(best)
+		if blockSize+size > maxSize {
+			//包含该交易的交易包都放不进区块，之后不再考虑
+			skipped[best[len(best)-1].Tx.TxHash] = true
+			continue
+		}
+		for _, entry := range best {
+			selected[entry.Tx.TxHash] = true
+			result = append(result, entry)
+		}
+		blockSize += size
+	}
+	return result
+}
+
+/**
+ *获取entry与它在交易池中尚未被挑选的所有祖先交易组成的交易包，父交易排在前面，entry排在最后
+ */
+func ancestorPackage(entry TxEntry, byId map[[32]byte]TxEntry, selected map[[32]byte]bool) []TxEntry {
+	pkg := make([]TxEntry, 0)
+	visited := make(map[[32]byte]bool)
+	var visit func(current TxEntry)
+	visit = func(current TxEntry) {
+		visited[current.Tx.TxHash] = true
+		for _, input := range current.Tx.Inputs {
+			parent, ok := byId[input.TxId]
+			if !ok || selected[input.TxId] || visited[input.TxId] {
+				continue
+			}
+			visit(parent)
+		}
+		pkg = append(pkg, current)
+	}
+	visit(entry)
+	return pkg
+}
+
+/**
+ *计算交易包的手续费之和与大小之和
+ */
+func packageFeeAndSize(pkg []TxEntry) (float64, int) {
+	var fee float64
+	size := 0
+	for _, entry := range pkg {
+		fee += entry.Fee
+		size += entry.Size
+	}
+	return fee, size
+}
+
+/**
+ *判断两笔交易是否花费了同一个utxo
+ */
+func spendsSameUTXO(a transaction.Transaction, b transaction.Transaction) bool {
+	for _, inputA := range a.Inputs {
+		for _, inputB := range b.Inputs {
+			if inputA.TxId == inputB.TxId && inputA.Vout == inputB.Vout {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/**
+ *把交易条目按照进入交易池的时间排序
+ */
+func sortEntriesByTime(entries []TxEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time < entries[j].Time
+	})
+}
@@ -0,0 +1,55 @@
+package mempool
+
+import (
+	"XianfengChain04/transaction"
+	"testing"
+)
+
+/**
+ *构建一个花费parent交易第0个输出的测试交易条目
+ */
+func newTestEntry(id byte, parent byte, fee float64, size int) TxEntry {
+	tx := transaction.Transaction{TxHash: [32]byte{id}}
+	tx.Inputs = []transaction.TxInput{{TxId: [32]byte{parent}, Vout: 0}}
+	return TxEntry{Tx: tx, Fee: fee, Size: size, Time: int64(id)}
+}
+
+func TestSelectPackagesChildPaysForParent(t *testing.T) {
+	//父交易的手续费率低于minFeeRate，由高手续费率的子交易带动一起打包
+	parent := newTestEntry(1, 100, 0.0001, 100)
+	child := newTestEntry(2, 1, 0.01, 100)
+	lone := newTestEntry(3, 101, 0.0001, 100)
+	selected := SelectPackages([]TxEntry{child, parent, lone}, 1000, 0.00001)
+	if len(selected) != 2 || selected[0].Tx.TxHash != parent.Tx.TxHash || selected[1].Tx.TxHash != child.Tx.TxHash {
+		t.Fatalf("交易包的挑选结果不正确：%+v", selected)
+	}
+}
+
+func TestSelectPackagesSkipsOversized(t *testing.T) {
+	parent := newTestEntry(1, 100, 0.0001, 100)
+	child := newTestEntry(2, 1, 0.01, 100)
+	small := newTestEntry(3, 101, 0.0005, 50)
+	//父子交易组成的交易包放不进剩余的区块空间，跳过后继续挑选较小的交易包
+	selected := SelectPackages([]TxEntry{child, parent, small}, 150, 0.00001)
+	if len(selected) != 1 || selected[0].Tx.TxHash != small.Tx.TxHash {
+		t.Fatalf("放不进区块的交易包应当被跳过：%+v", selected)
+	}
+	if selected = SelectPackages([]TxEntry{child, parent}, 150, 0.00001); len(selected) != 0 {
+		t.Fatalf("子交易不能在父交易之外被单独打包：%+v", selected)
+	}
+}
+
+func TestDescendantsAndConflicts(t *testing.T) {
+	parent := newTestEntry(1, 100, 0.0001, 100)
+	child := newTestEntry(2, 1, 0.01, 100)
+	lone := newTestEntry(3, 101, 0.0001, 100)
+	entries := []TxEntry{child, parent, lone}
+	descendants := Descendants(entries, [][32]byte{parent.Tx.TxHash})
+	if len(descendants) != 1 || descendants[0].Tx.TxHash != child.Tx.TxHash {
+		t.Fatalf("后代交易不正确：%+v", descendants)
+	}
+	conflicts := Conflicts(entries, newTestEntry(9, 100, 0, 1).Tx)
+	if len(conflicts) != 1 || conflicts[0].Tx.TxHash != parent.Tx.TxHash {
+		t.Fatalf("冲突交易不正确：%+v", conflicts)
+	}
+}
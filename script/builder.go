@@ -0,0 +1,114 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+/**
+ *脚本构建器，按顺序追加操作码和数据，数据压入自动选择最短的编码方式
+ */
+type Builder struct {
+	script []byte
+}
+
+/**
+ *构建一个空的脚本构建器
+ */
+func NewBuilder() *Builder {
+	return &Builder{script: make([]byte, 0)}
+}
+
+/**
+ *追加一个操作码
+ */
+func (builder *Builder) AddOp(opcode byte) *Builder {
+	builder.script = append(builder.script, opcode)
+	return builder
+}
+
+/**
+ *追加一个数据压入操作
+ */
+func (builder *Builder) AddData(data []byte) *Builder {
+	length := len(data)
+	switch {
+	case length == 0:
+		builder.script = append(builder.script, OP_0)
+		return builder
+	case length == 1 && data[0] >= 1 && data[0] <= 16:
+		builder.script = append(builder.script, OP_1+data[0]-1)
+		return builder
+	case length == 1 && data[0] == 0x81:
+		builder.script = append(builder.script, OP_1NEGATE)
+		return builder
+	case length < OP_PUSHDATA1:
+		builder.script = append(builder.script, byte(length))
+	case length <= 0xff:
+		builder.script = append(builder.script, OP_PUSHDATA1, byte(length))
+	case length <= 0xffff:
+		lenBytes := make([]byte, 2)
+		binary.LittleEndian.PutUint16(lenBytes, uint16(length))
+		builder.script = append(append(builder.script, OP_PUSHDATA2), lenBytes...)
+	default:
+		lenBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(lenBytes, uint32(length))
+		builder.script = append(append(builder.script, OP_PUSHDATA4), lenBytes...)
+	}
+	builder.script = append(builder.script, data...)
+	return builder
+}
+
+/**
+ *追加一个数字，0和1-16使用对应的小数字操作码
+ */
+func (builder *Builder) AddInt64(value int64) *Builder {
+	switch {
+	case value == 0:
+		return builder.AddOp(OP_0)
+	case value == -1:
+		return builder.AddOp(OP_1NEGATE)
+	case value >= 1 && value <= 16:
+		return builder.AddOp(byte(OP_1 + value - 1))
+	}
+	return builder.AddData(scriptNum(value).Bytes())
+}
+
+/**
+ *返回构建完成的脚本
+ */
+func (builder *Builder) Script() []byte {
+	return builder.script
+}
+
+/**
+ *把脚本反汇编为可读的文本，数据以十六进制显示，解析失败时在末尾标注错误
+ */
+func Disasm(script []byte) string {
+	ops, err := parseScript(script)
+	if err != nil {
+		return "[error: " + err.Error() + "]"
+	}
+	items := make([]string, 0, len(ops))
+	for _, op := range ops {
+		switch {
+		case op.opcode == OP_0:
+			items = append(items, "0")
+		case op.opcode == OP_1NEGATE:
+			items = append(items, "-1")
+		case op.opcode >= OP_1 && op.opcode <= OP_16:
+			items = append(items, strconv.Itoa(int(op.opcode-OP_1+1)))
+		case isPushOp(op.opcode):
+			items = append(items, hex.EncodeToString(op.data))
+		default:
+			name, ok := opcodeNames[op.opcode]
+			if !ok {
+				name = "OP_UNKNOWN" + hex.EncodeToString([]byte{op.opcode})
+			}
+			items = append(items, name)
+		}
+	}
+	return strings.Join(items, " ")
+}
@@ -0,0 +1,602 @@
+package script
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"errors"
+)
+
+/**
+ *脚本执行的资源限制，防止恶意脚本消耗过多的计算和内存资源
+ */
+const (
+	MAXSCRIPTSIZE         = 10000 //单个脚本的最大字节数
+	MAXELEMENTSIZE        = 520   //栈中单个元素的最大字节数
+	MAXSTACKSIZE          = 1000  //主栈和备用栈的元素总数上限
+	MAXOPSPERSCRIPT       = 201   //单个脚本中非数据压入操作码的数量上限
+	MAXPUBKEYSPERMULTISIG = 20    //多重签名的公钥数量上限
+)
+
+/**
+ *时间锁的分界值，小于该值表示区块高度，大于等于该值表示unix时间戳（秒）
+ */
+const LOCKTIMETHRESHOLD = 500000000
+
+/**
+ *交易输入的sequence字段的编码，用于表示相对时间锁：
+ *最高位为1时不启用相对时间锁，第22位为1时表示以512秒为单位的时间，否则表示区块个数，低16位为锁定的数值
+ *所有交易输入的sequence都为SEQUENCEFINAL时，交易的锁定时间不生效
+ */
+const (
+	SEQUENCEFINAL       = 0xffffffff
+	SEQUENCEDISABLEFLAG = 1 << 31
+	SEQUENCETYPEFLAG    = 1 << 22
+	SEQUENCEMASK        = 0x0000ffff
+	SEQUENCEGRANULARITY = 9 //时间类型的相对时间锁以2^9=512秒为单位
+)
+
+/**
+ *签名检查器的接口标准，由交易提供签名验证、绝对时间锁和相对时间锁判断的能力
+ *scriptCode为当前正在执行的锁定脚本，参与签名哈希的计算
+ *allowBatch为true时，检查器可以把签名加入批量验证器延后验证
+ */
+type SigChecker interface {
+	CheckSig(sig []byte, pubk []byte, scriptCode []byte, allowBatch bool) bool
+	CheckLockTime(lockTime int64) bool
+	CheckSequence(sequence int64) bool
+}
+
+/**
+ *脚本执行引擎
+ */
+type Engine struct {
+	stack     [][]byte
+	altStack  [][]byte
+	condStack []bool
+	numOps    int
+	script    []byte
+	checker   SigChecker
+}
+
+/**
+ *验证解锁脚本能否解锁锁定脚本：先执行解锁脚本，再在同一个栈上执行锁定脚本，
+ *执行结束后栈中只能剩下一个元素，并且该元素为true
+ *锁定脚本为P2SH脚本时，锁定脚本执行成功后，还需要使用解锁脚本压入的其余数据执行赎回脚本
+ */
+func VerifyScript(scriptSig []byte, scriptPub []byte, checker SigChecker) error {
+	if !IsPushOnly(scriptSig) {
+		return errors.New("解锁脚本只能包含数据压入操作")
+	}
+	engine := &Engine{checker: checker}
+	if err := engine.Execute(scriptSig); err != nil {
+		return err
+	}
+	//保存解锁脚本执行后的栈，P2SH的赎回脚本在该栈上执行
+	isP2SH := IsPayToScriptHash(scriptPub)
+	var p2shStack [][]byte
+	if isP2SH {
+		p2shStack = make([][]byte, len(engine.stack))
+		copy(p2shStack, engine.stack)
+	}
+	if err := engine.Execute(scriptPub); err != nil {
+		return err
+	}
+	if len(engine.stack) == 0 || !castToBool(engine.stack[len(engine.stack)-1]) {
+		return errors.New("脚本执行结果为false")
+	}
+	if isP2SH {
+		//栈顶为赎回脚本，其哈希已由锁定脚本验证
+		redeemScript := p2shStack[len(p2shStack)-1]
+		engine.stack = p2shStack[:len(p2shStack)-1]
+		engine.altStack = nil
+		if err := engine.Execute(redeemScript); err != nil {
+			return err
+		}
+	}
+	if len(engine.stack) != 1 || !castToBool(engine.stack[0]) {
+		return errors.New("脚本执行结果为false")
+	}
+	return nil
+}
+
+/**
+ *在当前的栈上执行一个脚本
+ */
+func (engine *Engine) Execute(script []byte) error {
+	if len(script) > MAXSCRIPTSIZE {
+		return errors.New("脚本长度超出限制")
+	}
+	ops, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+	engine.script = script
+	engine.numOps = 0
+	engine.condStack = nil
+	for _, op := range ops {
+		if len(op.data) > MAXELEMENTSIZE {
+			return errors.New("压入的数据长度超出限制")
+		}
+		if !isPushOp(op.opcode) {
+			engine.numOps++
+			if engine.numOps > MAXOPSPERSCRIPT {
+				return errors.New("脚本操作码数量超出限制")
+			}
+		}
+		if err := engine.step(op); err != nil {
+			return err
+		}
+		if len(engine.stack)+len(engine.altStack) > MAXSTACKSIZE {
+			return errors.New("栈的元素个数超出限制")
+		}
+	}
+	if len(engine.condStack) != 0 {
+		return errors.New("OP_IF与OP_ENDIF不匹配")
+	}
+	return nil
+}
+
+/**
+ *判断当前是否处于执行状态，所有外层条件分支都为true时才执行
+ */
+func (engine *Engine) isExecuting() bool {
+	for _, cond := range engine.condStack {
+		if !cond {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ *执行一条指令
+ */
+func (engine *Engine) step(op parsedOp) error {
+	executing := engine.isExecuting()
+	//条件分支操作码即使在不执行的分支中也需要处理，以维护条件栈
+	switch op.opcode {
+	case OP_IF, OP_NOTIF:
+		cond := false
+		if executing {
+			value, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			cond = castToBool(value)
+			if op.opcode == OP_NOTIF {
+				cond = !cond
+			}
+		}
+		engine.condStack = append(engine.condStack, cond)
+		return nil
+	case OP_ELSE:
+		if len(engine.condStack) == 0 {
+			return errors.New("OP_ELSE缺少对应的OP_IF")
+		}
+		engine.condStack[len(engine.condStack)-1] = !engine.condStack[len(engine.condStack)-1]
+		return nil
+	case OP_ENDIF:
+		if len(engine.condStack) == 0 {
+			return errors.New("OP_ENDIF缺少对应的OP_IF")
+		}
+		engine.condStack = engine.condStack[:len(engine.condStack)-1]
+		return nil
+	}
+	if !executing {
+		return nil
+	}
+
+	if isPushOp(op.opcode) {
+		if !op.isMinimalPush() {
+			return errors.New("数据压入没有使用最短编码")
+		}
+		switch {
+		case op.opcode == OP_1NEGATE:
+			engine.push(scriptNum(-1).Bytes())
+		case op.opcode >= OP_1 && op.opcode <= OP_16:
+			engine.push(scriptNum(op.opcode - OP_1 + 1).Bytes())
+		default:
+			engine.push(op.data)
+		}
+		return nil
+	}
+
+	switch op.opcode {
+	case OP_NOP:
+		return nil
+	case OP_VERIFY:
+		return engine.verify()
+	case OP_RETURN:
+		return errors.New("脚本执行遇到OP_RETURN，该输出不可花费")
+
+	//栈操作
+	case OP_TOALTSTACK:
+		value, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		engine.altStack = append(engine.altStack, value)
+	case OP_FROMALTSTACK:
+		if len(engine.altStack) == 0 {
+			return errors.New("备用栈为空")
+		}
+		engine.push(engine.altStack[len(engine.altStack)-1])
+		engine.altStack = engine.altStack[:len(engine.altStack)-1]
+	case OP_2DROP:
+		if _, err := engine.popN(2); err != nil {
+			return err
+		}
+	case OP_2DUP:
+		items, err := engine.peekN(2)
+		if err != nil {
+			return err
+		}
+		engine.push(items[0])
+		engine.push(items[1])
+	case OP_IFDUP:
+		value, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+		if castToBool(value) {
+			engine.push(value)
+		}
+	case OP_DEPTH:
+		engine.push(scriptNum(len(engine.stack)).Bytes())
+	case OP_DROP:
+		if _, err := engine.pop(); err != nil {
+			return err
+		}
+	case OP_DUP:
+		value, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+		engine.push(value)
+	case OP_NIP:
+		items, err := engine.popN(2)
+		if err != nil {
+			return err
+		}
+		engine.push(items[1])
+	case OP_OVER:
+		value, err := engine.peek(1)
+		if err != nil {
+			return err
+		}
+		engine.push(value)
+	case OP_ROT:
+		items, err := engine.popN(3)
+		if err != nil {
+			return err
+		}
+		engine.push(items[1])
+		engine.push(items[2])
+		engine.push(items[0])
+	case OP_SWAP:
+		items, err := engine.popN(2)
+		if err != nil {
+			return err
+		}
+		engine.push(items[1])
+		engine.push(items[0])
+	case OP_SIZE:
+		value, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+		engine.push(scriptNum(len(value)).Bytes())
+
+	//比较
+	case OP_EQUAL, OP_EQUALVERIFY:
+		items, err := engine.popN(2)
+		if err != nil {
+			return err
+		}
+		engine.push(boolBytes(bytes.Equal(items[0], items[1])))
+		if op.opcode == OP_EQUALVERIFY {
+			return engine.verify()
+		}
+
+	//算术运算
+	case OP_1ADD, OP_1SUB, OP_NEGATE, OP_ABS, OP_NOT, OP_0NOTEQUAL:
+		return engine.unaryNumOp(op.opcode)
+	case OP_ADD, OP_SUB, OP_BOOLAND, OP_BOOLOR, OP_NUMEQUAL, OP_NUMEQUALVERIFY, OP_NUMNOTEQUAL,
+		OP_LESSTHAN, OP_GREATERTHAN, OP_LESSTHANOREQUAL, OP_GREATERTHANOREQUAL, OP_MIN, OP_MAX:
+		return engine.binaryNumOp(op.opcode)
+	case OP_WITHIN:
+		max, err := engine.popNum(MAXNUMSIZE)
+		if err != nil {
+			return err
+		}
+		min, err := engine.popNum(MAXNUMSIZE)
+		if err != nil {
+			return err
+		}
+		x, err := engine.popNum(MAXNUMSIZE)
+		if err != nil {
+			return err
+		}
+		engine.push(boolBytes(min <= x && x < max))
+
+	//哈希运算
+	case OP_SHA256, OP_HASH160, OP_HASH256:
+		value, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		hash := utils.Hash256(value)
+		if op.opcode == OP_HASH160 {
+			hash = utils.HashRipemd160(hash)
+		} else if op.opcode == OP_HASH256 {
+			hash = utils.Hash256(hash)
+		}
+		engine.push(hash)
+
+	//签名验证
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		return engine.checkSig(op.opcode == OP_CHECKSIGVERIFY)
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		return engine.checkMultiSig(op.opcode == OP_CHECKMULTISIGVERIFY)
+
+	//时间锁
+	case OP_CHECKLOCKTIMEVERIFY:
+		value, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+		lockTime, err := makeScriptNum(value, MAXLOCKTIMENUMSIZE)
+		if err != nil {
+			return err
+		}
+		if lockTime < 0 {
+			return errors.New("时间锁不能为负数")
+		}
+		if !engine.checker.CheckLockTime(int64(lockTime)) {
+			return errors.New("交易未满足时间锁的要求")
+		}
+	case OP_CHECKSEQUENCEVERIFY:
+		value, err := engine.peek(0)
+		if err != nil {
+			return err
+		}
+		sequence, err := makeScriptNum(value, MAXLOCKTIMENUMSIZE)
+		if err != nil {
+			return err
+		}
+		if sequence < 0 {
+			return errors.New("相对时间锁不能为负数")
+		}
+		//脚本中的数值设置了禁用标志时，该操作码等同于OP_NOP
+		if sequence&SEQUENCEDISABLEFLAG != 0 {
+			return nil
+		}
+		if !engine.checker.CheckSequence(int64(sequence)) {
+			return errors.New("交易未满足相对时间锁的要求")
+		}
+	default:
+		return errors.New("不支持的操作码")
+	}
+	return nil
+}
+
+func (engine *Engine) unaryNumOp(opcode byte) error {
+	x, err := engine.popNum(MAXNUMSIZE)
+	if err != nil {
+		return err
+	}
+	switch opcode {
+	case OP_1ADD:
+		x++
+	case OP_1SUB:
+		x--
+	case OP_NEGATE:
+		x = -x
+	case OP_ABS:
+		if x < 0 {
+			x = -x
+		}
+	case OP_NOT:
+		engine.push(boolBytes(x == 0))
+		return nil
+	case OP_0NOTEQUAL:
+		engine.push(boolBytes(x != 0))
+		return nil
+	}
+	engine.push(x.Bytes())
+	return nil
+}
+
+func (engine *Engine) binaryNumOp(opcode byte) error {
+	b, err := engine.popNum(MAXNUMSIZE)
+	if err != nil {
+		return err
+	}
+	a, err := engine.popNum(MAXNUMSIZE)
+	if err != nil {
+		return err
+	}
+	switch opcode {
+	case OP_ADD:
+		engine.push((a + b).Bytes())
+	case OP_SUB:
+		engine.push((a - b).Bytes())
+	case OP_BOOLAND:
+		engine.push(boolBytes(a != 0 && b != 0))
+	case OP_BOOLOR:
+		engine.push(boolBytes(a != 0 || b != 0))
+	case OP_NUMEQUAL, OP_NUMEQUALVERIFY:
+		engine.push(boolBytes(a == b))
+		if opcode == OP_NUMEQUALVERIFY {
+			return engine.verify()
+		}
+	case OP_NUMNOTEQUAL:
+		engine.push(boolBytes(a != b))
+	case OP_LESSTHAN:
+		engine.push(boolBytes(a < b))
+	case OP_GREATERTHAN:
+		engine.push(boolBytes(a > b))
+	case OP_LESSTHANOREQUAL:
+		engine.push(boolBytes(a <= b))
+	case OP_GREATERTHANOREQUAL:
+		engine.push(boolBytes(a >= b))
+	case OP_MIN:
+		if b < a {
+			a = b
+		}
+		engine.push(a.Bytes())
+	case OP_MAX:
+		if b > a {
+			a = b
+		}
+		engine.push(a.Bytes())
+	}
+	return nil
+}
+
+/**
+ *OP_CHECKSIG：弹出公钥和签名进行验证，签名验证失败时签名必须为空，否则脚本直接失败
+ */
+func (engine *Engine) checkSig(isVerify bool) error {
+	items, err := engine.popN(2)
+	if err != nil {
+		return err
+	}
+	sig, pubk := items[0], items[1]
+	success := len(sig) > 0 && engine.checker.CheckSig(sig, pubk, engine.script, true)
+	if !success && len(sig) > 0 {
+		return errors.New("签名验证失败")
+	}
+	engine.push(boolBytes(success))
+	if isVerify {
+		return engine.verify()
+	}
+	return nil
+}
+
+/**
+ *OP_CHECKMULTISIG：栈中依次为 dummy <sig1>...<sigM> M <pubk1>...<pubkN> N
+ *签名需要按照公钥的顺序排列，dummy元素必须为空
+ */
+func (engine *Engine) checkMultiSig(isVerify bool) error {
+	n, err := engine.popNum(MAXNUMSIZE)
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > MAXPUBKEYSPERMULTISIG {
+		return errors.New("多重签名的公钥数量超出限制")
+	}
+	engine.numOps += int(n)
+	if engine.numOps > MAXOPSPERSCRIPT {
+		return errors.New("脚本操作码数量超出限制")
+	}
+	pubks, err := engine.popN(int(n))
+	if err != nil {
+		return err
+	}
+	m, err := engine.popNum(MAXNUMSIZE)
+	if err != nil {
+		return err
+	}
+	if m < 0 || m > n {
+		return errors.New("多重签名的签名数量不正确")
+	}
+	sigs, err := engine.popN(int(m))
+	if err != nil {
+		return err
+	}
+	dummy, err := engine.pop()
+	if err != nil {
+		return err
+	}
+	if len(dummy) != 0 {
+		return errors.New("多重签名的dummy元素必须为空")
+	}
+
+	success := true
+	sigIndex, pubkIndex := 0, 0
+	for sigIndex < len(sigs) {
+		//剩余的公钥数量不足以匹配剩余的签名
+		if len(sigs)-sigIndex > len(pubks)-pubkIndex {
+			success = false
+			break
+		}
+		sig := sigs[sigIndex]
+		if len(sig) > 0 && engine.checker.CheckSig(sig, pubks[pubkIndex], engine.script, false) {
+			sigIndex++
+		}
+		pubkIndex++
+	}
+	if !success {
+		for _, sig := range sigs {
+			if len(sig) > 0 {
+				return errors.New("多重签名验证失败")
+			}
+		}
+	}
+	engine.push(boolBytes(success))
+	if isVerify {
+		return engine.verify()
+	}
+	return nil
+}
+
+func (engine *Engine) verify() error {
+	value, err := engine.pop()
+	if err != nil {
+		return err
+	}
+	if !castToBool(value) {
+		return errors.New("脚本验证失败")
+	}
+	return nil
+}
+
+func (engine *Engine) push(data []byte) {
+	engine.stack = append(engine.stack, data)
+}
+
+func (engine *Engine) pop() ([]byte, error) {
+	if len(engine.stack) == 0 {
+		return nil, errors.New("栈中没有足够的元素")
+	}
+	value := engine.stack[len(engine.stack)-1]
+	engine.stack = engine.stack[:len(engine.stack)-1]
+	return value, nil
+}
+
+/**
+ *弹出栈顶的n个元素，按照压栈的顺序返回
+ */
+func (engine *Engine) popN(n int) ([][]byte, error) {
+	items, err := engine.peekN(n)
+	if err != nil {
+		return nil, err
+	}
+	engine.stack = engine.stack[:len(engine.stack)-n]
+	return items, nil
+}
+
+func (engine *Engine) peek(depth int) ([]byte, error) {
+	if depth < 0 || depth >= len(engine.stack) {
+		return nil, errors.New("栈中没有足够的元素")
+	}
+	return engine.stack[len(engine.stack)-1-depth], nil
+}
+
+func (engine *Engine) peekN(n int) ([][]byte, error) {
+	if n < 0 || n > len(engine.stack) {
+		return nil, errors.New("栈中没有足够的元素")
+	}
+	items := make([][]byte, n)
+	copy(items, engine.stack[len(engine.stack)-n:])
+	return items, nil
+}
+
+func (engine *Engine) popNum(maxSize int) (scriptNum, error) {
+	value, err := engine.pop()
+	if err != nil {
+		return 0, err
+	}
+	return makeScriptNum(value, maxSize)
+}
@@ -0,0 +1,257 @@
+package script
+
+import (
+	"bytes"
+	"testing"
+)
+
+/**
+ *测试用的签名检查器：签名为"sig:"加公钥时验证通过，锁定时间和相对时间锁不超过设定值时满足要求
+ */
+type testChecker struct {
+	lockTime int64
+	sequence int64
+}
+
+func (checker testChecker) CheckSig(sig []byte, pubk []byte, scriptCode []byte, allowBatch bool) bool {
+	return bytes.Equal(sig, testSig(pubk))
+}
+
+func (checker testChecker) CheckLockTime(lockTime int64) bool {
+	return lockTime <= checker.lockTime
+}
+
+func (checker testChecker) CheckSequence(sequence int64) bool {
+	return sequence <= checker.sequence
+}
+
+func testSig(pubk []byte) []byte {
+	return append([]byte("sig:"), pubk...)
+}
+
+type scriptCase struct {
+	name      string
+	scriptSig []byte
+	scriptPub []byte
+	valid     bool
+}
+
+func runScriptCases(t *testing.T, checker SigChecker, cases []scriptCase) {
+	t.Helper()
+	for _, c := range cases {
+		err := VerifyScript(c.scriptSig, c.scriptPub, checker)
+		if (err == nil) != c.valid {
+			t.Errorf("%s：%s 验证结果为%v，应为%v", c.name, Disasm(c.scriptPub), err, c.valid)
+		}
+	}
+}
+
+func TestEngineOpcodes(t *testing.T) {
+	b := NewBuilder
+	runScriptCases(t, testChecker{}, []scriptCase{
+		{"OP_ADD", b().AddInt64(2).AddInt64(3).Script(), b().AddOp(OP_ADD).AddInt64(5).AddOp(OP_EQUAL).Script(), true},
+		{"OP_ADD结果不符", b().AddInt64(2).AddInt64(4).Script(), b().AddOp(OP_ADD).AddInt64(5).AddOp(OP_EQUAL).Script(), false},
+		{"OP_SUB负数", b().AddInt64(2).AddInt64(5).Script(), b().AddOp(OP_SUB).AddInt64(-3).AddOp(OP_NUMEQUAL).Script(), true},
+		{"OP_GREATERTHAN", b().AddInt64(1000).Script(), b().AddInt64(999).AddOp(OP_GREATERTHAN).Script(), true},
+		{"OP_WITHIN", b().AddInt64(5).Script(), b().AddInt64(5).AddInt64(6).AddOp(OP_WITHIN).Script(), true},
+		{"OP_WITHIN上界不包含", b().AddInt64(6).Script(), b().AddInt64(5).AddInt64(6).AddOp(OP_WITHIN).Script(), false},
+		{"OP_MIN和OP_MAX", b().AddInt64(3).AddInt64(7).Script(),
+			b().AddOp(OP_2DUP).AddOp(OP_MIN).AddInt64(3).AddOp(OP_NUMEQUALVERIFY).AddOp(OP_MAX).AddInt64(7).AddOp(OP_NUMEQUAL).Script(), true},
+		{"OP_NOT", b().AddInt64(0).Script(), b().AddOp(OP_NOT).Script(), true},
+		{"OP_1ADD和OP_ABS", b().AddInt64(-5).Script(), b().AddOp(OP_1ADD).AddOp(OP_ABS).AddInt64(4).AddOp(OP_NUMEQUAL).Script(), true},
+		{"数值操作数超过4字节", b().AddData([]byte{1, 0, 0, 0, 1}).Script(), b().AddOp(OP_1ADD).Script(), false},
+		{"OP_DUP和OP_DROP", b().AddInt64(1).Script(), b().AddOp(OP_DUP).AddOp(OP_DROP).Script(), true},
+		{"OP_SWAP", b().AddInt64(1).AddInt64(2).Script(), b().AddOp(OP_SWAP).AddInt64(1).AddOp(OP_EQUALVERIFY).AddInt64(2).AddOp(OP_EQUAL).Script(), true},
+		{"OP_OVER和OP_NIP", b().AddInt64(1).AddInt64(2).Script(), b().AddOp(OP_OVER).AddOp(OP_NIP).AddOp(OP_NIP).Script(), true},
+		{"备用栈", b().AddInt64(7).Script(), b().AddOp(OP_TOALTSTACK).AddOp(OP_FROMALTSTACK).AddInt64(7).AddOp(OP_EQUAL).Script(), true},
+		{"空备用栈", nil, b().AddOp(OP_FROMALTSTACK).Script(), false},
+		{"OP_DEPTH", b().AddInt64(9).AddInt64(9).Script(), b().AddOp(OP_DEPTH).AddInt64(2).AddOp(OP_EQUALVERIFY).AddOp(OP_EQUAL).Script(), true},
+		{"OP_SIZE", b().AddData([]byte("abc")).Script(), b().AddOp(OP_SIZE).AddInt64(3).AddOp(OP_EQUALVERIFY).AddOp(OP_DROP).AddInt64(1).Script(), true},
+		{"OP_SHA256", b().AddData([]byte("secret")).Script(), b().AddOp(OP_SIZE).AddOp(OP_DROP).AddOp(OP_SHA256).AddOp(OP_SIZE).AddInt64(32).AddOp(OP_EQUALVERIFY).AddOp(OP_DROP).AddInt64(1).Script(), true},
+		{"OP_HASH160", b().AddData([]byte("data")).Script(), b().AddOp(OP_HASH160).AddData(Hash160([]byte("data"))).AddOp(OP_EQUALVERIFY).AddInt64(1).Script(), true},
+		{"OP_IF进入ELSE分支", b().AddInt64(0).Script(), b().AddOp(OP_IF).AddInt64(0).AddOp(OP_ELSE).AddInt64(1).AddOp(OP_ENDIF).Script(), true},
+		{"OP_NOTIF", b().AddInt64(0).Script(), b().AddOp(OP_NOTIF).AddInt64(1).AddOp(OP_ELSE).AddInt64(0).AddOp(OP_ENDIF).Script(), true},
+		{"嵌套的OP_IF", b().AddInt64(0).AddInt64(1).Script(),
+			b().AddOp(OP_IF).AddOp(OP_IF).AddInt64(0).AddOp(OP_ELSE).AddInt64(1).AddOp(OP_ENDIF).AddOp(OP_ELSE).AddInt64(0).AddOp(OP_ENDIF).Script(), true},
+		{"缺少OP_ENDIF", nil, b().AddInt64(1).AddOp(OP_IF).AddInt64(1).Script(), false},
+		{"多余的OP_ENDIF", nil, b().AddInt64(1).AddOp(OP_ENDIF).Script(), false},
+		{"OP_VERIFY失败", b().AddInt64(0).Script(), b().AddOp(OP_VERIFY).AddInt64(1).Script(), false},
+		{"OP_RETURN", b().AddInt64(1).Script(), []byte{OP_RETURN}, false},
+		{"未执行分支中的OP_RETURN", b().AddInt64(0).Script(), b().AddOp(OP_IF).AddOp(OP_RETURN).AddOp(OP_ENDIF).AddInt64(1).Script(), true},
+		{"不支持的操作码", nil, []byte{OP_RESERVED}, false},
+		{"栈中剩余多个元素", b().AddInt64(1).Script(), []byte{OP_DUP}, false},
+		{"空栈", nil, []byte{OP_NOP}, false},
+		{"解锁脚本只能压入数据", []byte{OP_DUP}, []byte{OP_1}, false},
+		{"数据压入没有使用最短编码", []byte{OP_PUSHDATA1, 1, 7}, b().AddData([]byte{7}).AddOp(OP_EQUAL).Script(), false},
+	})
+}
+
+/**
+ *脚本长度、元素大小、操作码数量、栈深度和多重签名公钥数量的限制
+ */
+func TestEngineLimits(t *testing.T) {
+	b := NewBuilder
+	nops := func(count int) []byte {
+		return append(bytes.Repeat([]byte{OP_NOP}, count), OP_1)
+	}
+	runScriptCases(t, testChecker{}, []scriptCase{
+		{"操作码数量达到上限", nil, nops(MAXOPSPERSCRIPT), true},
+		{"操作码数量超出上限", nil, nops(MAXOPSPERSCRIPT + 1), false},
+		{"脚本长度超出上限", nil, append(b().AddData(make([]byte, MAXELEMENTSIZE)).AddOp(OP_DROP).Script(), bytes.Repeat([]byte{OP_0}, MAXSCRIPTSIZE)...), false},
+		{"元素大小达到上限", b().AddData(make([]byte, MAXELEMENTSIZE)).Script(), b().AddOp(OP_SIZE).AddData(scriptNum(MAXELEMENTSIZE).Bytes()).AddOp(OP_EQUALVERIFY).AddOp(OP_DROP).AddInt64(1).Script(), true},
+		{"元素大小超出上限", b().AddData(make([]byte, MAXELEMENTSIZE+1)).Script(), b().AddOp(OP_DROP).AddInt64(1).Script(), false},
+	})
+
+	//主栈和备用栈的元素总数不能超过上限
+	engine := &Engine{checker: testChecker{}}
+	if err := engine.Execute(bytes.Repeat([]byte{OP_1}, MAXSTACKSIZE-1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Execute([]byte{OP_TOALTSTACK, OP_DUP}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Execute([]byte{OP_DUP}); err == nil {
+		t.Error("栈的元素总数超出上限时应当执行失败")
+	}
+
+	pubks := make([][]byte, MAXPUBKEYSPERMULTISIG+1)
+	for i := range pubks {
+		pubks[i] = []byte{byte(i + 1), 0xaa}
+	}
+	if _, err := MultiSigScript(1, pubks); err == nil {
+		t.Error("多重签名的公钥数量超出上限时不应生成脚本")
+	}
+	tooMany := b().AddInt64(1)
+	for _, pubk := range pubks {
+		tooMany.AddData(pubk)
+	}
+	tooMany.AddInt64(int64(len(pubks))).AddOp(OP_CHECKMULTISIG)
+	if err := VerifyScript(b().AddOp(OP_0).AddData(testSig(pubks[0])).Script(), tooMany.Script(), testChecker{}); err == nil {
+		t.Error("多重签名的公钥数量超出上限时应当验证失败")
+	}
+}
+
+func TestEngineP2PKH(t *testing.T) {
+	pubk := []byte("pubkey")
+	scriptPub, err := PayToPubKeyHashScript(Hash160(pubk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPayToPubKeyHash(scriptPub) || !bytes.Equal(ExtractPubKeyHash(scriptPub), Hash160(pubk)) {
+		t.Fatal("P2PKH锁定脚本格式不正确")
+	}
+	if _, err = PayToPubKeyHashScript([]byte("short")); err == nil {
+		t.Fatal("长度不正确的公钥哈希应当被拒绝")
+	}
+	runScriptCases(t, testChecker{}, []scriptCase{
+		{"P2PKH", PubKeyHashSigScript(testSig(pubk), pubk), scriptPub, true},
+		{"P2PKH签名错误", PubKeyHashSigScript(testSig([]byte("other")), pubk), scriptPub, false},
+		{"P2PKH公钥不符", PubKeyHashSigScript(testSig([]byte("other")), []byte("other")), scriptPub, false},
+		{"P2PKH空签名", PubKeyHashSigScript(nil, pubk), scriptPub, false},
+	})
+}
+
+func TestEngineMultiSig(t *testing.T) {
+	k1, k2, k3 := []byte("k1"), []byte("k2"), []byte("k3")
+	multiSig, err := MultiSigScript(2, [][]byte{k1, k2, k3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	required, pubks, ok := ExtractMultiSig(multiSig)
+	if !ok || required != 2 || len(pubks) != 3 || !bytes.Equal(pubks[2], k3) {
+		t.Fatal("多重签名脚本解析结果不正确")
+	}
+	runScriptCases(t, testChecker{}, []scriptCase{
+		{"2-of-3", MultiSigSigScript([][]byte{testSig(k1), testSig(k3)}), multiSig, true},
+		{"签名顺序与公钥顺序不一致", MultiSigSigScript([][]byte{testSig(k3), testSig(k1)}), multiSig, false},
+		{"签名数量不足", MultiSigSigScript([][]byte{testSig(k2)}), multiSig, false},
+		{"无效签名", MultiSigSigScript([][]byte{testSig(k1), testSig([]byte("k4"))}), multiSig, false},
+		{"dummy元素不为空", NewBuilder().AddInt64(1).AddData(testSig(k1)).AddData(testSig(k2)).Script(), multiSig, false},
+	})
+}
+
+func TestEngineP2SH(t *testing.T) {
+	k1, k2 := []byte("k1"), []byte("k2")
+	redeemScript, err := MultiSigScript(2, [][]byte{k1, k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptPub, err := PayToScriptHashScript(Hash160(redeemScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPayToScriptHash(scriptPub) || !bytes.Equal(ExtractScriptHash(scriptPub), Hash160(redeemScript)) {
+		t.Fatal("P2SH锁定脚本格式不正确")
+	}
+	sigScript := func(sigs [][]byte, redeem []byte) []byte {
+		return PushOnlyScript(append(append([][]byte{{}}, sigs...), redeem))
+	}
+	other, _ := MultiSigScript(1, [][]byte{k1, k2})
+	runScriptCases(t, testChecker{}, []scriptCase{
+		{"P2SH多重签名", sigScript([][]byte{testSig(k1), testSig(k2)}, redeemScript), scriptPub, true},
+		{"赎回脚本的签名不足", sigScript([][]byte{testSig(k1)}, redeemScript), scriptPub, false},
+		{"赎回脚本与哈希不符", sigScript([][]byte{testSig(k1)}, other), scriptPub, false},
+		{"缺少赎回脚本", MultiSigSigScript([][]byte{testSig(k1), testSig(k2)}), scriptPub, false},
+	})
+}
+
+func TestEngineTimeLock(t *testing.T) {
+	b := NewBuilder
+	checker := testChecker{lockTime: 100, sequence: 10}
+	runScriptCases(t, checker, []scriptCase{
+		{"绝对时间锁已到期", nil, b().AddInt64(100).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), true},
+		{"绝对时间锁未到期", nil, b().AddInt64(101).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), false},
+		{"负数的绝对时间锁", nil, b().AddInt64(-1).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), false},
+		{"空栈上的绝对时间锁", nil, b().AddOp(OP_CHECKLOCKTIMEVERIFY).AddInt64(1).Script(), false},
+		{"相对时间锁已到期", nil, b().AddInt64(10).AddOp(OP_CHECKSEQUENCEVERIFY).Script(), true},
+		{"相对时间锁未到期", nil, b().AddInt64(11).AddOp(OP_CHECKSEQUENCEVERIFY).Script(), false},
+		{"禁用标志的相对时间锁", nil, b().AddInt64(SEQUENCEDISABLEFLAG | 11).AddOp(OP_CHECKSEQUENCEVERIFY).Script(), true},
+		{"5字节的时间锁", nil, b().AddInt64(1 << 32).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), false},
+	})
+
+	pubk := []byte("pubkey")
+	p2pkh, _ := PayToPubKeyHashScript(Hash160(pubk))
+	for _, relative := range []bool{false, true} {
+		lockScript, err := TimeLockScript(10, relative, p2pkh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lockTime, isRelative, inner, ok := ExtractTimeLock(lockScript)
+		if !ok || lockTime != 10 || isRelative != relative || !bytes.Equal(inner, p2pkh) || !bytes.Equal(StripTimeLock(lockScript), p2pkh) {
+			t.Fatalf("时间锁脚本解析结果不正确：%s", Disasm(lockScript))
+		}
+		if err = VerifyScript(PubKeyHashSigScript(testSig(pubk), pubk), lockScript, checker); err != nil {
+			t.Fatal(err)
+		}
+		if err = VerifyScript(PubKeyHashSigScript(testSig(pubk), pubk), lockScript, testChecker{}); err == nil {
+			t.Fatal("时间锁未到期时不应能花费")
+		}
+	}
+	if _, err := TimeLockScript(0, false, p2pkh); err == nil {
+		t.Fatal("时间锁必须大于0")
+	}
+	if _, err := TimeLockScript(SEQUENCEDISABLEFLAG|1, true, p2pkh); err == nil {
+		t.Fatal("相对时间锁不能设置禁用标志")
+	}
+	if _, _, _, ok := ExtractTimeLock(p2pkh); ok || !bytes.Equal(StripTimeLock(p2pkh), p2pkh) {
+		t.Fatal("不带时间锁的脚本不应解析出时间锁")
+	}
+}
+
+func TestScriptNum(t *testing.T) {
+	for _, value := range []int64{0, 1, -1, 127, 128, -128, 255, 256, 32767, -32768, 1 << 31, -(1 << 31)} {
+		num, err := makeScriptNum(scriptNum(value).Bytes(), MAXLOCKTIMENUMSIZE)
+		if err != nil || int64(num) != value {
+			t.Errorf("%d编码后解析为%d：%v", value, num, err)
+		}
+	}
+	if len(scriptNum(0).Bytes()) != 0 {
+		t.Error("0应当编码为空字节")
+	}
+	if _, err := makeScriptNum([]byte{1, 0}, MAXNUMSIZE); err == nil {
+		t.Error("非最短编码的数值应当被拒绝")
+	}
+	if _, err := makeScriptNum([]byte{1, 2, 3, 4, 5}, MAXNUMSIZE); err == nil {
+		t.Error("超出长度限制的数值应当被拒绝")
+	}
+}
@@ -0,0 +1,117 @@
+package script
+
+import (
+	"bytes"
+	"errors"
+)
+
+const SECRETLEN = 32     //原子交换秘密值的长度
+const SECRETHASHLEN = 32 //秘密值的sha256哈希长度
+
+/**
+ *哈希时间锁合约（HTLC）的各项参数
+ */
+type HTLCContract struct {
+	SecretHash    []byte //秘密值的sha256哈希
+	RecipientHash []byte //凭秘密值领取资金的接收方公钥哈希
+	RefundHash    []byte //锁定时间到期后取回资金的退款方公钥哈希
+	LockTime      int64  //退款的锁定时间，小于LOCKTIMETHRESHOLD为区块高度，否则为unix时间戳
+}
+
+/**
+ *生成哈希时间锁合约的赎回脚本：
+ *OP_IF
+ *    OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <secretHash> OP_EQUALVERIFY OP_DUP OP_HASH160 <recipientHash>
+ *OP_ELSE
+ *    <lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 <refundHash>
+ *OP_ENDIF
+ *OP_EQUALVERIFY OP_CHECKSIG
+ *接收方提供秘密值和签名即可领取资金，锁定时间到期后退款方可以凭签名取回资金
+ */
+func HTLCScript(contract HTLCContract) ([]byte, error) {
+	if len(contract.SecretHash) != SECRETHASHLEN {
+		return nil, errors.New("秘密值哈希的长度不正确")
+	}
+	if len(contract.RecipientHash) != PUBKHASHLEN || len(contract.RefundHash) != PUBKHASHLEN {
+		return nil, errors.New("公钥哈希的长度不正确")
+	}
+	if contract.LockTime <= 0 || contract.LockTime >= 1<<32 {
+		return nil, errors.New("合约的锁定时间超出允许的范围")
+	}
+	builder := NewBuilder()
+	builder.AddOp(OP_IF)
+	builder.AddOp(OP_SIZE).AddInt64(SECRETLEN).AddOp(OP_EQUALVERIFY)
+	builder.AddOp(OP_SHA256).AddData(contract.SecretHash).AddOp(OP_EQUALVERIFY)
+	builder.AddOp(OP_DUP).AddOp(OP_HASH160).AddData(contract.RecipientHash)
+	builder.AddOp(OP_ELSE)
+	builder.AddInt64(contract.LockTime).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP)
+	builder.AddOp(OP_DUP).AddOp(OP_HASH160).AddData(contract.RefundHash)
+	builder.AddOp(OP_ENDIF)
+	builder.AddOp(OP_EQUALVERIFY).AddOp(OP_CHECKSIG)
+	return builder.Script(), nil
+}
+
+/**
+ *从赎回脚本中解析哈希时间锁合约的参数，不是标准的哈希时间锁合约时ok为false
+ */
+func ExtractHTLC(script []byte) (contract HTLCContract, ok bool) {
+	ops, err := parseScript(script)
+	if err != nil || len(ops) != 20 {
+		return contract, false
+	}
+	contract.SecretHash = ops[5].data
+	contract.RecipientHash = ops[9].data
+	contract.RefundHash = ops[16].data
+	var num scriptNum
+	switch {
+	case isSmallInt(ops[11].opcode):
+		num = scriptNum(ops[11].opcode - OP_1 + 1)
+	case isPushOp(ops[11].opcode) && ops[11].isMinimalPush():
+		num, err = makeScriptNum(ops[11].data, MAXLOCKTIMENUMSIZE)
+		if err != nil {
+			return contract, false
+		}
+	default:
+		return contract, false
+	}
+	contract.LockTime = int64(num)
+	//按照解析出的参数重新生成脚本，与原脚本完全一致才是标准的哈希时间锁合约
+	expected, err := HTLCScript(contract)
+	if err != nil || !bytes.Equal(expected, script) {
+		return HTLCContract{}, false
+	}
+	return contract, true
+}
+
+/**
+ *判断脚本是否是标准的哈希时间锁合约
+ */
+func IsHTLC(script []byte) bool {
+	_, ok := ExtractHTLC(script)
+	return ok
+}
+
+/**
+ *生成花费哈希时间锁合约的解锁数据（不包含赎回脚本）：
+ *secret不为空时走领取分支 <sig> <pubk> <secret> OP_TRUE，为空时走退款分支 <sig> <pubk> OP_FALSE
+ */
+func HTLCSigPushes(sig []byte, pubk []byte, secret []byte) [][]byte {
+	if len(secret) == 0 {
+		return [][]byte{sig, pubk, nil}
+	}
+	return [][]byte{sig, pubk, secret, {1}}
+}
+
+/**
+ *从花费哈希时间锁合约的解锁脚本中提取接收方公开的秘密值，解锁脚本不是领取分支时返回nil
+ */
+func ExtractHTLCSecret(sigScript []byte, contract []byte) []byte {
+	pushes, err := PushedData(sigScript)
+	if err != nil || len(pushes) != 5 {
+		return nil
+	}
+	if !bytes.Equal(pushes[4], contract) || len(pushes[2]) != SECRETLEN {
+		return nil
+	}
+	return pushes[2]
+}
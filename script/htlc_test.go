@@ -0,0 +1,69 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestHTLCRoundTrip(t *testing.T) {
+	contract := HTLCContract{
+		SecretHash:    bytes.Repeat([]byte{1}, SECRETHASHLEN),
+		RecipientHash: bytes.Repeat([]byte{2}, PUBKHASHLEN),
+		RefundHash:    bytes.Repeat([]byte{3}, PUBKHASHLEN),
+		LockTime:      7,
+	}
+	htlc, err := HTLCScript(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := ExtractHTLC(htlc)
+	if !ok || decoded.LockTime != 7 || !bytes.Equal(decoded.SecretHash, contract.SecretHash) ||
+		!bytes.Equal(decoded.RecipientHash, contract.RecipientHash) || !bytes.Equal(decoded.RefundHash, contract.RefundHash) {
+		t.Fatalf("HTLC脚本解析结果不正确：%+v", decoded)
+	}
+	if IsHTLC(htlc[:len(htlc)-1]) || IsHTLC(append(htlc, OP_NOP)) {
+		t.Fatal("被截断或追加了操作码的脚本不应被识别为HTLC")
+	}
+
+	secret := bytes.Repeat([]byte{5}, SECRETLEN)
+	redeem := PushOnlyScript(append(HTLCSigPushes([]byte{9}, []byte{8}, secret), htlc))
+	if !bytes.Equal(ExtractHTLCSecret(redeem, htlc), secret) {
+		t.Fatal("没有从赎回交易中提取出秘密值")
+	}
+	refund := PushOnlyScript(append(HTLCSigPushes([]byte{9}, []byte{8}, nil), htlc))
+	if ExtractHTLCSecret(refund, htlc) != nil {
+		t.Fatal("退款交易中不应提取出秘密值")
+	}
+}
+
+/**
+ *HTLC的两条花费路径：接收方提供秘密值赎回，或者时间锁到期后由退款方取回
+ */
+func TestHTLCSpend(t *testing.T) {
+	recipient, refunder := []byte("recipient"), []byte("refunder")
+	secret := bytes.Repeat([]byte{5}, SECRETLEN)
+	secretHash := sha256.Sum256(secret)
+	htlc, err := HTLCScript(HTLCContract{
+		SecretHash:    secretHash[:],
+		RecipientHash: Hash160(recipient),
+		RefundHash:    Hash160(refunder),
+		LockTime:      50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigScript := func(pubk []byte, secret []byte) []byte {
+		return PushOnlyScript(HTLCSigPushes(testSig(pubk), pubk, secret))
+	}
+	runScriptCases(t, testChecker{lockTime: 10}, []scriptCase{
+		{"接收方使用秘密值赎回", sigScript(recipient, secret), htlc, true},
+		{"秘密值错误", sigScript(recipient, bytes.Repeat([]byte{6}, SECRETLEN)), htlc, false},
+		{"退款方使用秘密值赎回", sigScript(refunder, secret), htlc, false},
+		{"时间锁未到期时退款", sigScript(refunder, nil), htlc, false},
+	})
+	runScriptCases(t, testChecker{lockTime: 50}, []scriptCase{
+		{"时间锁到期后退款", sigScript(refunder, nil), htlc, true},
+		{"接收方使用退款路径", sigScript(recipient, nil), htlc, false},
+	})
+}
@@ -0,0 +1,91 @@
+package script
+
+import "errors"
+
+const MAXNUMSIZE = 4         //算术运算的操作数最多4个字节
+const MAXLOCKTIMENUMSIZE = 5 //时间锁的操作数最多5个字节
+
+/**
+ *脚本中的数字，使用小端序的符号-数值编码：最高字节的最高位为符号位
+ */
+type scriptNum int64
+
+/**
+ *把栈中的数据解析为脚本数字，数据必须使用最短编码，并且长度不能超过maxSize
+ */
+func makeScriptNum(data []byte, maxSize int) (scriptNum, error) {
+	if len(data) > maxSize {
+		return 0, errors.New("脚本数字超出允许的长度")
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	//最高字节除符号位外全为0时，必须是为了避免与前一个字节的符号位冲突，否则不是最短编码
+	if data[len(data)-1]&0x7f == 0 {
+		if len(data) == 1 || data[len(data)-2]&0x80 == 0 {
+			return 0, errors.New("脚本数字不是最短编码")
+		}
+	}
+	var result int64
+	for i, b := range data {
+		result |= int64(b) << uint8(8*i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		result &= ^(int64(0x80) << uint8(8*(len(data)-1)))
+		return scriptNum(-result), nil
+	}
+	return scriptNum(result), nil
+}
+
+/**
+ *把脚本数字编码为最短的字节数据
+ */
+func (num scriptNum) Bytes() []byte {
+	if num == 0 {
+		return nil
+	}
+	isNegative := num < 0
+	abs := int64(num)
+	if isNegative {
+		abs = -abs
+	}
+	result := make([]byte, 0, 9)
+	for abs > 0 {
+		result = append(result, byte(abs&0xff))
+		abs >>= 8
+	}
+	//最高字节的最高位被占用时，需要额外增加一个字节存放符号位
+	if result[len(result)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if isNegative {
+			extra = 0x80
+		}
+		result = append(result, extra)
+	} else if isNegative {
+		result[len(result)-1] |= 0x80
+	}
+	return result
+}
+
+/**
+ *把栈中的数据转换为布尔值：全为0（包括负零）时为false，否则为true
+ */
+func castToBool(data []byte) bool {
+	for i, b := range data {
+		if b != 0 {
+			//负零：最后一个字节为0x80，其余字节为0
+			if i == len(data)-1 && b == 0x80 {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func boolBytes(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package script
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+/**
+ *脚本的操作码定义，操作码的取值与比特币脚本保持一致
+ *0x01-0x4b：直接把随后的n个字节压入栈中
+ */
+const (
+	OP_0         = 0x00
+	OP_FALSE     = OP_0
+	OP_PUSHDATA1 = 0x4c
+	OP_PUSHDATA2 = 0x4d
+	OP_PUSHDATA4 = 0x4e
+	OP_1NEGATE   = 0x4f
+	OP_RESERVED  = 0x50
+	OP_1         = 0x51
+	OP_TRUE      = OP_1
+	OP_2         = 0x52
+	OP_16        = 0x60
+
+	//流程控制
+	OP_NOP    = 0x61
+	OP_IF     = 0x63
+	OP_NOTIF  = 0x64
+	OP_ELSE   = 0x67
+	OP_ENDIF  = 0x68
+	OP_VERIFY = 0x69
+	OP_RETURN = 0x6a
+
+	//栈操作
+	OP_TOALTSTACK   = 0x6b
+	OP_FROMALTSTACK = 0x6c
+	OP_2DROP        = 0x6d
+	OP_2DUP         = 0x6e
+	OP_IFDUP        = 0x73
+	OP_DEPTH        = 0x74
+	OP_DROP         = 0x75
+	OP_DUP          = 0x76
+	OP_NIP          = 0x77
+	OP_OVER         = 0x78
+	OP_ROT          = 0x7b
+	OP_SWAP         = 0x7c
+	OP_SIZE         = 0x82
+
+	//比较
+	OP_EQUAL       = 0x87
+	OP_EQUALVERIFY = 0x88
+
+	//算术运算
+	OP_1ADD               = 0x8b
+	OP_1SUB               = 0x8c
+	OP_NEGATE             = 0x8f
+	OP_ABS                = 0x90
+	OP_NOT                = 0x91
+	OP_0NOTEQUAL          = 0x92
+	OP_ADD                = 0x93
+	OP_SUB                = 0x94
+	OP_BOOLAND            = 0x9a
+	OP_BOOLOR             = 0x9b
+	OP_NUMEQUAL           = 0x9c
+	OP_NUMEQUALVERIFY     = 0x9d
+	OP_NUMNOTEQUAL        = 0x9e
+	OP_LESSTHAN           = 0x9f
+	OP_GREATERTHAN        = 0xa0
+	OP_LESSTHANOREQUAL    = 0xa1
+	OP_GREATERTHANOREQUAL = 0xa2
+	OP_MIN                = 0xa3
+	OP_MAX                = 0xa4
+	OP_WITHIN             = 0xa5
+
+	//哈希运算
+	OP_SHA256  = 0xa8
+	OP_HASH160 = 0xa9
+	OP_HASH256 = 0xaa
+
+	//签名验证
+	OP_CHECKSIG            = 0xac
+	OP_CHECKSIGVERIFY      = 0xad
+	OP_CHECKMULTISIG       = 0xae
+	OP_CHECKMULTISIGVERIFY = 0xaf
+
+	//时间锁
+	OP_CHECKLOCKTIMEVERIFY = 0xb1
+	OP_CHECKSEQUENCEVERIFY = 0xb2
+)
+
+/**
+ *操作码与名称的对应关系，用于脚本的反汇编显示
+ */
+var opcodeNames = map[byte]string{
+	OP_0: "OP_0", OP_PUSHDATA1: "OP_PUSHDATA1", OP_PUSHDATA2: "OP_PUSHDATA2", OP_PUSHDATA4: "OP_PUSHDATA4",
+	OP_1NEGATE: "OP_1NEGATE", OP_NOP: "OP_NOP", OP_IF: "OP_IF", OP_NOTIF: "OP_NOTIF", OP_ELSE: "OP_ELSE",
+	OP_ENDIF: "OP_ENDIF", OP_VERIFY: "OP_VERIFY", OP_RETURN: "OP_RETURN",
+	OP_TOALTSTACK: "OP_TOALTSTACK", OP_FROMALTSTACK: "OP_FROMALTSTACK", OP_2DROP: "OP_2DROP", OP_2DUP: "OP_2DUP",
+	OP_IFDUP: "OP_IFDUP", OP_DEPTH: "OP_DEPTH", OP_DROP: "OP_DROP", OP_DUP: "OP_DUP", OP_NIP: "OP_NIP",
+	OP_OVER: "OP_OVER", OP_ROT: "OP_ROT", OP_SWAP: "OP_SWAP", OP_SIZE: "OP_SIZE",
+	OP_EQUAL: "OP_EQUAL", OP_EQUALVERIFY: "OP_EQUALVERIFY",
+	OP_1ADD: "OP_1ADD", OP_1SUB: "OP_1SUB", OP_NEGATE: "OP_NEGATE", OP_ABS: "OP_ABS", OP_NOT: "OP_NOT",
+	OP_0NOTEQUAL: "OP_0NOTEQUAL", OP_ADD: "OP_ADD", OP_SUB: "OP_SUB", OP_BOOLAND: "OP_BOOLAND",
+	OP_BOOLOR: "OP_BOOLOR", OP_NUMEQUAL: "OP_NUMEQUAL", OP_NUMEQUALVERIFY: "OP_NUMEQUALVERIFY",
+	OP_NUMNOTEQUAL: "OP_NUMNOTEQUAL", OP_LESSTHAN: "OP_LESSTHAN", OP_GREATERTHAN: "OP_GREATERTHAN",
+	OP_LESSTHANOREQUAL: "OP_LESSTHANOREQUAL", OP_GREATERTHANOREQUAL: "OP_GREATERTHANOREQUAL",
+	OP_MIN: "OP_MIN", OP_MAX: "OP_MAX", OP_WITHIN: "OP_WITHIN",
+	OP_SHA256: "OP_SHA256", OP_HASH160: "OP_HASH160", OP_HASH256: "OP_HASH256",
+	OP_CHECKSIG: "OP_CHECKSIG", OP_CHECKSIGVERIFY: "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG: "OP_CHECKMULTISIG", OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY", OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+}
+
+/**
+ *解析后的一条脚本指令：操作码 + 该操作码压入的数据（非数据压入操作码的data为空）
+ */
+type parsedOp struct {
+	opcode byte
+	data   []byte
+}
+
+/**
+ *判断操作码是否是数据压入类的操作码
+ */
+func isPushOp(opcode byte) bool {
+	return opcode <= OP_16 && opcode != OP_RESERVED
+}
+
+/**
+ *把脚本的字节数据解析为指令序列
+ */
+func parseScript(script []byte) ([]parsedOp, error) {
+	ops := make([]parsedOp, 0)
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+		var length int
+		switch {
+		case opcode > OP_0 && opcode < OP_PUSHDATA1:
+			length = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, errors.New("脚本格式错误，OP_PUSHDATA1缺少长度")
+			}
+			length = int(script[i])
+			i++
+		case opcode == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, errors.New("脚本格式错误，OP_PUSHDATA2缺少长度")
+			}
+			length = int(binary.LittleEndian.Uint16(script[i : i+2]))
+			i += 2
+		case opcode == OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, errors.New("脚本格式错误，OP_PUSHDATA4缺少长度")
+			}
+			length = int(binary.LittleEndian.Uint32(script[i : i+4]))
+			i += 4
+		}
+		if length < 0 || i+length > len(script) {
+			return nil, errors.New("脚本格式错误，压入的数据超出脚本长度")
+		}
+		op := parsedOp{opcode: opcode}
+		if length > 0 {
+			op.data = script[i : i+length]
+			i += length
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+/**
+ *判断数据压入是否使用了最短的编码方式
+ */
+func (op parsedOp) isMinimalPush() bool {
+	if op.opcode == OP_1NEGATE || (op.opcode >= OP_1 && op.opcode <= OP_16) {
+		return true
+	}
+	length := len(op.data)
+	switch {
+	case length == 0:
+		return op.opcode == OP_0
+	case length == 1 && op.data[0] >= 1 && op.data[0] <= 16:
+		return op.opcode == OP_1+op.data[0]-1
+	case length == 1 && op.data[0] == 0x81:
+		return op.opcode == OP_1NEGATE
+	case length < OP_PUSHDATA1:
+		return int(op.opcode) == length
+	case length <= 0xff:
+		return op.opcode == OP_PUSHDATA1
+	case length <= 0xffff:
+		return op.opcode == OP_PUSHDATA2
+	}
+	return true
+}
+
+/**
+ *判断脚本是否只包含数据压入操作，解锁脚本必须满足该条件
+ */
+func IsPushOnly(script []byte) bool {
+	ops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+	for _, op := range ops {
+		if !isPushOp(op.opcode) {
+			return false
+		}
+	}
+	return true
+}
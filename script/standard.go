@@ -0,0 +1,312 @@
+package script
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+const PUBKHASHLEN = 20        //公钥哈希的长度：ripemd160(sha256(pubk))
+const SCRIPTHASHLEN = 20      //脚本哈希的长度：ripemd160(sha256(script))
+const MAXDATACARRIERSIZE = 80 //数据输出中最多可以携带的数据字节数
+
+/**
+ *计算数据的hash160：ripemd160(sha256(data))，与OP_HASH160的计算方式一致
+ */
+func Hash160(data []byte) []byte {
+	return utils.HashRipemd160(utils.Hash256(data))
+}
+
+/**
+ *生成P2PKH（支付到公钥哈希）的锁定脚本：
+ *OP_DUP OP_HASH160 <pubkHash> OP_EQUALVERIFY OP_CHECKSIG
+ */
+func PayToPubKeyHashScript(pubkHash []byte) ([]byte, error) {
+	if len(pubkHash) != PUBKHASHLEN {
+		return nil, errors.New("公钥哈希的长度不正确")
+	}
+	return NewBuilder().AddOp(OP_DUP).AddOp(OP_HASH160).AddData(pubkHash).
+		AddOp(OP_EQUALVERIFY).AddOp(OP_CHECKSIG).Script(), nil
+}
+
+/**
+ *判断锁定脚本是否是标准的P2PKH脚本
+ */
+func IsPayToPubKeyHash(script []byte) bool {
+	return len(script) == PUBKHASHLEN+5 &&
+		script[0] == OP_DUP && script[1] == OP_HASH160 && script[2] == PUBKHASHLEN &&
+		script[PUBKHASHLEN+3] == OP_EQUALVERIFY && script[PUBKHASHLEN+4] == OP_CHECKSIG
+}
+
+/**
+ *从P2PKH锁定脚本中提取公钥哈希，不是P2PKH脚本时返回nil
+ */
+func ExtractPubKeyHash(script []byte) []byte {
+	if !IsPayToPubKeyHash(script) {
+		return nil
+	}
+	return script[3 : PUBKHASHLEN+3]
+}
+
+/**
+ *生成P2PKH的解锁脚本：<sig> <pubk>
+ */
+func PubKeyHashSigScript(sig []byte, pubk []byte) []byte {
+	return NewBuilder().AddData(sig).AddData(pubk).Script()
+}
+
+/**
+ *生成P2SH（支付到脚本哈希）的锁定脚本：OP_HASH160 <scriptHash> OP_EQUAL
+ *花费时需要在解锁脚本的最后压入赎回脚本，赎回脚本的哈希与scriptHash一致后，再使用其余数据执行赎回脚本
+ */
+func PayToScriptHashScript(scriptHash []byte) ([]byte, error) {
+	if len(scriptHash) != SCRIPTHASHLEN {
+		return nil, errors.New("脚本哈希的长度不正确")
+	}
+	return NewBuilder().AddOp(OP_HASH160).AddData(scriptHash).AddOp(OP_EQUAL).Script(), nil
+}
+
+/**
+ *判断锁定脚本是否是标准的P2SH脚本
+ */
+func IsPayToScriptHash(script []byte) bool {
+	return len(script) == SCRIPTHASHLEN+3 &&
+		script[0] == OP_HASH160 && script[1] == SCRIPTHASHLEN && script[SCRIPTHASHLEN+2] == OP_EQUAL
+}
+
+/**
+ *从P2SH锁定脚本中提取脚本哈希，不是P2SH脚本时返回nil
+ */
+func ExtractScriptHash(script []byte) []byte {
+	if !IsPayToScriptHash(script) {
+		return nil
+	}
+	return script[2 : SCRIPTHASHLEN+2]
+}
+
+/**
+ *生成依次压入给定数据的解锁脚本
+ */
+func PushOnlyScript(pushes [][]byte) []byte {
+	builder := NewBuilder()
+	for _, data := range pushes {
+		builder.AddData(data)
+	}
+	return builder.Script()
+}
+
+/**
+ *生成M-of-N多重签名的锁定脚本：
+ *M <pubk1> ... <pubkN> N OP_CHECKMULTISIG
+ */
+func MultiSigScript(required int, pubks [][]byte) ([]byte, error) {
+	if len(pubks) == 0 || len(pubks) > MAXPUBKEYSPERMULTISIG {
+		return nil, errors.New("多重签名的公钥数量超出限制")
+	}
+	if required < 1 || required > len(pubks) {
+		return nil, errors.New("多重签名所需的签名数量不正确")
+	}
+	builder := NewBuilder().AddInt64(int64(required))
+	for _, pubk := range pubks {
+		if len(pubk) == 0 {
+			return nil, errors.New("多重签名的公钥不能为空")
+		}
+		builder.AddData(pubk)
+	}
+	return builder.AddInt64(int64(len(pubks))).AddOp(OP_CHECKMULTISIG).Script(), nil
+}
+
+/**
+ *从多重签名锁定脚本中提取所需的签名数量和所有的公钥，不是多重签名脚本时ok为false
+ */
+func ExtractMultiSig(script []byte) (required int, pubks [][]byte, ok bool) {
+	ops, err := parseScript(script)
+	if err != nil || len(ops) < 4 {
+		return 0, nil, false
+	}
+	first, last := ops[0], ops[len(ops)-1]
+	countOp := ops[len(ops)-2]
+	if !isSmallInt(first.opcode) || !isSmallInt(countOp.opcode) || last.opcode != OP_CHECKMULTISIG {
+		return 0, nil, false
+	}
+	required = int(first.opcode - OP_1 + 1)
+	count := int(countOp.opcode - OP_1 + 1)
+	if count != len(ops)-3 || required > count {
+		return 0, nil, false
+	}
+	pubks = make([][]byte, 0, count)
+	for _, op := range ops[1 : len(ops)-2] {
+		if len(op.data) == 0 || !op.isMinimalPush() {
+			return 0, nil, false
+		}
+		pubks = append(pubks, op.data)
+	}
+	return required, pubks, true
+}
+
+/**
+ *判断锁定脚本是否是标准的多重签名脚本
+ */
+func IsMultiSig(script []byte) bool {
+	_, _, ok := ExtractMultiSig(script)
+	return ok
+}
+
+/**
+ *生成多重签名的解锁脚本：OP_0 <sig1> ... <sigM>
+ *OP_0为OP_CHECKMULTISIG额外弹出的dummy元素，签名需要按照公钥的顺序排列
+ */
+func MultiSigSigScript(sigs [][]byte) []byte {
+	return PushOnlyScript(append([][]byte{nil}, sigs...))
+}
+
+/**
+ *获取只包含数据压入操作的脚本中依次压入的数据
+ */
+func PushedData(script []byte) ([][]byte, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	pushes := make([][]byte, 0, len(ops))
+	for _, op := range ops {
+		switch {
+		case !isPushOp(op.opcode):
+			return nil, errors.New("脚本中包含非数据压入的操作码")
+		case op.opcode == OP_1NEGATE:
+			pushes = append(pushes, scriptNum(-1).Bytes())
+		case isSmallInt(op.opcode):
+			pushes = append(pushes, scriptNum(op.opcode-OP_1+1).Bytes())
+		default:
+			pushes = append(pushes, op.data)
+		}
+	}
+	return pushes, nil
+}
+
+/**
+ *判断操作码是否是OP_1至OP_16的小整数操作码
+ */
+func isSmallInt(opcode byte) bool {
+	return opcode >= OP_1 && opcode <= OP_16
+}
+
+/**
+ *生成带时间锁的脚本：<lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP <inner>
+ *relative为true时使用OP_CHECKSEQUENCEVERIFY，lockTime为sequence编码的相对时间锁
+ *inner为时间锁到期后需要满足的花费条件，例如P2PKH或多重签名脚本
+ */
+func TimeLockScript(lockTime int64, relative bool, inner []byte) ([]byte, error) {
+	if lockTime <= 0 {
+		return nil, errors.New("时间锁必须大于0")
+	}
+	if relative && lockTime&SEQUENCEDISABLEFLAG != 0 {
+		return nil, errors.New("相对时间锁不能设置禁用标志")
+	}
+	if !relative && lockTime >= 1<<32 {
+		return nil, errors.New("时间锁超出允许的范围")
+	}
+	opcode := byte(OP_CHECKLOCKTIMEVERIFY)
+	if relative {
+		opcode = OP_CHECKSEQUENCEVERIFY
+	}
+	builder := NewBuilder().AddInt64(lockTime).AddOp(opcode).AddOp(OP_DROP)
+	return append(builder.Script(), inner...), nil
+}
+
+/**
+ *从带时间锁的脚本中提取时间锁和内部的花费条件，不是带时间锁的脚本时ok为false
+ */
+func ExtractTimeLock(script []byte) (lockTime int64, relative bool, inner []byte, ok bool) {
+	ops, err := parseScript(script)
+	if err != nil || len(ops) < 3 {
+		return 0, false, nil, false
+	}
+	if ops[2].opcode != OP_DROP {
+		return 0, false, nil, false
+	}
+	switch ops[1].opcode {
+	case OP_CHECKLOCKTIMEVERIFY:
+	case OP_CHECKSEQUENCEVERIFY:
+		relative = true
+	default:
+		return 0, false, nil, false
+	}
+	var num scriptNum
+	switch {
+	case isSmallInt(ops[0].opcode):
+		num = scriptNum(ops[0].opcode - OP_1 + 1)
+	case isPushOp(ops[0].opcode) && ops[0].isMinimalPush():
+		num, err = makeScriptNum(ops[0].data, MAXLOCKTIMENUMSIZE)
+		if err != nil {
+			return 0, false, nil, false
+		}
+	default:
+		return 0, false, nil, false
+	}
+	if num <= 0 {
+		return 0, false, nil, false
+	}
+	prefix := NewBuilder().AddInt64(int64(num)).AddOp(ops[1].opcode).AddOp(OP_DROP).Script()
+	if !bytes.HasPrefix(script, prefix) {
+		return 0, false, nil, false
+	}
+	return int64(num), relative, script[len(prefix):], true
+}
+
+/**
+ *去除脚本开头的时间锁，返回内部的花费条件，不是带时间锁的脚本时原样返回
+ */
+func StripTimeLock(script []byte) []byte {
+	if _, _, inner, ok := ExtractTimeLock(script); ok {
+		return inner
+	}
+	return script
+}
+
+/**
+ *生成携带数据的锁定脚本：OP_RETURN <data>，执行到OP_RETURN时脚本立即失败，因此该输出可证明无法被花费
+ */
+func NullDataScript(data []byte) ([]byte, error) {
+	if len(data) > MAXDATACARRIERSIZE {
+		return nil, errors.New("携带的数据超出" + strconv.Itoa(MAXDATACARRIERSIZE) + "字节的限制")
+	}
+	builder := NewBuilder().AddOp(OP_RETURN)
+	if len(data) > 0 {
+		builder.AddData(data)
+	}
+	return builder.Script(), nil
+}
+
+/**
+ *判断脚本是否是标准的数据输出脚本：OP_RETURN之后最多跟随一个不超过限制的最小数据压入
+ */
+func IsNullData(script []byte) bool {
+	return ExtractNullData(script) != nil || bytes.Equal(script, []byte{OP_RETURN})
+}
+
+/**
+ *提取数据输出脚本中携带的数据，不是标准的数据输出脚本或没有携带数据时返回nil
+ */
+func ExtractNullData(script []byte) []byte {
+	ops, err := parseScript(script)
+	if err != nil || len(ops) != 2 || ops[0].opcode != OP_RETURN {
+		return nil
+	}
+	if !isPushOp(ops[1].opcode) || !ops[1].isMinimalPush() {
+		return nil
+	}
+	pushes, err := PushedData(script[1:])
+	if err != nil || len(pushes[0]) == 0 || len(pushes[0]) > MAXDATACARRIERSIZE {
+		return nil
+	}
+	return pushes[0]
+}
+
+/**
+ *判断锁定脚本是否可证明无法被花费：以OP_RETURN开头或超出脚本长度限制，这类输出不需要保存到utxo集合中
+ */
+func IsUnspendable(script []byte) bool {
+	return (len(script) > 0 && script[0] == OP_RETURN) || len(script) > MAXSCRIPTSIZE
+}
@@ -0,0 +1,289 @@
+package transaction
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const BNBMAXTRIES = 100000           //分支定界搜索的最大尝试次数，超过后放弃精确匹配
+const RANDOMIMPROVEMAXTARGET = 3     //随机改进策略中所选金额不超过转账金额的倍数
+
+/**
+ *选币策略的接口标准：从可花费的utxos中选出金额合计不小于amount的一组utxo
+ */
+type CoinSelector interface {
+	Name() string
+	Select(utxos []UTXO, amount float64) ([]UTXO, error)
+}
+
+/**
+ *根据策略名称获取对应的选币策略，供命令行参数使用，名称为空时使用默认策略
+ */
+func GetCoinSelector(name string) (CoinSelector, error) {
+	if len(name) == 0 {
+		return DefaultCoinSelector(), nil
+	}
+	for _, selector := range []CoinSelector{
+		BranchAndBoundSelector{Fallback: LargestFirstSelector{}},
+		LargestFirstSelector{},
+		SmallestFirstSelector{},
+		RandomImproveSelector{},
+		PrivacySelector{},
+	} {
+		if selector.Name() == name {
+			return selector, nil
+		}
+	}
+	return nil, errors.New("不支持的选币策略：" + name)
+}
+
+/**
+ *默认的选币策略：优先寻找无需找零的精确匹配，找不到时按存储顺序依次选取
+ */
+func DefaultCoinSelector() CoinSelector {
+	return BranchAndBoundSelector{Fallback: InOrderSelector{}}
+}
+
+/**
+ *按utxo的存储顺序依次选取，直到金额合计不小于转账金额
+ */
+type InOrderSelector struct{}
+
+func (InOrderSelector) Name() string {
+	return "inorder"
+}
+
+func (InOrderSelector) Select(utxos []UTXO, amount float64) ([]UTXO, error) {
+	return accumulate(utxos, amount)
+}
+
+/**
+ *优先选取金额最大的utxo，使用的输入最少
+ */
+type LargestFirstSelector struct{}
+
+func (LargestFirstSelector) Name() string {
+	return "largest"
+}
+
+func (LargestFirstSelector) Select(utxos []UTXO, amount float64) ([]UTXO, error) {
+	sorted := sortUTXOsByValue(utxos, true)
+	return accumulate(sorted, amount)
+}
+
+/**
+ *优先选取金额最小的utxo，用于合并零散的小额utxo
+ */
+type SmallestFirstSelector struct{}
+
+func (SmallestFirstSelector) Name() string {
+	return "smallest"
+}
+
+func (SmallestFirstSelector) Select(utxos []UTXO, amount float64) ([]UTXO, error) {
+	sorted := sortUTXOsByValue(utxos, false)
+	return accumulate(sorted, amount)
+}
+
+/**
+ *分支定界策略：搜索金额合计恰好等于转账金额的utxo组合，交易无需找零
+ *找不到精确匹配时使用Fallback策略，Fallback为空时返回错误
+ */
+type BranchAndBoundSelector struct {
+	Fallback CoinSelector
+}
+
+func (BranchAndBoundSelector) Name() string {
+	return "bnb"
+}
+
+func (selector BranchAndBoundSelector) Select(utxos []UTXO, amount float64) ([]UTXO, error) {
+	if err := checkAvailable(utxos, amount); err != nil {
+		return nil, err
+	}
+	sorted := sortUTXOsByValue(utxos, true)
+	//remaining[i]为下标不小于i的utxo的金额合计，用于剪枝
+	remaining := make([]float64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Value
+	}
+	selected := make([]bool, len(sorted))
+	tries := 0
+	var search func(index int, total float64) bool
+	search = func(index int, total float64) bool {
+		tries++
+		if total > amount-AMOUNTPRECISION && total < amount+AMOUNTPRECISION {
+			return true
+		}
+		//已超过转账金额、剩余utxo不足或尝试次数过多时回溯
+		if total > amount || index == len(sorted) || total+remaining[index] < amount-AMOUNTPRECISION || tries > BNBMAXTRIES {
+			return false
+		}
+		selected[index] = true
+		if search(index+1, total+sorted[index].Value) {
+			return true
+		}
+		selected[index] = false
+		return search(index+1, total)
+	}
+	if search(0, 0) {
+		result := make([]UTXO, 0)
+		for i, utxo := range sorted {
+			if selected[i] {
+				result = append(result, utxo)
+			}
+		}
+		return result, nil
+	}
+	if selector.Fallback == nil {
+		return nil, errors.New("未找到金额恰好等于转账金额的utxo组合")
+	}
+	return selector.Fallback.Select(utxos, amount)
+}
+
+/**
+ *随机改进策略：先随机选取utxo直到金额足够，再继续随机追加utxo，
+ *使找零金额接近转账金额且所选金额不超过转账金额的RANDOMIMPROVEMAXTARGET倍，
+ *找零与转账金额相当可以避免钱包中的utxo越来越零散
+ */
+type RandomImproveSelector struct{}
+
+func (RandomImproveSelector) Name() string {
+	return "random"
+}
+
+func (RandomImproveSelector) Select(utxos []UTXO, amount float64) ([]UTXO, error) {
+	if err := checkAvailable(utxos, amount); err != nil {
+		return nil, err
+	}
+	shuffled := make([]UTXO, len(utxos))
+	copy(shuffled, utxos)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	selected, err := accumulate(shuffled, amount)
+	if err != nil {
+		return nil, err
+	}
+	var total float64
+	for _, utxo := range selected {
+		total += utxo.Value
+	}
+	ideal := 2 * amount
+	for _, utxo := range shuffled[len(selected):] {
+		newTotal := total + utxo.Value
+		if newTotal > RANDOMIMPROVEMAXTARGET*amount || abs(ideal-newTotal) >= abs(ideal-total) {
+			continue
+		}
+		selected = append(selected, utxo)
+		total = newTotal
+	}
+	return selected, nil
+}
+
+/**
+ *隐私策略：按地址把utxo分组，同一地址的utxo总是一起花费，避免部分花费使该地址在之后的交易中再次被关联，
+ *优先只使用一个地址的资金，单个地址的资金不足时才按金额从大到小合并多个地址，使交易关联的地址最少
+ */
+type PrivacySelector struct{}
+
+func (PrivacySelector) Name() string {
+	return "privacy"
+}
+
+func (PrivacySelector) Select(utxos []UTXO, amount float64) ([]UTXO, error) {
+	if err := checkAvailable(utxos, amount); err != nil {
+		return nil, err
+	}
+	groups := make([][]UTXO, 0)
+	totals := make([]float64, 0)
+	groupIndex := make(map[string]int)
+	for _, utxo := range utxos {
+		key := string(utxo.PubkHash)
+		index, ok := groupIndex[key]
+		if !ok {
+			index = len(groups)
+			groupIndex[key] = index
+			groups = append(groups, nil)
+			totals = append(totals, 0)
+		}
+		groups[index] = append(groups[index], utxo)
+		totals[index] += utxo.Value
+	}
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return totals[order[i]] > totals[order[j]]
+	})
+	//单个地址足够时，选择金额足够的地址中金额最小的一个
+	for i := len(order) - 1; i >= 0; i-- {
+		if totals[order[i]] > amount-AMOUNTPRECISION {
+			return groups[order[i]], nil
+		}
+	}
+	selected := make([]UTXO, 0)
+	var total float64
+	for _, index := range order {
+		selected = append(selected, groups[index]...)
+		total += totals[index]
+		if total > amount-AMOUNTPRECISION {
+			break
+		}
+	}
+	return selected, nil
+}
+
+/**
+ *按顺序依次选取utxo，直到金额合计不小于amount
+ */
+func accumulate(utxos []UTXO, amount float64) ([]UTXO, error) {
+	var total float64
+	for index, utxo := range utxos {
+		total += utxo.Value
+		if total > amount-AMOUNTPRECISION {
+			return utxos[:index+1], nil
+		}
+	}
+	return nil, errors.New("可花费的utxo余额不足")
+}
+
+/**
+ *检查utxos的金额合计是否足够支付amount
+ */
+func checkAvailable(utxos []UTXO, amount float64) error {
+	var total float64
+	for _, utxo := range utxos {
+		total += utxo.Value
+	}
+	if total < amount-AMOUNTPRECISION {
+		return errors.New("可花费的utxo余额不足")
+	}
+	return nil
+}
+
+/**
+ *把utxos按金额排序后返回新的切片，descending为true时从大到小排序
+ */
+func sortUTXOsByValue(utxos []UTXO, descending bool) []UTXO {
+	sorted := make([]UTXO, len(utxos))
+	copy(sorted, utxos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return sorted[i].Value > sorted[j].Value
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	return sorted
+}
+
+func abs(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
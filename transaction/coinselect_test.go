@@ -0,0 +1,128 @@
+package transaction
+
+import (
+	"math"
+	"testing"
+)
+
+/**
+ *按金额生成测试用的utxo，下标为偶数和奇数的utxo分别属于两个地址
+ */
+func newTestUTXOs(values ...float64) []UTXO {
+	utxos := make([]UTXO, 0, len(values))
+	for index, value := range values {
+		utxo := UTXO{Vout: index}
+		utxo.Value = value
+		utxo.PubkHash = []byte{byte(index % 2)}
+		utxos = append(utxos, utxo)
+	}
+	return utxos
+}
+
+func sumUTXOs(utxos []UTXO) float64 {
+	var total float64
+	for _, utxo := range utxos {
+		total += utxo.Value
+	}
+	return total
+}
+
+/**
+ *分支定界策略找到金额恰好等于转账金额的组合，找不到时使用Fallback策略
+ */
+func TestBranchAndBoundExactMatch(t *testing.T) {
+	utxos := newTestUTXOs(50, 7, 3, 20, 0.1, 0.2)
+	exact := BranchAndBoundSelector{}
+	for _, amount := range []float64{10, 0.3, 27.3, 80.3, 50} {
+		selected, err := exact.Select(utxos, amount)
+		if err != nil {
+			t.Fatalf("%v：%v", amount, err)
+		}
+		if math.Abs(sumUTXOs(selected)-amount) > AMOUNTPRECISION {
+			t.Fatalf("%v：选出的金额合计为%v", amount, sumUTXOs(selected))
+		}
+	}
+	if selected, _ := exact.Select(utxos, 0.3); len(selected) != 2 {
+		t.Fatalf("0.3应当由0.1和0.2精确匹配：%v", selected)
+	}
+	if _, err := exact.Select(utxos, 51); err == nil {
+		t.Fatal("没有精确匹配且没有Fallback时应当返回错误")
+	}
+	if _, err := exact.Select(utxos, 100); err == nil {
+		t.Fatal("余额不足时应当返回错误")
+	}
+
+	selected, err := DefaultCoinSelector().Select(utxos, 51)
+	if err != nil || sumUTXOs(selected) < 51 {
+		t.Fatalf("没有精确匹配时应当使用Fallback策略：%v %v", selected, err)
+	}
+	//Fallback为按存储顺序选取
+	if len(selected) != 2 || selected[0].Vout != 0 || selected[1].Vout != 1 {
+		t.Fatalf("Fallback策略的选取结果不正确：%v", selected)
+	}
+}
+
+func TestCoinSelectors(t *testing.T) {
+	utxos := newTestUTXOs(50, 7, 3, 20, 0.1, 0.2)
+	cases := []struct {
+		selector CoinSelector
+		amount   float64
+		count    int
+	}{
+		{LargestFirstSelector{}, 51, 2},
+		{LargestFirstSelector{}, 50, 1},
+		{SmallestFirstSelector{}, 5, 4},
+		{InOrderSelector{}, 57, 2},
+	}
+	for _, c := range cases {
+		selected, err := c.selector.Select(utxos, c.amount)
+		if err != nil || len(selected) != c.count || sumUTXOs(selected) < c.amount {
+			t.Errorf("%s选取%v的结果不正确：%v %v", c.selector.Name(), c.amount, selected, err)
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		selected, err := RandomImproveSelector{}.Select(utxos, 5)
+		if err != nil || sumUTXOs(selected) < 5 {
+			t.Fatalf("random选取的金额不足：%v %v", selected, err)
+		}
+	}
+
+	//隐私策略只花费同一个地址的utxo，并且花费该地址的全部utxo
+	selected, err := PrivacySelector{}.Select(utxos, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, utxo := range selected {
+		if utxo.PubkHash[0] != selected[0].PubkHash[0] {
+			t.Fatalf("privacy策略选取了多个地址的utxo：%v", selected)
+		}
+	}
+	if len(selected) != 3 {
+		t.Fatalf("privacy策略应当花费该地址的全部utxo：%v", selected)
+	}
+	if selected, _ = (PrivacySelector{}).Select(utxos, 60); len(selected) != len(utxos) {
+		t.Fatalf("单个地址的资金不足时应当合并多个地址：%v", selected)
+	}
+
+	for _, selector := range []CoinSelector{LargestFirstSelector{}, SmallestFirstSelector{}, RandomImproveSelector{}, PrivacySelector{}} {
+		if _, err = selector.Select(utxos, 100); err == nil {
+			t.Errorf("%s在余额不足时应当返回错误", selector.Name())
+		}
+	}
+}
+
+func TestGetCoinSelector(t *testing.T) {
+	for _, name := range []string{"bnb", "largest", "smallest", "random", "privacy"} {
+		selector, err := GetCoinSelector(name)
+		if err != nil || selector.Name() != name {
+			t.Errorf("%s：%v", name, err)
+		}
+	}
+	if selector, err := GetCoinSelector(""); err != nil || selector.Name() != "bnb" {
+		t.Error("名称为空时应当返回默认策略", err)
+	}
+	if _, err := GetCoinSelector("nope"); err == nil {
+		t.Error("不支持的选币策略应当返回错误")
+	}
+}
@@ -0,0 +1,88 @@
+package transaction
+
+import (
+	"errors"
+)
+
+/**
+ *交易输入的sequence不大于该值时，表示交易允许在确认之前被支付更高手续费的交易替换
+ */
+const REPLACEABLESEQUENCE = DEFAULTSEQUENCE - 1
+
+/**
+ *判断交易是否允许被替换：任意一个交易输入的sequence不大于REPLACEABLESEQUENCE即表示允许替换
+ */
+func (tx *Transaction) SignalsReplacement() bool {
+	for _, input := range tx.Inputs {
+		if input.Sequence <= REPLACEABLESEQUENCE {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ *声明交易允许被替换：把sequence大于REPLACEABLESEQUENCE的交易输入设置为REPLACEABLESEQUENCE，并重新计算交易哈希，
+ *需要在签名之前设置，已经设置了相对时间锁的交易输入保持不变
+ */
+func (tx *Transaction) SignalReplacement() error {
+	for index := range tx.Inputs {
+		if tx.Inputs[index].Sequence > REPLACEABLESEQUENCE {
+			tx.Inputs[index].Sequence = REPLACEABLESEQUENCE
+		}
+	}
+	return tx.refreshTxHash()
+}
+
+/**
+ *计算交易的手续费：所花费utxo的金额之和减去所有输出的金额之和
+ */
+func (tx *Transaction) Fee(utxos []UTXO) (float64, error) {
+	if err := tx.CheckAmounts(utxos); err != nil {
+		return 0, err
+	}
+	var fee float64
+	for _, utxo := range utxos {
+		fee += utxo.Value
+	}
+	for _, output := range tx.Outputs {
+		fee -= output.Value
+	}
+	if fee < 0 {
+		fee = 0
+	}
+	return fee, nil
+}
+
+/**
+ *计算交易序列化后的字节数，包含签名等见证数据，用于计算手续费率
+ */
+func (tx *Transaction) Size() (int, error) {
+	txBytes, err := tx.Serialize()
+	if err != nil {
+		return 0, err
+	}
+	return len(txBytes), nil
+}
+
+/**
+ *把区块中所有交易的手续费加到coinbase交易的奖励中，并重新计算coinbase交易的哈希
+ */
+func (tx *Transaction) AddCoinbaseFees(fees float64) error {
+	if !tx.IsCoinbase() || len(tx.Outputs) == 0 {
+		return errors.New("只有coinbase交易可以领取手续费")
+	}
+	if !IsValidAmount(fees) {
+		return errors.New("区块的手续费金额无效")
+	}
+	if fees <= 0 {
+		return nil
+	}
+	tx.Outputs[0].Value += fees
+	txHash, err := tx.CalculateTxHash()
+	if err != nil {
+		return err
+	}
+	copy(tx.TxHash[:], txHash)
+	return nil
+}
@@ -0,0 +1,43 @@
+package transaction
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/wallet"
+	"errors"
+)
+
+/**
+ *对花费哈希时间锁合约的第index个交易输入进行签名，需要先通过SetRedeemScript设置合约的赎回脚本
+ *secret不为空时由接收方凭秘密值领取资金，为空时由退款方在锁定时间到期后取回资金，
+ *退款时交易的锁定时间需要先设置为不小于合约的锁定时间
+ */
+func (tx *Transaction) SignHTLCInput(index int, signer wallet.Signer, utxos []UTXO, secret []byte, hashType byte) error {
+	if index < 0 || index >= len(utxos) || len(tx.Inputs) != len(utxos) {
+		return errors.New("签名失败，交易输入与所花费的utxo不匹配")
+	}
+	scriptCode, _, redeemScript, err := tx.parseInputScript(index, utxos)
+	if err != nil {
+		return err
+	}
+	contract, ok := script.ExtractHTLC(scriptCode)
+	if redeemScript == nil || !ok {
+		return errors.New("所花费的utxo不是哈希时间锁合约")
+	}
+	if len(secret) > 0 && len(secret) != script.SECRETLEN {
+		return errors.New("秘密值的长度不正确")
+	}
+	if len(secret) == 0 && tx.LockedTime < contract.LockTime {
+		return errors.New("交易的锁定时间小于合约的锁定时间，无法退款")
+	}
+	sigHash, err := CalcSignatureHash(tx, index, utxos, scriptCode, hashType)
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign(sigHash)
+	if err != nil {
+		return err
+	}
+	pushes := script.HTLCSigPushes(append(sig, hashType), signer.PublicKey(), secret)
+	tx.Inputs[index].ScriptSig = script.PushOnlyScript(append(pushes, redeemScript))
+	return nil
+}
@@ -0,0 +1,91 @@
+package transaction
+
+import (
+	"XianfengChain04/script"
+	"errors"
+)
+
+/**
+ *判断交易在给定的区块高度和区块时间下是否已经可以被打包：
+ *交易的锁定时间为0、早于给定的高度或时间，或者所有输入的sequence都为SEQUENCEFINAL时，交易可以被打包
+ */
+func (tx *Transaction) IsFinal(height int64, blockTime int64) bool {
+	if tx.LockedTime == 0 {
+		return true
+	}
+	lockTarget := height
+	if tx.LockedTime >= script.LOCKTIMETHRESHOLD {
+		lockTarget = blockTime
+	}
+	if tx.LockedTime < lockTarget {
+		return true
+	}
+	for _, input := range tx.Inputs {
+		if input.Sequence != script.SEQUENCEFINAL {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ *检查交易每个输入的相对时间锁：从所花费utxo被确认的区块开始，
+ *经过的区块个数或时间必须不小于输入的sequence所要求的数值
+ *height和blockTime为交易将要被打包的区块的高度以及作为时间基准的区块时间
+ */
+func (tx *Transaction) CheckSequenceLocks(utxos []UTXO, height int64, blockTime int64) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+	if len(tx.Inputs) != len(utxos) {
+		return errors.New("交易输入与所花费的utxo不匹配")
+	}
+	for index, input := range tx.Inputs {
+		if input.Sequence&script.SEQUENCEDISABLEFLAG != 0 {
+			continue
+		}
+		lockValue := int64(input.Sequence & script.SEQUENCEMASK)
+		utxo := utxos[index]
+		if input.Sequence&script.SEQUENCETYPEFLAG != 0 {
+			if blockTime-utxo.Time < lockValue<<script.SEQUENCEGRANULARITY {
+				return errors.New("交易输入的相对时间锁未到期")
+			}
+			continue
+		}
+		if height-utxo.Height < lockValue {
+			return errors.New("交易输入的相对区块锁未到期")
+		}
+	}
+	return nil
+}
+
+/**
+ *设置交易的锁定时间，并重新计算交易哈希，需要在签名之前设置
+ */
+func (tx *Transaction) SetLockTime(lockTime int64) error {
+	if lockTime < 0 {
+		return errors.New("交易的锁定时间不能为负数")
+	}
+	tx.LockedTime = lockTime
+	return tx.refreshTxHash()
+}
+
+/**
+ *设置第index个交易输入的sequence，并重新计算交易哈希，需要在签名之前设置
+ */
+func (tx *Transaction) SetSequence(index int, sequence uint32) error {
+	if index < 0 || index >= len(tx.Inputs) {
+		return errors.New("交易输入的下标超出范围")
+	}
+	tx.Inputs[index].Sequence = sequence
+	return tx.refreshTxHash()
+}
+
+func (tx *Transaction) refreshTxHash() error {
+	txHash, err := tx.CalculateTxHash()
+	if err != nil {
+		return err
+	}
+	copy(tx.TxHash[:], txHash)
+	return nil
+}
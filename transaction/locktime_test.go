@@ -0,0 +1,99 @@
+package transaction
+
+import (
+	"XianfengChain04/script"
+	"testing"
+)
+
+func TestIsFinal(t *testing.T) {
+	const blockTime = script.LOCKTIMETHRESHOLD + 1000
+	cases := []struct {
+		name     string
+		lockTime int64
+		sequence uint32
+		final    bool
+	}{
+		{"没有锁定时间", 0, DEFAULTSEQUENCE, true},
+		{"锁定高度早于区块高度", 99, DEFAULTSEQUENCE, true},
+		{"锁定高度等于区块高度", 100, DEFAULTSEQUENCE, false},
+		{"锁定高度晚于区块高度", 101, DEFAULTSEQUENCE, false},
+		{"锁定时间早于区块时间", blockTime - 1, DEFAULTSEQUENCE, true},
+		{"锁定时间晚于区块时间", blockTime + 1, DEFAULTSEQUENCE, false},
+		{"sequence为SEQUENCEFINAL时锁定时间不生效", 101, script.SEQUENCEFINAL, true},
+	}
+	for _, c := range cases {
+		tx := newTestTx(t, []TxInput{NewTxInput([32]byte{1}, 0, nil)}, []TxOutPut{LockMoney2PubkHash(1, testAddress)}, 0)
+		if err := tx.SetSequence(0, c.sequence); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.SetLockTime(c.lockTime); err != nil {
+			t.Fatal(err)
+		}
+		if tx.IsFinal(100, blockTime) != c.final {
+			t.Errorf("%s：IsFinal应为%v", c.name, c.final)
+		}
+	}
+}
+
+/**
+ *相对时间锁按区块个数或512秒的时间单位计算，设置禁用标志时不检查
+ */
+func TestCheckSequenceLocks(t *testing.T) {
+	const granularity = 1 << script.SEQUENCEGRANULARITY
+	utxo := NewUTXO([32]byte{1}, 0, LockMoney2PubkHash(1, testAddress))
+	utxo.Height = 100
+	utxo.Time = 10000
+	cases := []struct {
+		name      string
+		sequence  uint32
+		height    int64
+		blockTime int64
+		valid     bool
+	}{
+		{"区块锁已到期", 10, 110, 0, true},
+		{"区块锁未到期", 10, 109, 0, false},
+		{"时间锁已到期", script.SEQUENCETYPEFLAG | 2, 0, 10000 + 2*granularity, true},
+		{"时间锁未到期", script.SEQUENCETYPEFLAG | 2, 0, 10000 + 2*granularity - 1, false},
+		{"禁用标志", script.SEQUENCEDISABLEFLAG | 10, 100, 0, true},
+		{"忽略掩码以外的位", 1<<16 | 10, 110, 0, true},
+	}
+	for _, c := range cases {
+		tx := newTestTx(t, []TxInput{NewTxInput([32]byte{1}, 0, nil)}, []TxOutPut{LockMoney2PubkHash(1, testAddress)}, 0)
+		if err := tx.SetSequence(0, c.sequence); err != nil {
+			t.Fatal(err)
+		}
+		err := tx.CheckSequenceLocks([]UTXO{utxo}, c.height, c.blockTime)
+		if (err == nil) != c.valid {
+			t.Errorf("%s：检查结果为%v，应为%v", c.name, err, c.valid)
+		}
+	}
+
+	tx := newTestTx(t, []TxInput{NewTxInput([32]byte{1}, 0, nil)}, []TxOutPut{LockMoney2PubkHash(1, testAddress)}, 0)
+	if err := tx.CheckSequenceLocks(nil, 0, 0); err == nil {
+		t.Error("utxo与交易输入个数不一致时应当返回错误")
+	}
+}
+
+func TestSetLockTimeRefreshesHash(t *testing.T) {
+	tx := newTestTx(t, []TxInput{NewTxInput([32]byte{1}, 0, nil)}, []TxOutPut{LockMoney2PubkHash(1, testAddress)}, 0)
+	before := tx.TxHash
+	if err := tx.SetLockTime(5); err != nil {
+		t.Fatal(err)
+	}
+	if tx.TxHash == before {
+		t.Fatal("设置锁定时间后交易哈希应当改变")
+	}
+	before = tx.TxHash
+	if err := tx.SetSequence(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if tx.TxHash == before {
+		t.Fatal("设置sequence后交易哈希应当改变")
+	}
+	if err := tx.SetLockTime(-1); err == nil {
+		t.Fatal("负数的锁定时间应当被拒绝")
+	}
+	if err := tx.SetSequence(1, 1); err == nil {
+		t.Fatal("超出范围的输入下标应当被拒绝")
+	}
+}
@@ -0,0 +1,100 @@
+package transaction
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/wallet"
+	"bytes"
+	"errors"
+)
+
+/**
+ *使用多重签名脚本scriptCode对交易输入进行签名，signer必须是脚本中的某个参与方
+ *pushes为解锁脚本中已有的数据（OP_0 + 已有签名），返回合并签名后的数据：
+ *已有的签名按照对应公钥的顺序保留，新签名插入到签名者公钥所在的位置，最多保留所需数量的签名
+ */
+func (tx *Transaction) signMultiSig(index int, signer wallet.Signer, utxos []UTXO, scriptCode []byte, pushes [][]byte, hashType byte) ([][]byte, error) {
+	required, pubks, _ := script.ExtractMultiSig(script.StripTimeLock(scriptCode))
+	position := -1
+	for i, pubk := range pubks {
+		if bytes.Equal(pubk, signer.PublicKey()) {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return nil, errors.New("签名者不是该多重签名的参与方")
+	}
+	sigs, err := tx.collectMultiSigs(index, utxos, scriptCode, pubks, pushes)
+	if err != nil {
+		return nil, err
+	}
+	sigHash, err := CalcSignatureHash(tx, index, utxos, scriptCode, hashType)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.Sign(sigHash)
+	if err != nil {
+		return nil, err
+	}
+	sigs[position] = append(sig, hashType)
+
+	//第一项为OP_CHECKMULTISIG额外弹出的dummy元素
+	newPushes := [][]byte{nil}
+	for _, sig := range sigs {
+		if len(sig) > 0 && len(newPushes) <= required {
+			newPushes = append(newPushes, sig)
+		}
+	}
+	return newPushes, nil
+}
+
+/**
+ *把解锁脚本中已有的多重签名与脚本中的公钥一一对应，返回的切片与公钥的顺序一致，没有签名的位置为空
+ */
+func (tx *Transaction) collectMultiSigs(index int, utxos []UTXO, scriptCode []byte, pubks [][]byte, pushes [][]byte) ([][]byte, error) {
+	sigs := make([][]byte, len(pubks))
+	if len(pushes) == 0 {
+		return sigs, nil
+	}
+	if len(pushes[0]) != 0 {
+		return nil, errors.New("多重签名的解锁脚本格式不正确")
+	}
+	checker := &txSigChecker{tx: tx, index: index, utxos: utxos}
+	for _, sig := range pushes[1:] {
+		for i, pubk := range pubks {
+			if len(sigs[i]) == 0 && checker.CheckSig(sig, pubk, scriptCode, false) {
+				sigs[i] = sig
+				break
+			}
+		}
+	}
+	return sigs, nil
+}
+
+/**
+ *统计交易输入中已有的有效多重签名数量以及所需的签名数量，支持直接花费和通过P2SH花费的多重签名输出
+ */
+func (tx *Transaction) CountMultiSigs(index int, utxos []UTXO) (int, int, error) {
+	if index < 0 || index >= len(tx.Inputs) || len(tx.Inputs) != len(utxos) {
+		return 0, 0, errors.New("交易输入与所花费的utxo不匹配")
+	}
+	scriptCode, pushes, _, err := tx.parseInputScript(index, utxos)
+	if err != nil {
+		return 0, 0, err
+	}
+	required, pubks, ok := script.ExtractMultiSig(script.StripTimeLock(scriptCode))
+	if !ok {
+		return 0, 0, errors.New("所花费的utxo不是多重签名输出")
+	}
+	sigs, err := tx.collectMultiSigs(index, utxos, scriptCode, pubks, pushes)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := 0
+	for _, sig := range sigs {
+		if len(sig) > 0 {
+			count++
+		}
+	}
+	return count, required, nil
+}
@@ -0,0 +1,589 @@
+package transaction
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/utils"
+	"XianfengChain04/wallet"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var PSBTMAGIC = []byte("xfpsbt\xff") //部分签名交易序列化数据的前缀
+
+/**
+ *部分签名交易：未签名的交易以及每个输入所花费的utxo、赎回脚本和各参与方的签名
+ *多个参与方分别对同一个部分签名交易签名，合并后即可生成完整签名的交易
+ */
+type PSBT struct {
+	Tx     *Transaction //未签名的交易，交易输入不包含任何见证数据
+	Inputs []PSBTInput  //与交易输入一一对应的签名信息
+}
+
+/**
+ *部分签名交易中单个交易输入的签名信息
+ */
+type PSBTInput struct {
+	UTXO             *UTXO             //该输入所花费的utxo，签名前必须提供
+	RedeemScript     []byte            //花费P2SH输出时所需的赎回脚本
+	PartialSigs      map[string][]byte //十六进制公钥 -> 附加了签名哈希类型的签名
+	MuSigKeys        [][]byte          //花费MuSig聚合地址时所有参与方的schnorr公钥
+	MuSigNonces      map[string][]byte //十六进制参与方公钥 -> 附加了签名哈希类型的公开nonce
+	MuSigPartialSigs map[string][]byte //十六进制参与方公钥 -> MuSig部分签名
+}
+
+/**
+ *创建一个空的交易输入签名信息
+ */
+func newPSBTInput() PSBTInput {
+	return PSBTInput{
+		PartialSigs:      make(map[string][]byte),
+		MuSigNonces:      make(map[string][]byte),
+		MuSigPartialSigs: make(map[string][]byte),
+	}
+}
+
+/**
+ *使用未签名的交易创建部分签名交易，交易中已有的见证数据会被清除
+ */
+func NewPSBT(tx *Transaction) (*PSBT, error) {
+	if tx.IsCoinbase() {
+		return nil, errors.New("不能使用coinbase交易创建部分签名交易")
+	}
+	unsignedTx := tx.CopyTx()
+	for index := range unsignedTx.Inputs {
+		unsignedTx.Inputs[index].Sig = nil
+		unsignedTx.Inputs[index].PubK = nil
+		unsignedTx.Inputs[index].ScriptSig = nil
+	}
+	inputs := make([]PSBTInput, len(unsignedTx.Inputs))
+	for index := range inputs {
+		inputs[index] = newPSBTInput()
+	}
+	return &PSBT{Tx: &unsignedTx, Inputs: inputs}, nil
+}
+
+/**
+ *设置第index个输入所花费的utxo以及赎回脚本，赎回脚本为空时不修改已有的赎回脚本
+ */
+func (psbt *PSBT) SetInputUTXO(index int, utxo UTXO, redeemScript []byte) error {
+	if index < 0 || index >= len(psbt.Inputs) {
+		return errors.New("交易输入的下标超出范围")
+	}
+	if !utxo.IsUTXOSpend(psbt.Tx.Inputs[index]) {
+		return errors.New("utxo与交易输入不匹配")
+	}
+	psbt.Inputs[index].UTXO = &utxo
+	if len(redeemScript) > 0 {
+		psbt.Inputs[index].RedeemScript = redeemScript
+	}
+	_, err := psbt.scriptCode(index)
+	return err
+}
+
+/**
+ *获取第index个输入签名时所使用的scriptCode：花费P2SH输出时为赎回脚本，否则为utxo的锁定脚本
+ */
+func (psbt *PSBT) scriptCode(index int) ([]byte, error) {
+	input := psbt.Inputs[index]
+	if input.UTXO == nil {
+		return nil, fmt.Errorf("缺少第%d个交易输入所花费的utxo", index)
+	}
+	scriptPub := input.UTXO.GetScriptPub()
+	if !script.IsPayToScriptHash(scriptPub) {
+		return scriptPub, nil
+	}
+	if len(input.RedeemScript) == 0 {
+		return nil, fmt.Errorf("缺少第%d个交易输入的赎回脚本", index)
+	}
+	if !bytes.Equal(script.Hash160(input.RedeemScript), script.ExtractScriptHash(scriptPub)) {
+		return nil, fmt.Errorf("第%d个交易输入的赎回脚本与P2SH输出的脚本哈希不一致", index)
+	}
+	return input.RedeemScript, nil
+}
+
+/**
+ *获取所有输入所花费的utxo，任何一个输入缺少utxo时返回错误
+ */
+func (psbt *PSBT) UTXOs() ([]UTXO, error) {
+	utxos := make([]UTXO, 0, len(psbt.Inputs))
+	for index, input := range psbt.Inputs {
+		if input.UTXO == nil {
+			return nil, fmt.Errorf("缺少第%d个交易输入所花费的utxo", index)
+		}
+		utxos = append(utxos, *input.UTXO)
+	}
+	return utxos, nil
+}
+
+/**
+ *获取第index个输入的锁定条件中可以签名的公钥和所需的签名数量，
+ *P2PKH输出返回公钥哈希，多重签名输出返回所有参与方的公钥
+ */
+func (psbt *PSBT) InputKeys(index int) (pubkHash []byte, pubks [][]byte, required int, err error) {
+	scriptCode, err := psbt.scriptCode(index)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	template := script.StripTimeLock(scriptCode)
+	if pubkHash = script.ExtractPubKeyHash(template); pubkHash != nil {
+		return pubkHash, nil, 1, nil
+	}
+	if required, pubks, ok := script.ExtractMultiSig(template); ok {
+		return nil, pubks, required, nil
+	}
+	return nil, nil, 0, fmt.Errorf("第%d个交易输入的锁定脚本不支持部分签名", index)
+}
+
+/**
+ *使用signer对第index个输入签名，签名作为部分签名保存，signer必须是该输入锁定条件中的某个公钥
+ */
+func (psbt *PSBT) SignInput(index int, signer wallet.Signer, hashType byte) error {
+	if index < 0 || index >= len(psbt.Inputs) {
+		return errors.New("交易输入的下标超出范围")
+	}
+	pubkHash, pubks, _, err := psbt.InputKeys(index)
+	if err != nil {
+		return err
+	}
+	pubk := signer.PublicKey()
+	relevant := pubkHash != nil && bytes.Equal(script.Hash160(pubk), pubkHash)
+	for _, key := range pubks {
+		relevant = relevant || bytes.Equal(key, pubk)
+	}
+	if !relevant {
+		return errors.New("签名者的公钥与交易输入的锁定条件不符")
+	}
+	sigHash, err := psbt.SigHash(index, hashType)
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign(sigHash)
+	if err != nil {
+		return err
+	}
+	psbt.Inputs[index].PartialSigs[hex.EncodeToString(pubk)] = append(sig, hashType)
+	return nil
+}
+
+/**
+ *计算第index个输入使用hashType签名时的签名哈希
+ */
+func (psbt *PSBT) SigHash(index int, hashType byte) ([]byte, error) {
+	if index < 0 || index >= len(psbt.Inputs) {
+		return nil, errors.New("交易输入的下标超出范围")
+	}
+	utxos, err := psbt.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+	scriptCode, err := psbt.scriptCode(index)
+	if err != nil {
+		return nil, err
+	}
+	return CalcSignatureHash(psbt.Tx, index, utxos, scriptCode, hashType)
+}
+
+/**
+ *设置第index个输入所花费的MuSig聚合地址的参与方公钥，参与方公钥的聚合公钥必须与该输入的锁定条件一致
+ */
+func (psbt *PSBT) SetMuSigKeys(index int, pubks [][]byte) error {
+	pubkHash, _, _, err := psbt.InputKeys(index)
+	if err != nil {
+		return err
+	}
+	aggPub, err := wallet.AggregateSchnorrPubKeys(pubks)
+	if err != nil {
+		return err
+	}
+	if pubkHash == nil || !bytes.Equal(script.Hash160(aggPub), pubkHash) {
+		return errors.New("MuSig参与方的聚合公钥与交易输入的锁定条件不符")
+	}
+	psbt.Inputs[index].MuSigKeys = pubks
+	return nil
+}
+
+/**
+ *判断pubk是否为第index个输入的MuSig参与方
+ */
+func (psbt *PSBT) isMuSigKey(index int, pubk []byte) bool {
+	for _, key := range psbt.Inputs[index].MuSigKeys {
+		if bytes.Equal(key, pubk) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ *保存第index个输入中MuSig参与方pubk的公开nonce，hashType为该参与方计划使用的签名哈希类型
+ */
+func (psbt *PSBT) AddMuSigNonce(index int, pubk []byte, nonce []byte, hashType byte) error {
+	if index < 0 || index >= len(psbt.Inputs) || !psbt.isMuSigKey(index, pubk) {
+		return errors.New("公钥不是该交易输入的MuSig参与方")
+	}
+	if len(nonce) != wallet.MUSIGNONCELEN {
+		return errors.New("MuSig公开nonce的长度不正确")
+	}
+	psbt.Inputs[index].MuSigNonces[hex.EncodeToString(pubk)] = append(append([]byte{}, nonce...), hashType)
+	return nil
+}
+
+/**
+ *按照参与方公钥的顺序获取第index个输入中所有参与方的公开nonce以及约定的签名哈希类型，
+ *还有参与方没有提供nonce时第三个返回值为false
+ */
+func (psbt *PSBT) MuSigNonces(index int) ([][]byte, byte, bool, error) {
+	input := psbt.Inputs[index]
+	nonces := make([][]byte, 0, len(input.MuSigKeys))
+	var hashType byte
+	for _, pubk := range input.MuSigKeys {
+		nonce, ok := input.MuSigNonces[hex.EncodeToString(pubk)]
+		if !ok {
+			return nil, 0, false, nil
+		}
+		if len(nonce) != wallet.MUSIGNONCELEN+1 {
+			return nil, 0, false, fmt.Errorf("参与方%x的公开nonce长度不正确", pubk)
+		}
+		if len(nonces) > 0 && nonce[wallet.MUSIGNONCELEN] != hashType {
+			return nil, 0, false, errors.New("MuSig参与方使用的签名哈希类型不一致")
+		}
+		hashType = nonce[wallet.MUSIGNONCELEN]
+		nonces = append(nonces, nonce[:wallet.MUSIGNONCELEN])
+	}
+	return nonces, hashType, len(nonces) > 0, nil
+}
+
+/**
+ *保存第index个输入中MuSig参与方pubk的部分签名
+ */
+func (psbt *PSBT) AddMuSigPartialSig(index int, pubk []byte, partialSig []byte) error {
+	if index < 0 || index >= len(psbt.Inputs) || !psbt.isMuSigKey(index, pubk) {
+		return errors.New("公钥不是该交易输入的MuSig参与方")
+	}
+	psbt.Inputs[index].MuSigPartialSigs[hex.EncodeToString(pubk)] = partialSig
+	return nil
+}
+
+/**
+ *统计第index个输入已收集的MuSig公开nonce和部分签名的个数，以及参与方的个数
+ */
+func (psbt *PSBT) MuSigProgress(index int) (int, int, int) {
+	input := psbt.Inputs[index]
+	nonces, partialSigs := 0, 0
+	for _, pubk := range input.MuSigKeys {
+		key := hex.EncodeToString(pubk)
+		if _, ok := input.MuSigNonces[key]; ok {
+			nonces++
+		}
+		if _, ok := input.MuSigPartialSigs[key]; ok {
+			partialSigs++
+		}
+	}
+	return nonces, partialSigs, len(input.MuSigKeys)
+}
+
+/**
+ *所有MuSig参与方的部分签名都已收集时，把部分签名聚合为聚合公钥的签名，部分签名不全时不做处理
+ */
+func (psbt *PSBT) CombineMuSig(index int) error {
+	input := psbt.Inputs[index]
+	if len(input.MuSigKeys) == 0 {
+		return nil
+	}
+	aggPub, err := wallet.AggregateSchnorrPubKeys(input.MuSigKeys)
+	if err != nil {
+		return err
+	}
+	if _, ok := input.PartialSigs[hex.EncodeToString(aggPub)]; ok {
+		return nil
+	}
+	nonces, hashType, complete, err := psbt.MuSigNonces(index)
+	if err != nil || !complete {
+		return err
+	}
+	partialSigs := make([][]byte, 0, len(input.MuSigKeys))
+	for _, pubk := range input.MuSigKeys {
+		partialSig, ok := input.MuSigPartialSigs[hex.EncodeToString(pubk)]
+		if !ok {
+			return nil
+		}
+		partialSigs = append(partialSigs, partialSig)
+	}
+	sigHash, err := psbt.SigHash(index, hashType)
+	if err != nil {
+		return err
+	}
+	sig, err := wallet.CombineMuSigPartialSigs(input.MuSigKeys, nonces, partialSigs, sigHash)
+	if err != nil {
+		return err
+	}
+	input.PartialSigs[hex.EncodeToString(aggPub)] = append(sig, hashType)
+	return nil
+}
+
+/**
+ *统计第index个输入已有的签名数量和所需的签名数量
+ */
+func (psbt *PSBT) CountSigs(index int) (int, int, error) {
+	pubkHash, pubks, required, err := psbt.InputKeys(index)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := 0
+	for key := range psbt.Inputs[index].PartialSigs {
+		pubk, _ := hex.DecodeString(key)
+		if pubkHash != nil && bytes.Equal(script.Hash160(pubk), pubkHash) {
+			count++
+		}
+		for _, multiSigKey := range pubks {
+			if bytes.Equal(multiSigKey, pubk) {
+				count++
+			}
+		}
+	}
+	if count > required {
+		count = required
+	}
+	return count, required, nil
+}
+
+/**
+ *合并另一个参与方签名后的部分签名交易，两者必须对应同一笔未签名的交易
+ */
+func (psbt *PSBT) Combine(other *PSBT) error {
+	if psbt.Tx.TxHash != other.Tx.TxHash || len(psbt.Inputs) != len(other.Inputs) {
+		return errors.New("部分签名交易对应的交易不一致，无法合并")
+	}
+	for index, input := range other.Inputs {
+		if psbt.Inputs[index].UTXO == nil && input.UTXO != nil {
+			psbt.Inputs[index].UTXO = input.UTXO
+		}
+		if len(psbt.Inputs[index].RedeemScript) == 0 {
+			psbt.Inputs[index].RedeemScript = input.RedeemScript
+		}
+		for key, sig := range input.PartialSigs {
+			psbt.Inputs[index].PartialSigs[key] = sig
+		}
+		if len(psbt.Inputs[index].MuSigKeys) == 0 {
+			psbt.Inputs[index].MuSigKeys = input.MuSigKeys
+		}
+		for key, nonce := range input.MuSigNonces {
+			psbt.Inputs[index].MuSigNonces[key] = nonce
+		}
+		for key, partialSig := range input.MuSigPartialSigs {
+			psbt.Inputs[index].MuSigPartialSigs[key] = partialSig
+		}
+	}
+	return nil
+}
+
+/**
+ *使用已有的部分签名生成每个输入的解锁脚本，返回通过验证的完整签名交易
+ */
+func (psbt *PSBT) Finalize() (*Transaction, error) {
+	utxos, err := psbt.UTXOs()
+	if err != nil {
+		return nil, err
+	}
+	tx := psbt.Tx.CopyTx()
+	for index, input := range psbt.Inputs {
+		//MuSig输入先把所有参与方的部分签名聚合为聚合公钥的签名
+		if err = psbt.CombineMuSig(index); err != nil {
+			return nil, err
+		}
+		pubkHash, pubks, required, err := psbt.InputKeys(index)
+		if err != nil {
+			return nil, err
+		}
+		var pushes [][]byte
+		if pubkHash != nil {
+			for key, sig := range input.PartialSigs {
+				pubk, _ := hex.DecodeString(key)
+				if bytes.Equal(script.Hash160(pubk), pubkHash) {
+					pushes = [][]byte{sig, pubk}
+					break
+				}
+			}
+			if pushes == nil {
+				return nil, fmt.Errorf("第%d个交易输入还没有签名", index)
+			}
+		} else {
+			//多重签名按照公钥的顺序排列签名，第一项为OP_CHECKMULTISIG额外弹出的dummy元素
+			pushes = [][]byte{nil}
+			for _, pubk := range pubks {
+				if sig, ok := input.PartialSigs[hex.EncodeToString(pubk)]; ok && len(pushes) <= required {
+					pushes = append(pushes, sig)
+				}
+			}
+			if len(pushes)-1 < required {
+				return nil, fmt.Errorf("第%d个交易输入需要%d个签名，目前只有%d个", index, required, len(pushes)-1)
+			}
+		}
+		if len(input.RedeemScript) == 0 && pubkHash != nil {
+			tx.Inputs[index].Sig = pushes[0]
+			tx.Inputs[index].PubK = pushes[1]
+			continue
+		}
+		if len(input.RedeemScript) > 0 {
+			pushes = append(pushes, input.RedeemScript)
+		}
+		tx.Inputs[index].ScriptSig = script.PushOnlyScript(pushes)
+	}
+	if _, err = tx.VerifyTx(utxos); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+/**
+ *部分签名交易的序列化：前缀 + 未签名交易 + 输入个数(4) + 每个输入的签名信息
+ *每个输入：是否包含utxo(1) + [utxo金额(8) + 锁定脚本] + 赎回脚本 + 签名个数(4) + 按公钥排序的[公钥 + 签名]
+ *         + MuSig参与方个数(4) + [参与方公钥] + MuSig公开nonce + MuSig部分签名，后两者的格式与签名相同
+ */
+func (psbt *PSBT) Serialize() ([]byte, error) {
+	txBytes, err := psbt.Tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	buff := new(bytes.Buffer)
+	buff.Write(PSBTMAGIC)
+	utils.WriteVarBytes(buff, txBytes)
+	utils.WriteUint32(buff, uint32(len(psbt.Inputs)))
+	for _, input := range psbt.Inputs {
+		if input.UTXO == nil {
+			buff.WriteByte(0)
+		} else {
+			buff.WriteByte(1)
+			utils.WriteFloat64(buff, input.UTXO.Value)
+			utils.WriteVarBytes(buff, input.UTXO.GetScriptPub())
+		}
+		utils.WriteVarBytes(buff, input.RedeemScript)
+		writePSBTSigs(buff, input.PartialSigs)
+		utils.WriteUint32(buff, uint32(len(input.MuSigKeys)))
+		for _, pubk := range input.MuSigKeys {
+			utils.WriteVarBytes(buff, pubk)
+		}
+		writePSBTSigs(buff, input.MuSigNonces)
+		writePSBTSigs(buff, input.MuSigPartialSigs)
+	}
+	return buff.Bytes(), nil
+}
+
+/**
+ *序列化十六进制公钥到签名数据的映射：个数(4) + 按公钥排序的[公钥 + 签名数据]
+ */
+func writePSBTSigs(buff *bytes.Buffer, sigs map[string][]byte) {
+	keys := make([]string, 0, len(sigs))
+	for key := range sigs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	utils.WriteUint32(buff, uint32(len(keys)))
+	for _, key := range keys {
+		pubk, _ := hex.DecodeString(key)
+		utils.WriteVarBytes(buff, pubk)
+		utils.WriteVarBytes(buff, sigs[key])
+	}
+}
+
+/**
+ *反序列化十六进制公钥到签名数据的映射
+ */
+func readPSBTSigs(reader *bytes.Reader) (map[string][]byte, error) {
+	count, err := utils.ReadCount(reader, 4+4)
+	if err != nil {
+		return nil, err
+	}
+	sigs := make(map[string][]byte)
+	for i := 0; i < count; i++ {
+		pubk, err := utils.ReadVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := utils.ReadVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		sigs[hex.EncodeToString(pubk)] = sig
+	}
+	return sigs, nil
+}
+
+/**
+ *部分签名交易的反序列化
+ */
+func DeserializePSBT(data []byte) (*PSBT, error) {
+	if !bytes.HasPrefix(data, PSBTMAGIC) {
+		return nil, errors.New("不是部分签名交易的数据")
+	}
+	reader := bytes.NewReader(data[len(PSBTMAGIC):])
+	txBytes, err := utils.ReadVarBytes(reader)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := DeserializeTransaction(txBytes)
+	if err != nil {
+		return nil, err
+	}
+	count, err := utils.ReadCount(reader, 1+4+4+4+4+4)
+	if err != nil {
+		return nil, err
+	}
+	if count != len(tx.Inputs) {
+		return nil, errors.New("部分签名交易的输入个数与交易不一致")
+	}
+	psbt := &PSBT{Tx: tx, Inputs: make([]PSBTInput, count)}
+	for index := range psbt.Inputs {
+		input := &psbt.Inputs[index]
+		hasUTXO, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if hasUTXO == 1 {
+			value, err := utils.ReadFloat64(reader)
+			if err != nil {
+				return nil, err
+			}
+			scriptPub, err := utils.ReadVarBytes(reader)
+			if err != nil {
+				return nil, err
+			}
+			output, err := NewTxOutPutWithScript(value, scriptPub)
+			if err != nil {
+				return nil, err
+			}
+			utxo := NewUTXO(tx.Inputs[index].TxId, tx.Inputs[index].Vout, output)
+			input.UTXO = &utxo
+		}
+		if input.RedeemScript, err = utils.ReadVarBytes(reader); err != nil {
+			return nil, err
+		}
+		if input.PartialSigs, err = readPSBTSigs(reader); err != nil {
+			return nil, err
+		}
+		keyCount, err := utils.ReadCount(reader, 4)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < keyCount; i++ {
+			pubk, err := utils.ReadVarBytes(reader)
+			if err != nil {
+				return nil, err
+			}
+			input.MuSigKeys = append(input.MuSigKeys, pubk)
+		}
+		if input.MuSigNonces, err = readPSBTSigs(reader); err != nil {
+			return nil, err
+		}
+		if input.MuSigPartialSigs, err = readPSBTSigs(reader); err != nil {
+			return nil, err
+		}
+	}
+	if reader.Len() != 0 {
+		return nil, errors.New("反序列化失败，部分签名交易末尾存在多余的字节")
+	}
+	return psbt, nil
+}
@@ -0,0 +1,76 @@
+package transaction
+
+import (
+	"XianfengChain04/wallet"
+	"reflect"
+	"testing"
+)
+
+/**
+ *花费MuSig聚合地址的部分签名交易，参与方公钥、公开nonce和部分签名经过序列化后保持不变
+ */
+func TestPSBTMuSigRoundTrip(t *testing.T) {
+	pubks := make([][]byte, 0, 2)
+	for i := 0; i < 2; i++ {
+		keyPair, err := wallet.NewKeyPairWithCurve(wallet.SchnorrCurve{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubks = append(pubks, keyPair.Pub)
+	}
+	aggPub, err := wallet.AggregateSchnorrPubKeys(pubks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := (&wallet.Wallet{}).GetAddressByPubk(aggPub)
+	tx := newTestTx(t, []TxInput{NewTxInput([32]byte{6}, 1, nil)}, []TxOutPut{LockMoney2PubkHash(4.9, testAddress)}, 0)
+	psbt, err := NewPSBT(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := UTXO{TxId: [32]byte{6}, Vout: 1, TxOutPut: LockMoney2PubkHash(5, address)}
+	if err = psbt.SetInputUTXO(0, utxo, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = psbt.SetMuSigKeys(0, [][]byte{pubks[0], pubks[0]}); err == nil {
+		t.Fatal("与锁定条件不符的参与方公钥应当被拒绝")
+	}
+	if err = psbt.SetMuSigKeys(0, pubks); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, wallet.MUSIGNONCELEN)
+	nonce[0] = 0x02
+	if err = psbt.AddMuSigNonce(0, pubks[0], nonce, SIGHASH_ALL); err != nil {
+		t.Fatal(err)
+	}
+	if err = psbt.AddMuSigNonce(0, append([]byte{wallet.KEYTYPE_SCHNORR}, make([]byte, 32)...), nonce, SIGHASH_ALL); err == nil {
+		t.Fatal("非参与方的nonce应当被拒绝")
+	}
+	if _, _, complete, err := psbt.MuSigNonces(0); err != nil || complete {
+		t.Fatal("还有参与方没有提供nonce时不应返回完整的nonce", err)
+	}
+	partialSig := make([]byte, wallet.MUSIGPARTIALSIGLEN)
+	partialSig[0] = 0x01
+	if err = psbt.AddMuSigPartialSig(0, pubks[1], partialSig); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := psbt.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DeserializePSBT(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Inputs[0].MuSigKeys, pubks) ||
+		!reflect.DeepEqual(decoded.Inputs[0].MuSigNonces, psbt.Inputs[0].MuSigNonces) ||
+		!reflect.DeepEqual(decoded.Inputs[0].MuSigPartialSigs, psbt.Inputs[0].MuSigPartialSigs) {
+		t.Fatal("MuSig签名信息序列化前后不一致")
+	}
+	nonces, partialSigs, total := decoded.MuSigProgress(0)
+	if nonces != 1 || partialSigs != 1 || total != 2 {
+		t.Fatalf("MuSig签名进度不正确：%d %d %d", nonces, partialSigs, total)
+	}
+}
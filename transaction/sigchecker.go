@@ -0,0 +1,70 @@
+package transaction
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/wallet"
+)
+
+/**
+ *交易的签名检查器，为脚本执行引擎提供交易中某个输入的签名验证和时间锁判断
+ */
+type txSigChecker struct {
+	tx    *Transaction
+	index int
+	utxos []UTXO
+	batch *wallet.SchnorrBatch
+}
+
+/**
+ *验证签名：签名数据的最后一个字节为签名哈希类型，按照该类型计算签名哈希后，使用公钥对应的曲线验签
+ *允许批量验证并且设置了批量验证器时，schnorr签名加入批量验证器中延后验证
+ */
+func (checker *txSigChecker) CheckSig(sig []byte, pubk []byte, scriptCode []byte, allowBatch bool) bool {
+	signature, hashType, err := SplitSignature(sig)
+	if err != nil {
+		return false
+	}
+	sigHash, err := CalcSignatureHash(checker.tx, checker.index, checker.utxos, scriptCode, hashType)
+	if err != nil {
+		return false
+	}
+	if allowBatch && checker.batch != nil && wallet.IsSchnorrPubKey(pubk) {
+		return checker.batch.Add(pubk, sigHash, signature) == nil
+	}
+	return wallet.VerifySignature(pubk, sigHash, signature) == nil
+}
+
+/**
+ *判断交易的锁定时间是否满足脚本所要求的时间锁：
+ *两者必须同为区块高度或者同为时间戳，并且交易的锁定时间不早于脚本要求的时间
+ *当前输入的sequence为SEQUENCEFINAL时交易的锁定时间不生效，因此不满足时间锁
+ */
+func (checker *txSigChecker) CheckLockTime(lockTime int64) bool {
+	txLockTime := checker.tx.LockedTime
+	if (lockTime < script.LOCKTIMETHRESHOLD) != (txLockTime < script.LOCKTIMETHRESHOLD) {
+		return false
+	}
+	if lockTime > txLockTime {
+		return false
+	}
+	return checker.tx.Inputs[checker.index].Sequence != script.SEQUENCEFINAL
+}
+
+/**
+ *判断当前输入的sequence是否满足脚本所要求的相对时间锁：
+ *输入必须启用相对时间锁，两者的类型（区块个数或时间）必须一致，并且输入的锁定数值不小于脚本要求的数值
+ *输入的相对时间锁本身由区块验证时根据所花费utxo的确认高度进行检查
+ */
+func (checker *txSigChecker) CheckSequence(sequence int64) bool {
+	txSequence := int64(checker.tx.Inputs[checker.index].Sequence)
+	if txSequence&script.SEQUENCEDISABLEFLAG != 0 {
+		return false
+	}
+	const lockMask = script.SEQUENCETYPEFLAG | script.SEQUENCEMASK
+	sequence &= lockMask
+	txSequence &= lockMask
+	if (sequence&script.SEQUENCETYPEFLAG != 0) != (txSequence&script.SEQUENCETYPEFLAG != 0) {
+		return false
+	}
+	return sequence&script.SEQUENCEMASK <= txSequence&script.SEQUENCEMASK
+}
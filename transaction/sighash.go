@@ -0,0 +1,134 @@
+package transaction
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"errors"
+	"strings"
+)
+
+/**
+ *签名哈希类型，决定签名承诺了交易的哪些部分
+ *SIGHASH_ALL：承诺所有的输入和输出
+ *SIGHASH_NONE：承诺所有的输入，不承诺任何输出
+ *SIGHASH_SINGLE：承诺所有的输入，以及与当前输入下标相同的那一个输出
+ *SIGHASH_ANYONECANPAY：可与以上三种组合使用，只承诺当前输入，其他人可以继续向交易中添加输入
+ */
+const (
+	SIGHASH_ALL          = 0x01
+	SIGHASH_NONE         = 0x02
+	SIGHASH_SINGLE       = 0x03
+	SIGHASH_ANYONECANPAY = 0x80
+)
+
+const SIGHASHMASK = 0x1f //用于从签名哈希类型中取出基础类型
+
+/**
+ *判断签名哈希类型是否合法
+ */
+func IsValidHashType(hashType byte) bool {
+	if hashType&^(SIGHASHMASK|SIGHASH_ANYONECANPAY) != 0 {
+		return false
+	}
+	baseType := hashType & SIGHASHMASK
+	return baseType >= SIGHASH_ALL && baseType <= SIGHASH_SINGLE
+}
+
+/**
+ *根据名称解析签名哈希类型，可选ALL、NONE、SINGLE，以及附加了|ANYONECANPAY的组合，不区分大小写
+ */
+func ParseHashType(name string) (byte, error) {
+	baseName := strings.ToUpper(strings.TrimSpace(name))
+	var hashType byte
+	if strings.HasSuffix(baseName, "|ANYONECANPAY") {
+		hashType = SIGHASH_ANYONECANPAY
+		baseName = strings.TrimSuffix(baseName, "|ANYONECANPAY")
+	}
+	switch baseName {
+	case "ALL":
+		hashType |= SIGHASH_ALL
+	case "NONE":
+		hashType |= SIGHASH_NONE
+	case "SINGLE":
+		hashType |= SIGHASH_SINGLE
+	default:
+		return 0, errors.New("不支持的签名哈希类型：" + name)
+	}
+	return hashType, nil
+}
+
+/**
+ *计算交易中第index个输入的签名哈希，格式如下（整数均为大端序）：
+ *version(8) + hashPrevouts(32) + 当前输入的txid(32) + vout(8) + sequence(4) + 所花费utxo的锁定数据
+ * + 所花费utxo的金额(8) + hashOutputs(32) + lockedtime(8) + hashType(4)
+ *签名哈希承诺了所花费utxo的金额，签名者无需信任他人提供的金额信息
+ *scriptCode为当前正在执行的锁定脚本，作为所花费utxo的锁定数据参与计算
+ */
+func CalcSignatureHash(tx *Transaction, index int, utxos []UTXO, scriptCode []byte, hashType byte) ([]byte, error) {
+	if !IsValidHashType(hashType) {
+		return nil, errors.New("不支持的签名哈希类型")
+	}
+	if index < 0 || index >= len(tx.Inputs) || len(tx.Inputs) != len(utxos) {
+		return nil, errors.New("计算签名哈希失败，交易输入与所花费的utxo不匹配")
+	}
+	baseType := hashType & SIGHASHMASK
+	anyoneCanPay := hashType&SIGHASH_ANYONECANPAY != 0
+
+	//hashPrevouts：所有输入所引用的交易输出，ANYONECANPAY时不承诺其他输入
+	hashPrevouts := make([]byte, 32)
+	if !anyoneCanPay {
+		prevouts := new(bytes.Buffer)
+		for _, input := range tx.Inputs {
+			prevouts.Write(input.TxId[:])
+			utils.WriteInt64(prevouts, int64(input.Vout))
+		}
+		hashPrevouts = utils.Hash256(prevouts.Bytes())
+	}
+
+	//hashOutputs：根据签名哈希类型承诺全部输出、单个输出或不承诺输出
+	hashOutputs := make([]byte, 32)
+	switch baseType {
+	case SIGHASH_ALL:
+		outputs := new(bytes.Buffer)
+		for outIndex := range tx.Outputs {
+			tx.Outputs[outIndex].Serialize(outputs)
+		}
+		hashOutputs = utils.Hash256(outputs.Bytes())
+	case SIGHASH_SINGLE:
+		if index >= len(tx.Outputs) {
+			return nil, errors.New("SIGHASH_SINGLE签名要求存在与输入下标相同的输出")
+		}
+		output := new(bytes.Buffer)
+		tx.Outputs[index].Serialize(output)
+		hashOutputs = utils.Hash256(output.Bytes())
+	}
+
+	input := tx.Inputs[index]
+	utxo := utxos[index]
+	buff := new(bytes.Buffer)
+	utils.WriteInt64(buff, tx.Version)
+	buff.Write(hashPrevouts)
+	buff.Write(input.TxId[:])
+	utils.WriteInt64(buff, int64(input.Vout))
+	utils.WriteUint32(buff, input.Sequence)
+	utils.WriteVarBytes(buff, scriptCode)
+	utils.WriteFloat64(buff, utxo.Value)
+	buff.Write(hashOutputs)
+	utils.WriteInt64(buff, tx.LockedTime)
+	utils.WriteUint32(buff, uint32(hashType))
+	return utils.Hash256(buff.Bytes()), nil
+}
+
+/**
+ *把签名数据拆分为签名本身和签名哈希类型，签名哈希类型存放在签名数据的最后一个字节
+ */
+func SplitSignature(sig []byte) ([]byte, byte, error) {
+	if len(sig) < 2 {
+		return nil, 0, errors.New("签名数据长度不正确")
+	}
+	hashType := sig[len(sig)-1]
+	if !IsValidHashType(hashType) {
+		return nil, 0, errors.New("不支持的签名哈希类型")
+	}
+	return sig[:len(sig)-1], hashType, nil
+}
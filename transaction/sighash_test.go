@@ -0,0 +1,137 @@
+package transaction
+
+import (
+	"bytes"
+	"testing"
+)
+
+/**
+ *两个输入两个输出的测试交易及其所花费的utxo
+ */
+func newSigHashTestTx(t *testing.T) (*Transaction, []UTXO) {
+	tx := newTestTx(t, []TxInput{
+		NewTxInput([32]byte{1}, 0, nil),
+		NewTxInput([32]byte{2}, 1, nil),
+	}, []TxOutPut{LockMoney2PubkHash(1, testAddress), LockMoney2PubkHash(2, testAddress)}, 0)
+	utxos := []UTXO{
+		NewUTXO([32]byte{1}, 0, LockMoney2PubkHash(1.5, testAddress)),
+		NewUTXO([32]byte{2}, 1, LockMoney2PubkHash(1.6, testAddress)),
+	}
+	return tx, utxos
+}
+
+func calcTestSigHash(t *testing.T, tx *Transaction, utxos []UTXO, index int, hashType byte) []byte {
+	t.Helper()
+	hash, err := CalcSignatureHash(tx, index, utxos, utxos[index].GetScriptPub(), hashType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+/**
+ *每种签名哈希类型只承诺交易的对应部分：修改未承诺的部分签名哈希不变，修改承诺的部分签名哈希改变
+ */
+func TestCalcSignatureHashTypes(t *testing.T) {
+	cases := []struct {
+		name     string
+		hashType byte
+		mutate   func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) //修改交易，返回修改后的utxo以及签名哈希是否应当改变
+	}{
+		{"ALL修改其他输出", SIGHASH_ALL, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Outputs[1].Value = 3
+			return utxos, true
+		}},
+		{"NONE修改输出", SIGHASH_NONE, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Outputs[0].Value = 3
+			tx.Outputs = append(tx.Outputs, LockMoney2PubkHash(4, testAddress))
+			return utxos, false
+		}},
+		{"NONE修改其他输入", SIGHASH_NONE, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Inputs[1].Vout = 5
+			utxos[1].Vout = 5
+			return utxos, true
+		}},
+		{"SINGLE修改其他输出", SIGHASH_SINGLE, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Outputs[1].Value = 3
+			return utxos, false
+		}},
+		{"SINGLE修改对应输出", SIGHASH_SINGLE, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Outputs[0].Value = 3
+			return utxos, true
+		}},
+		{"ALL|ANYONECANPAY添加输入", SIGHASH_ALL | SIGHASH_ANYONECANPAY, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Inputs = append(tx.Inputs, NewTxInput([32]byte{3}, 0, nil))
+			return append(utxos, NewUTXO([32]byte{3}, 0, LockMoney2PubkHash(1, testAddress))), false
+		}},
+		{"ALL|ANYONECANPAY修改输出", SIGHASH_ALL | SIGHASH_ANYONECANPAY, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Outputs[1].Value = 3
+			return utxos, true
+		}},
+		{"SINGLE|ANYONECANPAY修改其他输入和输出", SIGHASH_SINGLE | SIGHASH_ANYONECANPAY, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			tx.Inputs[1].Vout = 5
+			utxos[1].Vout = 5
+			tx.Outputs[1].Value = 3
+			return utxos, false
+		}},
+		{"花费的金额", SIGHASH_NONE | SIGHASH_ANYONECANPAY, func(tx *Transaction, utxos []UTXO) ([]UTXO, bool) {
+			utxos[0].Value = 1.4
+			return utxos, true
+		}},
+	}
+	for _, c := range cases {
+		tx, utxos := newSigHashTestTx(t)
+		before := calcTestSigHash(t, tx, utxos, 0, c.hashType)
+		utxos, changed := c.mutate(tx, utxos)
+		after := calcTestSigHash(t, tx, utxos, 0, c.hashType)
+		if !bytes.Equal(before, after) != changed {
+			t.Errorf("%s：签名哈希是否改变应为%v", c.name, changed)
+		}
+	}
+
+	//不同的签名哈希类型得到不同的签名哈希
+	tx, utxos := newSigHashTestTx(t)
+	seen := make(map[string]byte)
+	for _, hashType := range []byte{SIGHASH_ALL, SIGHASH_NONE, SIGHASH_SINGLE, SIGHASH_ALL | SIGHASH_ANYONECANPAY} {
+		hash := string(calcTestSigHash(t, tx, utxos, 0, hashType))
+		if other, ok := seen[hash]; ok {
+			t.Errorf("签名哈希类型%#x与%#x的签名哈希相同", hashType, other)
+		}
+		seen[hash] = hashType
+	}
+}
+
+func TestCalcSignatureHashRejects(t *testing.T) {
+	tx, utxos := newSigHashTestTx(t)
+	if _, err := CalcSignatureHash(tx, 0, utxos, nil, 0x04); err == nil {
+		t.Error("不支持的签名哈希类型应当被拒绝")
+	}
+	if _, err := CalcSignatureHash(tx, 2, utxos, nil, SIGHASH_ALL); err == nil {
+		t.Error("超出范围的输入下标应当被拒绝")
+	}
+	tx.Outputs = tx.Outputs[:1]
+	if _, err := CalcSignatureHash(tx, 1, utxos, nil, SIGHASH_SINGLE); err == nil {
+		t.Error("SIGHASH_SINGLE缺少对应的输出时应当被拒绝")
+	}
+}
+
+func TestParseHashType(t *testing.T) {
+	valid := map[string]byte{
+		"ALL":                 SIGHASH_ALL,
+		"none":                SIGHASH_NONE,
+		"Single":              SIGHASH_SINGLE,
+		"ALL|ANYONECANPAY":    SIGHASH_ALL | SIGHASH_ANYONECANPAY,
+		"SINGLE|ANYONECANPAY": SIGHASH_SINGLE | SIGHASH_ANYONECANPAY,
+	}
+	for name, want := range valid {
+		hashType, err := ParseHashType(name)
+		if err != nil || hashType != want {
+			t.Errorf("%s解析结果为%#x，应为%#x：%v", name, hashType, want, err)
+		}
+	}
+	for _, name := range []string{"", "ANYONECANPAY", "ALL|", "DEFAULT"} {
+		if _, err := ParseHashType(name); err == nil {
+			t.Errorf("%s应当解析失败", name)
+		}
+	}
+}
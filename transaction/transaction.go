@@ -1,47 +1,58 @@
 package transaction
 
 import (
+	"XianfengChain04/script"
 	"XianfengChain04/utils"
 	"XianfengChain04/wallet"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/sha256"
+	"bytes"
 	"errors"
-	"time"
+	"math"
 )
 
 const REWARSIXE = 50
+const TXVERSION = 0x01 //交易序列化格式的版本号
+const AMOUNTPRECISION = 1e-8 //比较交易金额时允许的浮点误差
 
 /**
  *定义交易的结构体
  */
 type Transaction struct {
-	//交易哈希
+	//交易版本号，决定交易的序列化格式
+	Version int64
+	//交易哈希，由交易的序列化数据计算得到，不参与序列化
 	TxHash [32]byte
 	//交易输入
 	Inputs  []TxInput
 	//交易输出
 	Outputs []TxOutPut
-	LockedTime int64 //表示交易生成的时间
+	//交易的锁定时间：0表示不锁定，小于script.LOCKTIMETHRESHOLD时表示区块高度，否则表示unix时间戳（秒）
+	//交易只能被打包进高度或时间大于锁定时间的区块中
+	LockedTime int64
 }
 
 /**
  *该函数用于定义一个coinbase交易，并返回该交易结构体
+ *height为该coinbase交易所在区块的高度，extra为附加数据（extra-nonce或留言），
+ *两者写入coinbase交易输入中，保证不同的coinbase交易具有不同的交易哈希
  */
-func CreateCoinBase(addr string) (*Transaction, error) {
+func CreateCoinBase(addr string, height int64, extra []byte) (*Transaction, error) {
+	input0, err := NewCoinbaseInput(height, extra)
+	if err != nil {
+		return nil, err
+	}
 
 	output0 := LockMoney2PubkHash(REWARSIXE, addr)
 
 	coinbase := Transaction{
+		Version: TXVERSION,
+		Inputs:  []TxInput{input0},
 		Outputs: []TxOutPut{output0},
-		LockedTime: time.Now().Unix(),
 	}
-	coinbaseBytes, err := utils.Encoder(coinbase)
+	coinbaseHash, err := coinbase.CalculateTxHash()
 	if err != nil {
 		return nil, err
 	}
-	coinbase.TxHash = sha256.Sum256(coinbaseBytes)
+	copy(coinbase.TxHash[:], coinbaseHash)
 
 	return &coinbase, nil
 }
@@ -50,6 +61,13 @@ func CreateCoinBase(addr string) (*Transaction, error) {
  *该函数用于构建一笔普通的交易，返回构建好的交易实例
  */
 func CreateNewTransaction(utxos []UTXO, from string,pubk []byte, to string, amount float64) (*Transaction, error) {
+	return CreateNewTransactionWithFee(utxos, from, pubk, to, amount, 0, from)
+}
+
+/**
+ *该函数用于构建一笔支付手续费的普通交易，找零中扣除fee作为交易的手续费，找零转入change地址
+ */
+func CreateNewTransactionWithFee(utxos []UTXO, from string,pubk []byte, to string, amount float64, fee float64, change string) (*Transaction, error) {
 	//1，构建inputs
 	inputs := make([]TxInput, 0)//用于存放交易输入的容器
 	var inputAmount float64//该变量用于记录转账发起者一共付了多少钱
@@ -71,31 +89,188 @@ func CreateNewTransaction(utxos []UTXO, from string,pubk []byte, to string, amou
 
 	//判断是否需要找零，如果需要找零，则需要构建一个新的找零输出
 
-    if inputAmount - amount > 0 {
-    	output1 := LockMoney2PubkHash(inputAmount - amount, from)
+    if inputAmount - amount - fee < -AMOUNTPRECISION {
+		return nil, errors.New("所花费utxo的金额不足以支付转账金额和手续费")
+	}
+    if inputAmount - amount - fee > AMOUNTPRECISION {
+    	output1 := LockMoney2PubkHash(inputAmount - amount - fee, change)
 		outputs = append(outputs, output1)
 	}
 
 	//3，构建transaction
 	newTransaction := Transaction{
+		Version: TXVERSION,
 		Inputs:  inputs,
 		Outputs: outputs,
 	}
 	//4，计算transaction的哈希，并赋值
-	transactionBytes, err := utils.Encoder(newTransaction)
-	newTransaction.TxHash = sha256.Sum256(transactionBytes)
+	txHash, err := newTransaction.CalculateTxHash()
 	if err != nil {
 		return nil, err
 	}
+	copy(newTransaction.TxHash[:], txHash)
 	//5，将构建的transaction实例进行返回
 	return &newTransaction, nil
 }
 
+/**
+ *该函数用于构建一笔批量转账的交易：花费utxos，为每个接收者构建一个交易输出，
+ *剩余资金扣除手续费fee后合并为一个找零输出给change，交易输入不携带公钥，由签名时填入
+ */
+func CreateBatchTransaction(utxos []UTXO, tos []string, amounts []float64, change string, fee float64) (*Transaction, error) {
+	if len(tos) == 0 || len(tos) != len(amounts) {
+		return nil, errors.New("接收者与转账金额的个数不一致")
+	}
+	inputs := make([]TxInput, 0)
+	var inputAmount float64
+	for _, utxo := range utxos {
+		inputs = append(inputs, NewTxInput(utxo.TxId, utxo.Vout, nil))
+		inputAmount += utxo.Value
+	}
+	outputs := make([]TxOutPut, 0)
+	var outputAmount float64
+	for index, to := range tos {
+		if amounts[index] <= 0 {
+			return nil, errors.New("转账金额必须大于0")
+		}
+		outputs = append(outputs, LockMoney2PubkHash(amounts[index], to))
+		outputAmount += amounts[index]
+	}
+	if inputAmount-outputAmount-fee < -AMOUNTPRECISION {
+		return nil, errors.New("所花费utxo的金额不足以支付全部转账和手续费")
+	}
+	if inputAmount-outputAmount-fee > AMOUNTPRECISION {
+		outputs = append(outputs, LockMoney2PubkHash(inputAmount-outputAmount-fee, change))
+	}
+	return NewRawTransaction(inputs, outputs, 0)
+}
+
+/**
+ *该函数用于构建一笔携带数据的交易：花费utxos，第一个输出为携带数据的输出，
+ *其余资金扣除手续费fee后全部转入找零地址change
+ */
+func CreateDataTransaction(utxos []UTXO, change string, pubk []byte, data []byte, fee float64) (*Transaction, error) {
+	if len(utxos) == 0 {
+		return nil, errors.New("构建数据交易至少需要花费一个utxo")
+	}
+	dataOutput, err := NewDataOutput(data)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]TxInput, 0)
+	var inputAmount float64
+	for _, utxo := range utxos {
+		inputs = append(inputs, NewTxInput(utxo.TxId, utxo.Vout, pubk))
+		inputAmount += utxo.Value
+	}
+	if inputAmount-fee < AMOUNTPRECISION {
+		return nil, errors.New("所花费utxo的金额不足以支付手续费")
+	}
+	outputs := []TxOutPut{dataOutput, LockMoney2PubkHash(inputAmount-fee, change)}
+
+	newTransaction := Transaction{
+		Version: TXVERSION,
+		Inputs:  inputs,
+		Outputs: outputs,
+	}
+	txHash, err := newTransaction.CalculateTxHash()
+	if err != nil {
+		return nil, err
+	}
+	copy(newTransaction.TxHash[:], txHash)
+	return &newTransaction, nil
+}
+
+/**
+ *使用给定的交易输入和交易输出构建一笔未签名的原始交易，交易输入不携带公钥，签名时再补充
+ */
+func NewRawTransaction(inputs []TxInput, outputs []TxOutPut, lockTime int64) (*Transaction, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, errors.New("交易至少需要一个输入和一个输出")
+	}
+	if lockTime < 0 {
+		return nil, errors.New("交易的锁定时间不能为负数")
+	}
+	for _, input := range inputs {
+		if input.IsCoinbaseInput() {
+			return nil, errors.New("原始交易不能包含coinbase输入")
+		}
+	}
+	newTransaction := Transaction{
+		Version:    TXVERSION,
+		Inputs:     inputs,
+		Outputs:    outputs,
+		LockedTime: lockTime,
+	}
+	txHash, err := newTransaction.CalculateTxHash()
+	if err != nil {
+		return nil, err
+	}
+	copy(newTransaction.TxHash[:], txHash)
+	return &newTransaction, nil
+}
+
+/**
+ *判断金额是否有效：金额必须是有限的非负数，NaN和无穷大会使后续所有的金额计算失效
+ */
+func IsValidAmount(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0) && value >= 0
+}
+
+/**
+ *检查交易的金额：每个输出的金额必须是有限的非负数，所有输出的金额之和不能大于所花费utxo的金额之和
+ */
+func (tx *Transaction) CheckAmounts(utxos []UTXO) error {
+	if len(tx.Inputs) != len(utxos) {
+		return errors.New("交易输入与所花费的utxo不匹配")
+	}
+	var inputAmount, outputAmount float64
+	for _, utxo := range utxos {
+		if !IsValidAmount(utxo.Value) {
+			return errors.New("交易所花费的utxo金额无效")
+		}
+		inputAmount += utxo.Value
+	}
+	for _, output := range tx.Outputs {
+		if !IsValidAmount(output.Value) {
+			return errors.New("交易输出的金额必须是有限的非负数")
+		}
+		outputAmount += output.Value
+	}
+	if !IsValidAmount(inputAmount) || !IsValidAmount(outputAmount) {
+		return errors.New("交易的金额之和无效")
+	}
+	if outputAmount > inputAmount+AMOUNTPRECISION {
+		return errors.New("交易输出的金额之和大于所花费utxo的金额之和")
+	}
+	return nil
+}
+
 /**
  *交易的签名验证方法，该方法返回一个bool值
  *返回true表示签名验证通过，返回false表示签名验证不通过
  */
 func (tx *Transaction) VerifyTx(utxos []UTXO) (bool, error) {
+	return tx.VerifyTxWithBatch(utxos, nil)
+}
+
+/**
+ *交易的签名验证方法，batch不为空时，schnorr签名不立即验证，而是加入到批量验证器中，
+ *由调用者在所有交易验证完成后统一执行批量验证
+ */
+func (tx *Transaction) VerifyTxWithBatch(utxos []UTXO, batch *wallet.SchnorrBatch) (bool, error) {
+	dataOutputs := 0
+	for _, output := range tx.Outputs {
+		if err := output.CheckScriptPub(); err != nil {
+			return false, err
+		}
+		if output.IsUnspendable() {
+			dataOutputs++
+		}
+	}
+	if dataOutputs > 1 {
+		return false, errors.New("每笔交易最多只能包含一个数据输出")
+	}
 	if tx.IsCoinbase() {//如果传入的交易是coinbase交易，不需要验签，直接返回true
 		return true, nil
 	}
@@ -104,91 +279,257 @@ func (tx *Transaction) VerifyTx(utxos []UTXO) (bool, error) {
 		//交易中的input与所引用的utxo个数不一致，直接返回false
 		return false, errors.New("签名验证失败")
 	}
-	//签名：私钥，原始数据 -> Hash计算 -> hash值
-    //验签：公钥，签名数据，原始数据
-	//为了使交易对象的结构能够恢复为签名时的状态，需要将当前的交易做一次副本拷贝，并修改其中的字段
-	txCopy := tx.CopyTx()
-	for index, _ := range txCopy.Inputs {
-		//①公钥：Input.PubK 原始公钥
-		//②签名数据：Input.Sig 签名字段
-		//③原始数据：交易的hash值
-
-		//a，把input中的sign置空
-		txCopy.Inputs[index].Sig = nil
-		//b，把input中的PubK替换为所引用的utxo的pubkhash
-		txCopy.Inputs[index].PubK = utxos[index].PubkHash
-		//c，计算改造后的交易的hash
-		txHash, err := txCopy.CalculateTxHash()
+	//每个交易输入的解锁脚本与所花费utxo的锁定脚本拼接执行，脚本中的签名验证由签名检查器完成
+	for index, input := range tx.Inputs {
+		checker := &txSigChecker{
+			tx:    tx,
+			index: index,
+			utxos: utxos,
+			batch: batch,
+		}
+		err := script.VerifyScript(input.GetScriptSig(), utxos[index].GetScriptPub(), checker)
 		if err != nil {
 			return false, err
 		}
-		//调用系统api的签名验证方法，进行验签
-		//公钥格式：[]byte -> PublicKey
-		pub := wallet.RecoverPublicKey(elliptic.P256(), tx.Inputs[index].PubK)
-		//签名格式：[]byte ->r,s *big.Int
-		r, s := wallet.ConverSignature(tx.Inputs[index].Sig)
-		isVerify := ecdsa.Verify(&pub, txHash, r, s)
-		if !isVerify{
-			return false, errors.New("验签失败")
-		}
 	}
 	return true, nil
 }
 
 /**
- *对交易进行签名
+ *对交易进行签名，使用SIGHASH_ALL类型对所有的交易输入进行签名
  */
-func (tx *Transaction) SignTx(priv *ecdsa.PrivateKey, utxos []UTXO) (error) {
+func (tx *Transaction) SignTx(signer wallet.Signer, utxos []UTXO) (error) {
 	if tx.IsCoinbase() {//判断传入的交易是否是coinbase交易，是则直接返回
 		return nil
 	}
 
-	var err error
 	if len(tx.Inputs) != len(utxos) {
-		err = errors.New("签名失败，请重试")
-		return err
+		return errors.New("签名失败，请重试")
 	}
-	txCopy := tx.CopyTx()
-    for i := 0; i < len(txCopy.Inputs); i++ {
-    	//遍历得到每一笔消费input
-    	//input := tx.Inputs[i]
-    	//找到每一笔消费input所对应的花费utxo
-    	utxo := utxos[i]
-    	//得到utxo对应的锁定脚本
-    	pubkHash := utxo.PubkHash
-    	//将对应的input中的解锁脚本中的值设置为对应utxo中的锁定脚本
-		txCopy.Inputs[i].PubK = pubkHash
-
-    	txHash, err := txCopy.CalculateTxHash()
+    for i := 0; i < len(tx.Inputs); i++ {
+    	err := tx.SignInput(i, signer, utxos, SIGHASH_ALL)
     	if err != nil {
     		return err
 		}
-		//真正的API签名调用
-		r, s, err := ecdsa.Sign(rand.Reader, priv, txHash)
-    	if err != nil {
-    		return err
+	}
+	return nil
+}
+
+/**
+ *使用指定的签名哈希类型对交易的第index个输入进行签名，签名哈希类型附加在签名数据的末尾
+ *所花费的utxo为多重签名输出时，签名合并到该输入已有的解锁脚本中，多个参与方可以依次签名
+ *所花费的utxo为P2SH输出时，需要先通过SetRedeemScript设置赎回脚本，签名使用赎回脚本作为scriptCode
+ */
+func (tx *Transaction) SignInput(index int, signer wallet.Signer, utxos []UTXO, hashType byte) error {
+	if index < 0 || index >= len(utxos) || len(tx.Inputs) != len(utxos) {
+		return errors.New("签名失败，交易输入与所花费的utxo不匹配")
+	}
+	scriptCode, pushes, redeemScript, err := tx.parseInputScript(index, utxos)
+	if err != nil {
+		return err
+	}
+
+	//带时间锁的脚本按照内部的花费条件签名，签名哈希仍然使用完整的scriptCode
+	template := script.StripTimeLock(scriptCode)
+	var newPushes [][]byte
+	switch {
+	case script.IsMultiSig(template):
+		newPushes, err = tx.signMultiSig(index, signer, utxos, scriptCode, pushes, hashType)
+		if err != nil {
+			return err
 		}
-		tx.Inputs[i].Sig = append(r.Bytes(), s.Bytes()...)
-		txCopy.Inputs[i].PubK = nil
+	case script.IsPayToPubKeyHash(template):
+		sigHash, err := CalcSignatureHash(tx, index, utxos, scriptCode, hashType)
+		if err != nil {
+			return err
+		}
+		//根据签名者所使用的曲线，调用对应的签名方法
+		sig, err := signer.Sign(sigHash)
+		if err != nil {
+			return err
+		}
+		//直接花费P2PKH输出时，签名和公钥保存在Sig和PubK字段中，由GetScriptSig生成解锁脚本
+		//公钥属于见证数据，原始交易在签名时才补充公钥，不会改变交易哈希
+		if redeemScript == nil {
+			tx.Inputs[index].Sig = append(sig, hashType)
+			if len(tx.Inputs[index].PubK) == 0 {
+				tx.Inputs[index].PubK = signer.PublicKey()
+			}
+			return nil
+		}
+		newPushes = [][]byte{append(sig, hashType), signer.PublicKey()}
+	default:
+		return errors.New("签名失败，不支持对该类型的锁定脚本签名")
+	}
+	if redeemScript != nil {
+		newPushes = append(newPushes, redeemScript)
 	}
-	return err
+	tx.Inputs[index].ScriptSig = script.PushOnlyScript(newPushes)
+	return nil
 }
 
 /**
- *交易的序列化
+ *为花费P2SH输出的交易输入设置赎回脚本，赎回脚本作为解锁脚本的最后一项数据压入
+ */
+func (tx *Transaction) SetRedeemScript(index int, redeemScript []byte) error {
+	if index < 0 || index >= len(tx.Inputs) {
+		return errors.New("交易输入的下标超出范围")
+	}
+	tx.Inputs[index].ScriptSig = script.PushOnlyScript([][]byte{redeemScript})
+	return nil
+}
+
+/**
+ *解析交易输入的解锁脚本，返回签名所使用的scriptCode、解锁脚本中除赎回脚本以外的数据以及赎回脚本
+ *花费P2SH输出时，scriptCode为赎回脚本，并且赎回脚本的哈希必须与所花费utxo的脚本哈希一致
+ */
+func (tx *Transaction) parseInputScript(index int, utxos []UTXO) ([]byte, [][]byte, []byte, error) {
+	scriptPub := utxos[index].GetScriptPub()
+	var pushes [][]byte
+	if len(tx.Inputs[index].ScriptSig) > 0 {
+		var err error
+		pushes, err = script.PushedData(tx.Inputs[index].ScriptSig)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if !script.IsPayToScriptHash(scriptPub) {
+		return scriptPub, pushes, nil, nil
+	}
+	if len(pushes) == 0 {
+		return nil, nil, nil, errors.New("花费P2SH输出需要先设置赎回脚本")
+	}
+	redeemScript := pushes[len(pushes)-1]
+	if !bytes.Equal(script.Hash160(redeemScript), script.ExtractScriptHash(scriptPub)) {
+		return nil, nil, nil, errors.New("赎回脚本与P2SH输出的脚本哈希不一致")
+	}
+	return redeemScript, pushes[:len(pushes)-1], redeemScript, nil
+}
+
+/**
+ *交易的完整序列化，格式如下（整数均为大端序）：
+ *version(8) + 输入个数(4) + 每个输入 + 输出个数(4) + 每个输出 + 每个输入的见证数据 + lockedtime(8)
+ *交易哈希由去除见证数据后的序列化数据计算得到，因此TxHash字段本身不参与序列化
  */
 func (tx *Transaction) Serialize() ([]byte, error) {
-	txBytes, err := utils.Encoder(tx)
+	return tx.serialize(true)
+}
+
+/**
+ *交易的非见证序列化，不包含每个输入的签名和公钥，用于计算交易哈希（txid）
+ *签名的重新编码不会改变该序列化数据，因此交易哈希不具有延展性
+ */
+func (tx *Transaction) SerializeNoWitness() ([]byte, error) {
+	return tx.serialize(false)
+}
+
+func (tx *Transaction) serialize(withWitness bool) ([]byte, error) {
+	if tx.Version != TXVERSION {
+		return nil, errors.New("不支持的交易版本")
+	}
+	buff := new(bytes.Buffer)
+	utils.WriteInt64(buff, tx.Version)
+	utils.WriteUint32(buff, uint32(len(tx.Inputs)))
+	for index := range tx.Inputs {
+		tx.Inputs[index].Serialize(buff)
+	}
+	utils.WriteUint32(buff, uint32(len(tx.Outputs)))
+	for index := range tx.Outputs {
+		tx.Outputs[index].Serialize(buff)
+	}
+	if withWitness {
+		for index := range tx.Inputs {
+			tx.Inputs[index].SerializeWitness(buff)
+		}
+	}
+	utils.WriteInt64(buff, tx.LockedTime)
+	return buff.Bytes(), nil
+}
+
+/**
+ *交易的反序列化，反序列化完成后重新计算交易哈希
+ */
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+	reader := bytes.NewReader(data)
+	tx, err := ReadTransaction(reader)
 	if err != nil {
 		return nil, err
 	}
-	return txBytes, nil
+	if reader.Len() != 0 {
+		return nil, errors.New("反序列化失败，交易数据末尾存在多余的字节")
+	}
+	return tx, nil
 }
 
 /**
- *计算交易哈希值
+ *从reader中读取一笔完整序列化的交易
+ */
+func ReadTransaction(reader *bytes.Reader) (*Transaction, error) {
+	var tx Transaction
+	var err error
+	if tx.Version, err = utils.ReadInt64(reader); err != nil {
+		return nil, err
+	}
+	if tx.Version != TXVERSION {
+		return nil, errors.New("不支持的交易版本")
+	}
+	//每个交易输入至少包含txid、vout、sequence、一个长度前缀以及见证数据的三个长度前缀
+	inputNum, err := utils.ReadCount(reader, 32 + 8 + 4 + 4*4)
+	if err != nil {
+		return nil, err
+	}
+	tx.Inputs = make([]TxInput, 0, inputNum)
+	for i := 0; i < inputNum; i++ {
+		input, err := DeserializeTxInput(reader)
+		if err != nil {
+			return nil, err
+		}
+		tx.Inputs = append(tx.Inputs, input)
+	}
+	//每个交易输出至少包含value和两个长度前缀
+	outputNum, err := utils.ReadCount(reader, 8 + 4*2)
+	if err != nil {
+		return nil, err
+	}
+	tx.Outputs = make([]TxOutPut, 0, outputNum)
+	for i := 0; i < outputNum; i++ {
+		output, err := DeserializeTxOutPut(reader)
+		if err != nil {
+			return nil, err
+		}
+		tx.Outputs = append(tx.Outputs, output)
+	}
+	for index := range tx.Inputs {
+		if err = tx.Inputs[index].DeserializeWitness(reader); err != nil {
+			return nil, err
+		}
+	}
+	if tx.LockedTime, err = utils.ReadInt64(reader); err != nil {
+		return nil, err
+	}
+	txHash, err := tx.CalculateTxHash()
+	if err != nil {
+		return nil, err
+	}
+	copy(tx.TxHash[:], txHash)
+	return &tx, nil
+}
+
+/**
+ *计算交易哈希值（txid），只对交易的非见证数据进行哈希计算
  */
 func (tx *Transaction) CalculateTxHash() ([]byte, error) {
+	txBytes, err := tx.SerializeNoWitness()
+	if err != nil{
+		return nil, err
+	}
+	return utils.Hash256(txBytes), nil
+}
+
+/**
+ *计算交易的见证哈希值（wtxid），对包含签名和公钥在内的完整交易数据进行哈希计算
+ */
+func (tx *Transaction) CalculateWitnessHash() ([]byte, error) {
 	txBytes, err := tx.Serialize()
 	if err != nil{
 		return nil, err
@@ -205,8 +546,11 @@ func (tx Transaction)  CopyTx() Transaction {
         txIn := TxInput{
 			TxId: input.TxId,
 			Vout: input.Vout,
+			Sequence: input.Sequence,
+			ScriptSig: input.ScriptSig,
 			Sig:  input.Sig,
 			PubK: input.PubK,
+			Coinbase: input.Coinbase,
 		}
 		inputs = append(inputs, txIn)
 	}
@@ -215,6 +559,7 @@ func (tx Transaction)  CopyTx() Transaction {
 	for _, output := range tx.Outputs {
 		txOut := TxOutPut{
 			Value:    output.Value,
+			ScriptPub: output.ScriptPub,
 			PubkHash: output.PubkHash,
 		}
 		outputs = append(outputs, txOut)
@@ -222,16 +567,19 @@ func (tx Transaction)  CopyTx() Transaction {
 	hash := tx.TxHash
 
 	return Transaction{
+		Version: tx.Version,
 		TxHash:  hash,
 		Inputs:  inputs,
 		Outputs: outputs,
+		LockedTime: tx.LockedTime,
 	}
 }
 
 /**
  *该方法用于判断某个具体交易是否是coinbase交易
+ *coinbase交易有且只有一个交易输入，且该输入为携带区块高度的coinbase交易输入
  *是返回true，不是返回false
  */
 func (tx *Transaction) IsCoinbase() bool {
-	return len(tx.Inputs) == 0 && len(tx.Outputs) == 1
+	return len(tx.Inputs) == 1 && tx.Inputs[0].IsCoinbaseInput()
 }
\ No newline at end of file
@@ -0,0 +1,207 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+const testAddress = "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"
+
+/**
+ *构建一笔交易并计算交易哈希
+ */
+func newTestTx(t *testing.T, inputs []TxInput, outputs []TxOutPut, lockTime int64) *Transaction {
+	tx := &Transaction{Version: TXVERSION, Inputs: inputs, Outputs: outputs, LockedTime: lockTime}
+	txHash, err := tx.CalculateTxHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(tx.TxHash[:], txHash)
+	return tx
+}
+
+/**
+ *测试用的交易：coinbase交易、多输入交易、携带见证数据的交易和带锁定时间的交易
+ */
+func testTxs(t *testing.T) map[string]*Transaction {
+	coinbase, err := CreateCoinBase(testAddress, 7, []byte("extra"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := LockMoney2PubkHash(1.5, testAddress)
+	dataOutput, err := NewDataOutput([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	multiInput := newTestTx(t, []TxInput{
+		NewTxInput([32]byte{1}, 0, nil),
+		NewTxInput([32]byte{2}, 3, nil),
+		NewTxInput([32]byte{3}, 1, nil),
+	}, []TxOutPut{output, dataOutput}, 0)
+
+	witnessInput := NewTxInput([32]byte{4}, 2, []byte{0x02, 0xaa, 0xbb})
+	witnessInput.Sig = []byte{0x30, 0x01, 0x02}
+	witnessInput.ScriptSig = []byte{0x51}
+	witness := newTestTx(t, []TxInput{witnessInput}, []TxOutPut{output}, 0)
+
+	lockedInput := NewTxInput([32]byte{5}, 0, nil)
+	lockedInput.Sequence = 10
+	locked := newTestTx(t, []TxInput{lockedInput}, []TxOutPut{output}, 500)
+
+	return map[string]*Transaction{
+		"coinbase":   coinbase,
+		"multiinput": multiInput,
+		"witness":    witness,
+		"locktime":   locked,
+	}
+}
+
+func TestTransactionRoundTrip(t *testing.T) {
+	for name, tx := range testTxs(t) {
+		data, err := tx.Serialize()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		decoded, err := DeserializeTransaction(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(tx, decoded) {
+			t.Fatalf("%s: 反序列化结果不一致\n%+v\n%+v", name, tx, decoded)
+		}
+	}
+}
+
+func TestTxInputOutputRoundTrip(t *testing.T) {
+	input := NewTxInput([32]byte{9}, 4, []byte{0x03, 0x01})
+	input.Sig = []byte{0x30, 0x02}
+	input.ScriptSig = []byte{0x00, 0x51}
+	buff := new(bytes.Buffer)
+	input.Serialize(buff)
+	input.SerializeWitness(buff)
+	reader := bytes.NewReader(buff.Bytes())
+	decodedInput, err := DeserializeTxInput(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = decodedInput.DeserializeWitness(reader); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input, decodedInput) || reader.Len() != 0 {
+		t.Fatalf("交易输入反序列化结果不一致\n%+v\n%+v", input, decodedInput)
+	}
+
+	output := LockMoney2PubkHash(0.25, testAddress)
+	buff.Reset()
+	output.Serialize(buff)
+	reader = bytes.NewReader(buff.Bytes())
+	decodedOutput, err := DeserializeTxOutPut(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output, decodedOutput) || reader.Len() != 0 {
+		t.Fatalf("交易输出反序列化结果不一致\n%+v\n%+v", output, decodedOutput)
+	}
+}
+
+/**
+ *固定的测试向量：其他实现可以用它检查序列化格式和交易哈希的计算是否一致
+ */
+func TestTransactionVector(t *testing.T) {
+	input := NewTxInput([32]byte{1, 2, 3}, 1, nil)
+	tx := newTestTx(t, []TxInput{input}, []TxOutPut{LockMoney2PubkHash(1.5, testAddress)}, 100)
+	data, err := tx.SerializeNoWitness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//version + 1个输入(txid 010203..., vout 1, sequence fffffffe, 空coinbase) + 1个输出(1.5) + locktime 100
+	const wantData = "0000000000000001" + "00000001" +
+		"0102030000000000000000000000000000000000000000000000000000000000" + "0000000000000001" + "fffffffe" + "00000000" +
+		"00000001" + "3ff8000000000000" + "00000015" + "007680adec8eabcabac676be9e83854ade0bd22cdb" +
+		"00000019" + "76a9147680adec8eabcabac676be9e83854ade0bd22cdb88ac" +
+		"0000000000000064"
+	//交易哈希为非见证序列化数据的sha256
+	const wantTxId = "9dfcd38b9eec37483fb5fb2fff9a010db3415566a6b63af49a2d563cc7d22163"
+	if hex.EncodeToString(data) != wantData {
+		t.Fatalf("序列化数据不一致：%x", data)
+	}
+	if hex.EncodeToString(tx.TxHash[:]) != wantTxId {
+		t.Fatalf("交易哈希不一致：%x", tx.TxHash)
+	}
+}
+
+func TestDeserializeTransactionRejects(t *testing.T) {
+	data, err := testTxs(t)["multiinput"].Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for length := 0; length < len(data); length++ {
+		if _, err := DeserializeTransaction(data[:length]); err == nil {
+			t.Fatalf("截断到%d字节的交易数据应当被拒绝", length)
+		}
+	}
+	if _, err := DeserializeTransaction(append(data, 0x00)); err == nil {
+		t.Fatal("末尾存在多余字节的交易数据应当被拒绝")
+	}
+
+	unknown := append([]byte{}, data...)
+	unknown[7] = TXVERSION + 1
+	if _, err := DeserializeTransaction(unknown); err == nil {
+		t.Fatal("未知版本的交易数据应当被拒绝")
+	}
+	tx := testTxs(t)["witness"]
+	tx.Version = TXVERSION + 1
+	if _, err := tx.Serialize(); err == nil {
+		t.Fatal("未知版本的交易不应当被序列化")
+	}
+}
+
+/**
+ *金额为NaN或无穷大的输出会污染余额和coinbase奖励，必须被拒绝
+ */
+func TestCheckAmountsRejectsNonFinite(t *testing.T) {
+	utxo := UTXO{TxId: [32]byte{1}, Vout: 0, TxOutPut: LockMoney2PubkHash(5, testAddress)}
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), -1} {
+		tx := newTestTx(t, []TxInput{NewTxInput(utxo.TxId, utxo.Vout, nil)},
+			[]TxOutPut{LockMoney2PubkHash(1, testAddress), LockMoney2PubkHash(value, testAddress)}, 0)
+		if err := tx.CheckAmounts([]UTXO{utxo}); err == nil {
+			t.Fatalf("金额为%v的输出应当被拒绝", value)
+		}
+		if _, err := tx.Fee([]UTXO{utxo}); err == nil {
+			t.Fatalf("金额为%v的输出不应当计算出手续费", value)
+		}
+	}
+
+	//反序列化得到的NaN输出同样被拒绝
+	tx := newTestTx(t, []TxInput{NewTxInput(utxo.TxId, utxo.Vout, nil)}, []TxOutPut{LockMoney2PubkHash(1, testAddress)}, 0)
+	data, err := tx.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	valueOffset := bytes.Index(data, []byte{0x3f, 0xf0, 0, 0, 0, 0, 0, 0})
+	copy(data[valueOffset:], []byte{0x7f, 0xf8, 0, 0, 0, 0, 0, 0})
+	decoded, err := DeserializeTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = decoded.CheckAmounts([]UTXO{utxo}); err == nil {
+		t.Fatal("反序列化得到的NaN输出应当被拒绝")
+	}
+
+	utxo.Value = math.NaN()
+	tx = newTestTx(t, []TxInput{NewTxInput(utxo.TxId, utxo.Vout, nil)}, []TxOutPut{LockMoney2PubkHash(1, testAddress)}, 0)
+	if err = tx.CheckAmounts([]UTXO{utxo}); err == nil {
+		t.Fatal("花费金额为NaN的utxo应当被拒绝")
+	}
+
+	coinbase, err := CreateCoinBase(testAddress, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = coinbase.AddCoinbaseFees(math.NaN()); err == nil {
+		t.Fatal("金额为NaN的手续费应当被拒绝")
+	}
+}
@@ -1,9 +1,12 @@
 package transaction
 
 import (
+	"XianfengChain04/script"
 	"XianfengChain04/utils"
 	"XianfengChain04/wallet"
 	"bytes"
+	"encoding/binary"
+	"errors"
 )
 
 /**
@@ -12,23 +15,77 @@ import (
 type TxInput struct {
 	TxId      [32]byte  //该字段确定引用自哪笔交易
 	Vout      int       //该字段确定引用自该交易的哪笔输出
-	//ScritpSig []byte    //该字段表示使用交易输出的证明，解锁脚本
+	Sequence  uint32    //相对时间锁，编码方式见script.SEQUENCEDISABLEFLAG等常量
+	ScriptSig []byte    //该字段表示使用交易输出的证明，解锁脚本，为空时由Sig和PubK组成标准的P2PKH解锁脚本
     Sig []byte // 签名
 	PubK []byte// 原始公钥
+	Coinbase []byte//coinbase交易输入所携带的数据：区块高度 + 附加数据（extra-nonce或留言）
 }
 
+const COINBASEVOUT = -1 //coinbase交易输入的vout标志，表示不引用任何交易输出
+
+/**
+ *普通交易输入默认的sequence：不启用相对时间锁，但允许交易的锁定时间生效
+ */
+const DEFAULTSEQUENCE = script.SEQUENCEFINAL - 1
+
 /**
  *该函数用于生成一个交易输入案例，即一笔新的花费
  */
 func NewTxInput(txid [32]byte, vout int, pubk []byte) TxInput {
 	input := TxInput{
-		TxId: txid,
-		Vout: vout,
-		PubK: pubk,
+		TxId:     txid,
+		Vout:     vout,
+		Sequence: DEFAULTSEQUENCE,
+		PubK:     pubk,
 	}
 	return input
 }
 
+/**
+ *该函数用于生成coinbase交易的交易输入，该输入不引用任何交易输出
+ *Coinbase字段的前8个字节为区块高度，其后为矿工自定义的附加数据
+ */
+func NewCoinbaseInput(height int64, extra []byte) (TxInput, error) {
+	heightBytes, err := utils.Int2Byte(height)
+	if err != nil {
+		return TxInput{}, err
+	}
+	input := TxInput{
+		Vout:     COINBASEVOUT,
+		Sequence: script.SEQUENCEFINAL,
+		Coinbase: append(heightBytes, extra...),
+	}
+	return input, nil
+}
+
+/**
+ *判断某个交易输入是否是coinbase交易输入
+ */
+func (input *TxInput) IsCoinbaseInput() bool {
+	return input.TxId == [32]byte{} && input.Vout == COINBASEVOUT && len(input.Coinbase) >= 8
+}
+
+/**
+ *获取coinbase交易输入中所记录的区块高度
+ */
+func (input *TxInput) GetCoinbaseHeight() (int64, error) {
+	if !input.IsCoinbaseInput() {
+		return 0, errors.New("该交易输入不是coinbase交易输入")
+	}
+	return int64(binary.BigEndian.Uint64(input.Coinbase[:8])), nil
+}
+
+/**
+ *获取交易输入的解锁脚本，未设置解锁脚本时，使用签名和原始公钥生成P2PKH的解锁脚本
+ */
+func (input *TxInput) GetScriptSig() []byte {
+	if len(input.ScriptSig) > 0 {
+		return input.ScriptSig
+	}
+	return script.PubKeyHashSigScript(input.Sig, input.PubK)
+}
+
 /**
  *验证某个TxInput是否是某个特定address的消费
  */
@@ -50,3 +107,64 @@ func (input *TxInput) VertifyInputWithAddress(address string) bool {
 	rePubkHash := reAddress[:len(reAddress) - 4]
 	return bytes.Compare(pubkHash, rePubkHash) == 0
 }
+
+/**
+ *交易输入非见证部分的二进制序列化：txid(32) + vout(8) + sequence(4) + coinbase(带4字节长度前缀)
+ *该部分参与交易哈希（txid）的计算
+ */
+func (input *TxInput) Serialize(buff *bytes.Buffer) {
+	buff.Write(input.TxId[:])
+	utils.WriteInt64(buff, int64(input.Vout))
+	utils.WriteUint32(buff, input.Sequence)
+	utils.WriteVarBytes(buff, input.Coinbase)
+}
+
+/**
+ *交易输入见证部分的二进制序列化：sig + pubk + scriptsig，均带4字节长度前缀
+ *见证数据不参与交易哈希（txid）的计算，只参与见证哈希的计算
+ */
+func (input *TxInput) SerializeWitness(buff *bytes.Buffer) {
+	utils.WriteVarBytes(buff, input.Sig)
+	utils.WriteVarBytes(buff, input.PubK)
+	utils.WriteVarBytes(buff, input.ScriptSig)
+}
+
+/**
+ *交易输入非见证部分的反序列化
+ */
+func DeserializeTxInput(reader *bytes.Reader) (TxInput, error) {
+	var input TxInput
+	var err error
+	if input.TxId, err = utils.ReadHash(reader); err != nil {
+		return input, err
+	}
+	vout, err := utils.ReadInt64(reader)
+	if err != nil {
+		return input, err
+	}
+	input.Vout = int(vout)
+	if input.Sequence, err = utils.ReadUint32(reader); err != nil {
+		return input, err
+	}
+	if input.Coinbase, err = utils.ReadVarBytes(reader); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+/**
+ *交易输入见证部分的反序列化，读取到的见证数据写入input中
+ */
+func (input *TxInput) DeserializeWitness(reader *bytes.Reader) error {
+	var err error
+	if input.Sig, err = utils.ReadVarBytes(reader); err != nil {
+		return err
+	}
+	if input.PubK, err = utils.ReadVarBytes(reader); err != nil {
+		return err
+	}
+	if input.ScriptSig, err = utils.ReadVarBytes(reader); err != nil {
+		return err
+	}
+	return nil
+}
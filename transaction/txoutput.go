@@ -1,8 +1,11 @@
 package transaction
 
 import (
+	"XianfengChain04/script"
 	"XianfengChain04/utils"
+	"XianfengChain04/wallet"
 	"bytes"
+	"errors"
 )
 
 /**
@@ -10,8 +13,8 @@ import (
  */
 type TxOutPut struct {
 	Value     float64 //转账数量
-	//ScriptPub []byte  //锁定脚本
-    PubkHash []byte //公钥哈希
+	ScriptPub []byte  //锁定脚本，花费该输出时需要提供能够解锁该脚本的解锁脚本
+    PubkHash []byte //公钥哈希：地址版本号 + 地址载荷，用于按地址查询余额和utxo
 }
 
 /**
@@ -26,9 +29,119 @@ func LockMoney2PubkHash(value float64, addr string) TxOutPut {
 		Value:    value,
 		PubkHash: pubkHash,
 	}
+	//3，根据地址的版本号生成对应的标准锁定脚本
+	out.ScriptPub = out.GetScriptPub()
 	return out
 }
 
+/**
+ *生成一个携带数据的交易输出，该输出的金额为0，不属于任何地址，并且可证明无法被花费
+ */
+func NewDataOutput(data []byte) (TxOutPut, error) {
+	scriptPub, err := script.NullDataScript(data)
+	if err != nil {
+		return TxOutPut{}, err
+	}
+	return TxOutPut{ScriptPub: scriptPub}, nil
+}
+
+/**
+ *判断交易输出是否可证明无法被花费，这类输出不会被保存到utxo集合中
+ */
+func (output *TxOutPut) IsUnspendable() bool {
+	return script.IsUnspendable(output.GetScriptPub())
+}
+
+/**
+ *获取交易输出的锁定脚本，未设置锁定脚本时，根据公钥哈希的版本号生成对应的标准锁定脚本：
+ *普通地址生成P2PKH锁定脚本，P2SH地址生成P2SH锁定脚本，多重签名地址的载荷本身就是多重签名锁定脚本
+ */
+func (output *TxOutPut) GetScriptPub() []byte {
+	if len(output.ScriptPub) > 0 {
+		return output.ScriptPub
+	}
+	if len(output.PubkHash) == 0 {
+		return nil
+	}
+	switch output.PubkHash[0] {
+	case wallet.VERSION:
+		if len(output.PubkHash) != 1+script.PUBKHASHLEN {
+			return nil
+		}
+		scriptPub, _ := script.PayToPubKeyHashScript(output.PubkHash[1:])
+		return scriptPub
+	case wallet.P2SHVERSION:
+		if len(output.PubkHash) != 1+script.SCRIPTHASHLEN {
+			return nil
+		}
+		scriptPub, _ := script.PayToScriptHashScript(output.PubkHash[1:])
+		return scriptPub
+	case wallet.MULTISIGVERSION:
+		if !script.IsMultiSig(output.PubkHash[1:]) {
+			return nil
+		}
+		return output.PubkHash[1:]
+	}
+	return nil
+}
+
+/**
+ *使用锁定脚本生成交易输出，公钥哈希根据锁定脚本的类型计算得到
+ */
+func NewTxOutPutWithScript(value float64, scriptPub []byte) (TxOutPut, error) {
+	pubkHash, err := scriptPubkHash(scriptPub)
+	if err != nil {
+		return TxOutPut{}, err
+	}
+	output := TxOutPut{
+		Value:     value,
+		ScriptPub: scriptPub,
+		PubkHash:  pubkHash,
+	}
+	if err = output.CheckScriptPub(); err != nil {
+		return TxOutPut{}, err
+	}
+	return output, nil
+}
+
+/**
+ *根据标准锁定脚本计算对应的公钥哈希（地址版本号 + 地址载荷），数据输出不属于任何地址，返回nil
+ */
+func scriptPubkHash(scriptPub []byte) ([]byte, error) {
+	switch {
+	case script.IsPayToPubKeyHash(scriptPub):
+		return append([]byte{wallet.VERSION}, script.ExtractPubKeyHash(scriptPub)...), nil
+	case script.IsPayToScriptHash(scriptPub):
+		return append([]byte{wallet.P2SHVERSION}, script.ExtractScriptHash(scriptPub)...), nil
+	case script.IsMultiSig(scriptPub):
+		return append([]byte{wallet.MULTISIGVERSION}, scriptPub...), nil
+	case script.IsNullData(scriptPub):
+		return nil, nil
+	}
+	return nil, errors.New("不支持的锁定脚本类型")
+}
+
+/**
+ *检查锁定脚本与公钥哈希是否一致，防止输出按地址统计的归属与实际的花费条件不符
+ */
+func (output *TxOutPut) CheckScriptPub() error {
+	if len(output.ScriptPub) > script.MAXSCRIPTSIZE {
+		return errors.New("锁定脚本长度超出限制")
+	}
+	expected, err := scriptPubkHash(output.ScriptPub)
+	if err != nil {
+		return err
+	}
+	//数据输出不属于任何地址，也不能携带金额，避免资金被意外销毁
+	if script.IsNullData(output.ScriptPub) && output.Value != 0 {
+		return errors.New("数据输出的金额必须为0")
+	}
+	if !bytes.Equal(expected, output.PubkHash) {
+		return errors.New("锁定脚本与公钥哈希不一致")
+	}
+	return nil
+}
+
 /**
  *该函数用于验证某个交易输出是否属于某个地址
  */
@@ -40,3 +153,30 @@ func (output *TxOutPut) CheckPubKHashWithAddress(address string) bool {
 	//3，比较给定address的公钥哈希与output的公钥哈希是否相等
 	return bytes.Compare(pubKHash, output.PubkHash) == 0
 }
+
+/**
+ *交易输出的二进制序列化：value(8，IEEE754) + pubkhash + scriptpub，后两者均带4字节长度前缀
+ */
+func (output *TxOutPut) Serialize(buff *bytes.Buffer) {
+	utils.WriteFloat64(buff, output.Value)
+	utils.WriteVarBytes(buff, output.PubkHash)
+	utils.WriteVarBytes(buff, output.ScriptPub)
+}
+
+/**
+ *交易输出的反序列化
+ */
+func DeserializeTxOutPut(reader *bytes.Reader) (TxOutPut, error) {
+	var output TxOutPut
+	var err error
+	if output.Value, err = utils.ReadFloat64(reader); err != nil {
+		return output, err
+	}
+	if output.PubkHash, err = utils.ReadVarBytes(reader); err != nil {
+		return output, err
+	}
+	if output.ScriptPub, err = utils.ReadVarBytes(reader); err != nil {
+		return output, err
+	}
+	return output, nil
+}
@@ -1,8 +1,6 @@
 package transaction
 
 import (
-	"XianfengChain04/utils"
-	"XianfengChain04/wallet"
 	"bytes"
 )
 
@@ -13,6 +11,8 @@ type UTXO struct {
 	TxId [32]byte //该笔收入来自哪个交易
 	Vout int  //该笔收入来自哪个交易输出
     TxOutPut  //该笔收入的面额和拥有者
+	Height int64 //该笔收入被确认时所在区块的高度，用于判断相对时间锁
+	Time   int64 //该笔收入被确认时所在区块的时间戳
 }
 
 
@@ -30,21 +30,14 @@ func NewUTXO(txid [32]byte, vout int, out TxOutPut) UTXO {
 
 /**
  *判定某个utxo是否被某个交易引用进而被消费了
+ *交易输入通过txid和vout唯一确定所引用的utxo，花费者的身份由解锁脚本在验签时证明
  */
 func (utxo *UTXO) IsUTXOSpend(spend TxInput) bool {
 	//判断txid是否一致
 	equalTxId := bytes.Compare(utxo.TxId[:], spend.TxId[:]) == 0
-	//判断索引的下标是q否一致
+	//判断索引的下标是否一致
 	equalVout := utxo.Vout == spend.Vout
-	//判断消费者是否一致
-	//utxo.punkHash：公钥哈希
-	//spend.Punk：原始公钥
-	hash := utils.Hash256(spend.PubK)
-	ripemd160 := utils.HashRipemd160(hash)
-	pubkHash := append([]byte{wallet.VERSION}, ripemd160...)
-	equalConsumer := bytes.Compare(pubkHash, utxo.PubkHash) == 0
-	//三个条件同时满足
-	return equalTxId && equalVout && equalConsumer
+	return equalTxId && equalVout
 }
 
 /**
@@ -55,6 +55,15 @@ func JSONArray2Float(array string) ([]float64, error) {
 	return floatSlice, err
 }
 
+/**
+ *将json格式的对象转化为字符串到浮点型数据的映射
+ */
+func JSONObject2FloatMap(object string) (map[string]float64, error) {
+	var floatMap map[string]float64
+	err := json.Unmarshal([]byte(object), &floatMap)
+	return floatMap, err
+}
+
 /**
  *sha256哈希计算
  */
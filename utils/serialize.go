@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+/**
+ *该文件定义了区块和交易的二进制序列化所使用的基础编码规则：
+ *所有整数均使用大端序定长编码，变长数据使用4字节长度前缀 + 数据内容
+ *该编码规则与go语言的实现无关，其他语言的实现可以据此计算出相同的哈希值
+ */
+
+/**
+ *写入4字节的无符号整数
+ */
+func WriteUint32(buff *bytes.Buffer, num uint32) {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, num)
+	buff.Write(data)
+}
+
+/**
+ *写入8字节的有符号整数
+ */
+func WriteInt64(buff *bytes.Buffer, num int64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(num))
+	buff.Write(data)
+}
+
+/**
+ *写入8字节的浮点数，按照IEEE754格式编码
+ */
+func WriteFloat64(buff *bytes.Buffer, num float64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(num))
+	buff.Write(data)
+}
+
+/**
+ *写入带长度前缀的变长数据
+ */
+func WriteVarBytes(buff *bytes.Buffer, data []byte) {
+	WriteUint32(buff, uint32(len(data)))
+	buff.Write(data)
+}
+
+/**
+ *读取4字节的无符号整数
+ */
+func ReadUint32(reader *bytes.Reader) (uint32, error) {
+	data := make([]byte, 4)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return 0, errors.New("反序列化失败，数据长度不足")
+	}
+	return binary.BigEndian.Uint32(data), nil
+}
+
+/**
+ *读取8字节的有符号整数
+ */
+func ReadInt64(reader *bytes.Reader) (int64, error) {
+	data := make([]byte, 8)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return 0, errors.New("反序列化失败，数据长度不足")
+	}
+	return int64(binary.BigEndian.Uint64(data)), nil
+}
+
+/**
+ *读取8字节的浮点数
+ */
+func ReadFloat64(reader *bytes.Reader) (float64, error) {
+	num, err := ReadInt64(reader)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(uint64(num)), nil
+}
+
+/**
+ *读取32字节的哈希值
+ */
+func ReadHash(reader *bytes.Reader) ([32]byte, error) {
+	var hash [32]byte
+	if _, err := io.ReadFull(reader, hash[:]); err != nil {
+		return hash, errors.New("反序列化失败，数据长度不足")
+	}
+	return hash, nil
+}
+
+/**
+ *读取带长度前缀的变长数据，长度前缀超过剩余数据长度时返回错误
+ */
+func ReadVarBytes(reader *bytes.Reader) ([]byte, error) {
+	length, err := ReadUint32(reader)
+	if err != nil {
+		return nil, err
+	}
+	if int64(length) > int64(reader.Len()) {
+		return nil, errors.New("反序列化失败，长度前缀超出数据范围")
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, errors.New("反序列化失败，数据长度不足")
+	}
+	return data, nil
+}
+
+/**
+ *读取元素个数，每个元素至少占用minSize个字节，个数超出剩余数据所能容纳的范围时返回错误
+ */
+func ReadCount(reader *bytes.Reader, minSize int) (int, error) {
+	count, err := ReadUint32(reader)
+	if err != nil {
+		return 0, err
+	}
+	if int64(count)*int64(minSize) > int64(reader.Len()) {
+		return 0, errors.New("反序列化失败，元素个数超出数据范围")
+	}
+	return int(count), nil
+}
@@ -4,12 +4,14 @@ import (
 	"XianfengChain04/transaction"
 	"XianfengChain04/utils"
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"github.com/boltdb/bolt"
 )
 
 const UTXOSET = "utxoset"//存放utxoset的桶名
+const UTXOINDEX = "utxoindex"//存放utxo所属地址索引的桶名：txid + vout -> 地址
 
 /**
  *UTXO集合，表示用于优化代码结构，实现快速查询
@@ -40,9 +42,9 @@ func (utxoset *UTXOSet) QueryUTXOsByAddress(address string) ([]transaction.UTXO,
 	var err error
 
 	engine := utxoset.Engine
-	engine.View(func(tx *bolt.Tx) error {
+	err = engine.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(UTXOSET))
-		if bucket != nil {
+		if bucket == nil {
 			return nil
 		}
 		utxosBytes := bucket.Get([]byte(address))
@@ -66,7 +68,7 @@ func (utxuset *UTXOSet) AddUTXOsWithAddress(address string, utxos []transaction.
 	var err error
 	engine := utxuset.Engine
 
-	engine.Update(func(tx *bolt.Tx) error {
+	err = engine.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(UTXOSET))
 		if bucket == nil {
 			bucket, err = tx.CreateBucket([]byte(UTXOSET))
@@ -91,8 +93,12 @@ func (utxuset *UTXOSet) AddUTXOsWithAddress(address string, utxos []transaction.
 		if len(existUTXOs) > 0 {
 			sumUTXOs = append(sumUTXOs, existUTXOs...)
 		}
-		//把此次新增的utxo数据追加到bolt.DB中
-		sumUTXOs = append(sumUTXOs, utxos...)
+		//把此次新增的utxo数据追加到bolt.DB中，可证明无法被花费的数据输出不需要保存
+		for _, utxo := range utxos {
+			if !utxo.IsUnspendable() {
+				sumUTXOs = append(sumUTXOs, utxo)
+			}
+		}
 
 		//将utxos进行序列化
 		utxosBytes, err := utils.Encoder(sumUTXOs)
@@ -101,11 +107,55 @@ func (utxuset *UTXOSet) AddUTXOsWithAddress(address string, utxos []transaction.
 		}
 		//把序列化后的utxos的数据存到bolt.DB中
 		err = bucket.Put([]byte(address), utxosBytes)
-		return err
+		if err != nil {
+			return err
+		}
+
+		//记录每个utxo所属的地址，花费时不需要依赖交易输入中的公钥即可找到utxo
+		indexBucket, err := tx.CreateBucketIfNotExists([]byte(UTXOINDEX))
+		if err != nil {
+			return err
+		}
+		for _, utxo := range utxos {
+			if utxo.IsUnspendable() {
+				continue
+			}
+			err = indexBucket.Put(outpointKey(utxo.TxId, utxo.Vout), []byte(address))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	return err == nil, err
 }
 
+/**
+ *删除utxo集合和地址索引中的全部数据，再保存给定的utxo，用于旧版本数据库的迁移
+ */
+func (utxoset *UTXOSet) Rebuild(utxos map[string][]transaction.UTXO) error {
+	err := utxoset.Engine.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{UTXOSET, UTXOINDEX} {
+			if tx.Bucket([]byte(name)) == nil {
+				continue
+			}
+			if err := tx.DeleteBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for address, addressUTXOs := range utxos {
+		if _, err = utxoset.AddUTXOsWithAddress(address, addressUTXOs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 /**
  *把某个地址消费了的某些utxo删除掉
  */
@@ -114,7 +164,7 @@ func (utxoset *UTXOSet) DeleteUTXOsWithAddress(address string, records []SpendRe
 	engine := utxoset.Engine
 	var err error
 
-	engine.Update(func(tx *bolt.Tx) error {
+	err = engine.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(UTXOSET))
 		if bucket == nil {
 			return nil
@@ -160,6 +210,14 @@ func (utxoset *UTXOSet) DeleteUTXOsWithAddress(address string, records []SpendRe
 		}
 		//把剩余的utxos保存到utxoset桶中
 		bucket.Put([]byte(address), remainBytes)
+
+		//删除已花费utxo的地址索引
+		indexBucket := tx.Bucket([]byte(UTXOINDEX))
+		if indexBucket != nil {
+			for _, record := range records {
+				indexBucket.Delete(outpointKey(record.TxId, record.Vout))
+			}
+		}
 		return nil
 	})
 	return err == nil, err
@@ -173,9 +231,9 @@ func (utxoset *UTXOSet) GetUTXOsBySpendRecords(address string, records []SpendRe
 	spentUTXOs := make([]transaction.UTXO, 0)
 
 	engine := utxoset.Engine
-	engine.View(func(tx *bolt.Tx) error {
+	err = engine.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(UTXOSET))
-		if bucket != nil {
+		if bucket == nil {
 			return nil
 		}
 		//桶存在，可以尝试获取utxo数据
@@ -207,6 +265,32 @@ func (utxoset *UTXOSet) GetUTXOsBySpendRecords(address string, records []SpendRe
 	return spentUTXOs ,err
 }
 
+/**
+ *根据消费记录查询所花费的utxo所属的地址，utxoset中不存在该utxo时返回空字符串
+ */
+func (utxoset *UTXOSet) GetAddressBySpendRecord(record SpendRecord) (string, error) {
+	var address string
+	err := utxoset.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(UTXOINDEX))
+		if bucket == nil {
+			return nil
+		}
+		address = string(bucket.Get(outpointKey(record.TxId, record.Vout)))
+		return nil
+	})
+	return address, err
+}
+
+/**
+ *utxo地址索引的键：txid(32) + vout(8，大端序)
+ */
+func outpointKey(txid [32]byte, vout int) []byte {
+	key := make([]byte, 40)
+	copy(key, txid[:])
+	binary.BigEndian.PutUint64(key[32:], uint64(vout))
+	return key
+}
+
 /**
  *用于判断消费记录records是否是现存utxo的子集，如果是，返回true，不是子集，返回false
  */
@@ -0,0 +1,162 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"encoding/hex"
+	"errors"
+	"github.com/boltdb/bolt"
+	"sort"
+	"time"
+)
+
+const ADDRESSBOOK = "address_book" //地址簿的键名
+const MAXLABELLEN = 64             //地址标签的最大长度
+
+const (
+	PURPOSERECEIVE = "receive" //钱包的收款地址
+	PURPOSECHANGE  = "change"  //钱包的找零地址
+	PURPOSESEND    = "send"    //钱包之外的付款对象地址，只记录标签
+)
+
+/**
+ *地址簿中记录的地址元数据
+ */
+type AddressMeta struct {
+	Label      string
+	Purpose    string //PURPOSERECEIVE、PURPOSECHANGE或PURPOSESEND
+	CreateTime int64  //地址加入钱包的时间（秒），该功能加入之前已经存在的地址为0
+}
+
+/**
+ *地址的详细信息
+ */
+type AddressInfo struct {
+	Address     string
+	AddressMeta        //地址簿中的标签、用途和创建时间
+	IsMine      bool   //钱包可以花费该地址的资金
+	IsWatchOnly bool   //观察地址
+	IsScript    bool   //P2SH地址或多重签名地址
+	IsChange    bool   //找零地址
+	HDPath      string //HD地址的派生路径，非HD地址为空
+	PubKey      string //普通地址的十六进制公钥
+	KeyType     byte   //普通地址的密钥类型
+	Encrypted   bool   //私钥是否已经加密
+}
+
+/**
+ *为地址设置标签，地址可以是钱包自己的地址，也可以是钱包之外的付款对象地址，标签为空时清除标签
+ */
+func (wallet *Wallet) SetLabel(address string, label string) error {
+	if !wallet.CheckAddress(address) {
+		return errors.New("地址不符合规范，请检查后重试")
+	}
+	if len(label) > MAXLABELLEN {
+		return errors.New("地址标签过长")
+	}
+	meta, ok := wallet.AddressBook[address]
+	if !ok {
+		meta = wallet.newAddressMeta(address)
+	}
+	meta.Label = label
+	wallet.AddressBook[address] = meta
+	return wallet.saveAddressBook()
+}
+
+/**
+ *获取地址的元数据，地址簿中没有记录的地址根据钱包的信息推断用途
+ */
+func (wallet *Wallet) GetAddressMeta(address string) AddressMeta {
+	if meta, ok := wallet.AddressBook[address]; ok {
+		return meta
+	}
+	meta := wallet.newAddressMeta(address)
+	meta.CreateTime = 0
+	return meta
+}
+
+/**
+ *获取使用指定标签的所有地址，按地址排序
+ */
+func (wallet *Wallet) GetAddressesByLabel(label string) []string {
+	addresses := make([]string, 0)
+	for address, meta := range wallet.AddressBook {
+		if meta.Label == label {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+/**
+ *获取地址的详细信息
+ */
+func (wallet *Wallet) GetAddressInfo(address string) (AddressInfo, error) {
+	if !wallet.CheckAddress(address) {
+		return AddressInfo{}, errors.New("地址不符合规范，请检查后重试")
+	}
+	_, isP2SH := wallet.GetRedeemScript(address)
+	info := AddressInfo{
+		Address:     address,
+		AddressMeta: wallet.GetAddressMeta(address),
+		IsMine:      wallet.IsMine(address),
+		IsWatchOnly: wallet.IsWatchOnly(address),
+		IsScript:    isP2SH || IsMultiSigAddress(address),
+		IsChange:    wallet.IsInternalAddress(address),
+	}
+	if keyPair := wallet.GetKeyPairByAddress(address); keyPair != nil {
+		if len(keyPair.HDPath) > 0 {
+			info.HDPath = FormatHDPath(keyPair.HDPath)
+		}
+		info.PubKey = hex.EncodeToString(keyPair.Pub)
+		info.KeyType = keyPair.KeyType
+		info.Encrypted = len(keyPair.CryptedPriv) > 0
+	}
+	return info, nil
+}
+
+/**
+ *地址加入钱包时记录元数据，地址簿中已经存在的地址保留原有的标签和创建时间
+ */
+func (wallet *Wallet) addAddressMeta(address string, purpose string) error {
+	meta, ok := wallet.AddressBook[address]
+	if ok && meta.Purpose == purpose {
+		return nil
+	}
+	if !ok {
+		meta.CreateTime = time.Now().Unix()
+	}
+	meta.Purpose = purpose
+	wallet.AddressBook[address] = meta
+	return wallet.saveAddressBook()
+}
+
+/**
+ *为地址簿中还没有记录的地址生成元数据
+ */
+func (wallet *Wallet) newAddressMeta(address string) AddressMeta {
+	meta := AddressMeta{Purpose: PURPOSESEND, CreateTime: time.Now().Unix()}
+	if wallet.IsInternalAddress(address) {
+		meta.Purpose = PURPOSECHANGE
+	} else if wallet.IsMine(address) || wallet.IsWatchOnly(address) {
+		meta.Purpose = PURPOSERECEIVE
+	}
+	return meta
+}
+
+/**
+ *把地址簿保存到keystore桶中
+ */
+func (wallet *Wallet) saveAddressBook() error {
+	addressBookBytes, err := utils.Encoder(wallet.AddressBook)
+	if err != nil {
+		return err
+	}
+	return wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(ADDRESSBOOK), addressBookBytes)
+	})
+}
@@ -0,0 +1,344 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/boltdb/bolt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const MASTERKEY = "master_key" //加密钱包的主密钥记录的键名
+const MASTERKEYLEN = 32        //主密钥的字节数，用于AES-256-GCM加密私钥
+const SALTLEN = 16             //由密码派生密钥时所使用的盐的字节数
+const SCRYPTN = 1 << 15        //scrypt的CPU和内存开销参数
+const SCRYPTR = 8              //scrypt的块大小参数
+const SCRYPTP = 1              //scrypt的并行度参数
+
+/**
+ *加密钱包的主密钥记录：私钥使用随机生成的主密钥加密，主密钥再使用由密码派生的密钥加密，
+ *修改密码时只需要重新加密主密钥
+ */
+type MasterKey struct {
+	Salt       []byte //派生密钥所使用的盐
+	N          int    //scrypt参数
+	R          int
+	P          int
+	CryptedKey []byte //nonce + 使用派生密钥加密后的主密钥
+}
+
+/**
+ *主密钥记录的编码：盐 + scrypt参数 + 加密后的主密钥
+ */
+func (masterKey *MasterKey) Serialize() []byte {
+	buff := new(bytes.Buffer)
+	utils.WriteVarBytes(buff, masterKey.Salt)
+	utils.WriteInt64(buff, int64(masterKey.N))
+	utils.WriteInt64(buff, int64(masterKey.R))
+	utils.WriteInt64(buff, int64(masterKey.P))
+	utils.WriteVarBytes(buff, masterKey.CryptedKey)
+	return buff.Bytes()
+}
+
+/**
+ *主密钥记录的解码
+ */
+func DeserializeMasterKey(data []byte) (*MasterKey, error) {
+	reader := bytes.NewReader(data)
+	masterKey := &MasterKey{}
+	var err error
+	if masterKey.Salt, err = utils.ReadVarBytes(reader); err != nil {
+		return nil, err
+	}
+	params := make([]int64, 3)
+	for i := range params {
+		if params[i], err = utils.ReadInt64(reader); err != nil {
+			return nil, err
+		}
+	}
+	masterKey.N, masterKey.R, masterKey.P = int(params[0]), int(params[1]), int(params[2])
+	if masterKey.CryptedKey, err = utils.ReadVarBytes(reader); err != nil {
+		return nil, err
+	}
+	return masterKey, nil
+}
+
+/**
+ *判断钱包是否已经加密
+ */
+func (wallet *Wallet) IsEncrypted() bool {
+	return wallet.MasterKey != nil
+}
+
+/**
+ *判断钱包是否处于锁定状态：已加密且尚未使用密码解锁
+ */
+func (wallet *Wallet) IsLocked() bool {
+	return wallet.IsEncrypted() && wallet.unlockedKey == nil
+}
+
+/**
+ *检查钱包是否可以使用私钥，钱包被锁定时返回错误
+ */
+func (wallet *Wallet) CheckUnlocked() error {
+	if wallet.IsLocked() {
+		return errors.New("钱包已锁定，请通过XFWALLETPASSPHRASE环境变量或-stdinpassphrase参数提供钱包密码")
+	}
+	return nil
+}
+
+/**
+ *使用密码加密钱包中的所有私钥，加密完成后钱包处于锁定状态
+ */
+func (wallet *Wallet) EncryptWallet(passphrase string) error {
+	if wallet.IsEncrypted() {
+		return errors.New("钱包已经加密，修改密码请使用walletpassphrasechange命令")
+	}
+	if len(passphrase) == 0 {
+		return errors.New("密码不能为空")
+	}
+	key := make([]byte, MASTERKEYLEN)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	masterKey, err := newMasterKey(passphrase, key)
+	if err != nil {
+		return err
+	}
+	for _, keyPair := range wallet.Address {
+		if err = keyPair.encrypt(key); err != nil {
+			return err
+		}
+	}
+	if wallet.HDChain != nil {
+		if err = wallet.HDChain.encrypt(key); err != nil {
+			return err
+		}
+	}
+	wallet.MasterKey = masterKey
+	if err = wallet.saveMasterKey(); err != nil {
+		return err
+	}
+	if wallet.HDChain != nil {
+		if err = wallet.saveHDChain(); err != nil {
+			return err
+		}
+	}
+	wallet.SaveAddAndKeyPairs2DB()
+	return wallet.Lock()
+}
+
+/**
+ *使用密码解锁钱包：解密后的私钥和主密钥只保存在当前进程的内存中，不写入数据库或任何文件，
+ *每条命令都在独立的进程中运行，命令结束后钱包即恢复锁定状态
+ */
+func (wallet *Wallet) Unlock(passphrase string) error {
+	if !wallet.IsEncrypted() {
+		return errors.New("钱包未加密，无需解锁")
+	}
+	key, err := wallet.MasterKey.decrypt(passphrase)
+	if err != nil {
+		return err
+	}
+	return wallet.unlockWithKey(key)
+}
+
+/**
+ *锁定钱包：清除内存中的私钥和HD种子
+ */
+func (wallet *Wallet) Lock() error {
+	if !wallet.IsEncrypted() {
+		return errors.New("钱包未加密，无法锁定")
+	}
+	for _, keyPair := range wallet.Address {
+		keyPair.Priv = nil
+	}
+	if wallet.HDChain != nil {
+		wallet.HDChain.Mnemonic = ""
+		wallet.HDChain.Seed = nil
+	}
+	wallet.unlockedKey = nil
+	return nil
+}
+
+/**
+ *修改钱包的密码，只重新加密主密钥，私钥的密文保持不变
+ */
+func (wallet *Wallet) ChangePassphrase(oldPassphrase string, newPassphrase string) error {
+	if !wallet.IsEncrypted() {
+		return errors.New("钱包未加密，请先使用encryptwallet命令加密钱包")
+	}
+	if len(newPassphrase) == 0 {
+		return errors.New("密码不能为空")
+	}
+	key, err := wallet.MasterKey.decrypt(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	if err = wallet.Lock(); err != nil {
+		return err
+	}
+	masterKey, err := newMasterKey(newPassphrase, key)
+	if err != nil {
+		return err
+	}
+	wallet.MasterKey = masterKey
+	return wallet.saveMasterKey()
+}
+
+/**
+ *使用主密钥解密钱包中的所有私钥和HD种子
+ */
+func (wallet *Wallet) unlockWithKey(key []byte) error {
+	for _, keyPair := range wallet.Address {
+		if err := keyPair.decrypt(key); err != nil {
+			return err
+		}
+	}
+	if wallet.HDChain != nil {
+		if err := wallet.HDChain.decrypt(key); err != nil {
+			return err
+		}
+	}
+	wallet.unlockedKey = key
+	return nil
+}
+
+/**
+ *把主密钥记录保存到keystore桶中
+ */
+func (wallet *Wallet) saveMasterKey() error {
+	return wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(MASTERKEY), wallet.MasterKey.Serialize())
+	})
+}
+
+/**
+ *使用由密码派生的密钥加密主密钥，生成新的主密钥记录
+ */
+func newMasterKey(passphrase string, key []byte) (*MasterKey, error) {
+	masterKey := &MasterKey{Salt: make([]byte, SALTLEN), N: SCRYPTN, R: SCRYPTR, P: SCRYPTP}
+	if _, err := rand.Read(masterKey.Salt); err != nil {
+		return nil, err
+	}
+	derived, err := masterKey.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	masterKey.CryptedKey, err = sealAESGCM(derived, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return masterKey, nil
+}
+
+/**
+ *使用密码解密主密钥，密码错误时返回错误
+ */
+func (masterKey *MasterKey) decrypt(passphrase string) ([]byte, error) {
+	derived, err := masterKey.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := openAESGCM(derived, masterKey.CryptedKey, nil)
+	if err != nil || len(key) != MASTERKEYLEN {
+		return nil, errors.New("钱包密码不正确")
+	}
+	return key, nil
+}
+
+/**
+ *使用scrypt由密码派生出加密主密钥的密钥
+ */
+func (masterKey *MasterKey) deriveKey(passphrase string) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), masterKey.Salt, masterKey.N, masterKey.R, masterKey.P, MASTERKEYLEN)
+}
+
+/**
+ *使用主密钥加密秘钥对的私钥，公钥作为附加数据参与认证，加密后清除明文私钥
+ */
+func (keyPair *KeyPair) encrypt(key []byte) error {
+	if keyPair.Priv == nil {
+		return errors.New("秘钥对缺少私钥，无法加密")
+	}
+	crypted, err := sealAESGCM(key, keyPair.Priv.D.Bytes(), keyPair.Pub)
+	if err != nil {
+		return err
+	}
+	keyPair.CryptedPriv = crypted
+	keyPair.Priv = nil
+	return nil
+}
+
+/**
+ *使用主密钥解密秘钥对的私钥，并检查私钥与公钥是否匹配
+ */
+func (keyPair *KeyPair) decrypt(key []byte) error {
+	if keyPair.CryptedPriv == nil {
+		return nil
+	}
+	d, err := openAESGCM(key, keyPair.CryptedPriv, keyPair.Pub)
+	if err != nil {
+		return errors.New("私钥解密失败")
+	}
+	curve, err := GetCurve(keyPair.KeyType)
+	if err != nil {
+		return err
+	}
+	priv, err := curve.PrivKeyFromBytes(d)
+	if err != nil {
+		return err
+	}
+	//旧版本钱包的P256公钥为非压缩格式
+	if !bytes.Equal(curve.MarshalPubKey(&priv.PublicKey), keyPair.Pub) &&
+		!bytes.Equal(elliptic.Marshal(priv.Curve, priv.X, priv.Y), keyPair.Pub) {
+		return errors.New("解密得到的私钥与公钥不匹配")
+	}
+	keyPair.Priv = priv
+	return nil
+}
+
+/**
+ *AES-256-GCM加密，返回nonce + 密文
+ */
+func sealAESGCM(key []byte, plaintext []byte, additional []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+/**
+ *AES-256-GCM解密，data为nonce + 密文
+ */
+func openAESGCM(key []byte, data []byte, additional []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("密文长度不正确")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additional)
+}
@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"testing"
+)
+
+/**
+ *加密后钱包处于锁定状态，重新打开的钱包需要密码解锁才能使用私钥，解锁后的私钥与加密前一致
+ */
+func TestEncryptWallet(t *testing.T) {
+	wallet := newTestWallet(t, "crypter")
+	if err := wallet.SetHDSeed(testMnemonic); err != nil {
+		t.Fatal(err)
+	}
+	address, err := wallet.NewAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv := wallet.GetKeyPairByAddress(address).Priv.D
+	if err = wallet.Lock(); err == nil {
+		t.Fatal("未加密的钱包不应能被锁定")
+	}
+	if err = wallet.EncryptWallet(""); err == nil {
+		t.Fatal("空密码应当被拒绝")
+	}
+	if err = wallet.EncryptWallet("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if !wallet.IsEncrypted() || !wallet.IsLocked() || wallet.CheckUnlocked() == nil {
+		t.Fatal("加密后的钱包应当处于锁定状态")
+	}
+	if err = wallet.EncryptWallet("pw"); err == nil {
+		t.Fatal("重复加密钱包应当返回错误")
+	}
+
+	reopened, err := OpenWallet(wallet.Engine, wallet.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPair := reopened.GetKeyPairByAddress(address)
+	if !reopened.IsLocked() || keyPair.Priv != nil || reopened.HDChain.Seed != nil || reopened.HDChain.Mnemonic != "" {
+		t.Fatal("数据库中不应保存明文的私钥和HD种子")
+	}
+	if _, err = keyPair.Sign(utils.Hash256([]byte("locked"))); err == nil {
+		t.Fatal("锁定的钱包不应能签名")
+	}
+	if err = reopened.Unlock("wrong"); err == nil {
+		t.Fatal("错误的密码应当被拒绝")
+	}
+	if err = reopened.Unlock("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if reopened.IsLocked() || keyPair.Priv == nil || keyPair.Priv.D.Cmp(priv) != 0 || reopened.HDChain.Mnemonic != testMnemonic {
+		t.Fatal("解锁后的私钥或助记词与加密前不一致")
+	}
+	if _, err = keyPair.Sign(utils.Hash256([]byte("unlocked"))); err != nil {
+		t.Fatal(err)
+	}
+	if err = reopened.Lock(); err != nil || !reopened.IsLocked() || keyPair.Priv != nil {
+		t.Fatal("锁定后应当清除内存中的私钥", err)
+	}
+}
+
+/**
+ *修改密码后旧密码失效，私钥仍然可以使用新密码解锁
+ */
+func TestChangePassphrase(t *testing.T) {
+	wallet := newTestWallet(t, "crypter")
+	address, err := wallet.NewAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv := wallet.GetKeyPairByAddress(address).Priv.D
+	if err = wallet.ChangePassphrase("", "new"); err == nil {
+		t.Fatal("未加密的钱包不应能修改密码")
+	}
+	if err = wallet.EncryptWallet("old"); err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet.ChangePassphrase("wrong", "new"); err == nil {
+		t.Fatal("旧密码错误时应当返回错误")
+	}
+	if err = wallet.ChangePassphrase("old", ""); err == nil {
+		t.Fatal("新密码不能为空")
+	}
+	if err = wallet.ChangePassphrase("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenWallet(wallet.Engine, wallet.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = reopened.Unlock("old"); err == nil {
+		t.Fatal("修改密码后旧密码应当失效")
+	}
+	if err = reopened.Unlock("new"); err != nil {
+		t.Fatal(err)
+	}
+	if reopened.GetKeyPairByAddress(address).Priv.D.Cmp(priv) != 0 {
+		t.Fatal("修改密码后私钥应当保持不变")
+	}
+}
+
+func TestMasterKeyRoundTrip(t *testing.T) {
+	masterKey, err := newMasterKey("pw", make([]byte, MASTERKEYLEN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DeserializeMasterKey(masterKey.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decoded.decrypt("pw")
+	if err != nil || len(key) != MASTERKEYLEN {
+		t.Fatal("解码后的主密钥记录无法解密", err)
+	}
+	if _, err = DeserializeMasterKey(masterKey.Serialize()[:10]); err == nil {
+		t.Fatal("被截断的主密钥记录应当被拒绝")
+	}
+}
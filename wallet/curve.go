@@ -0,0 +1,236 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	btcecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"math/big"
+)
+
+/**
+ *密钥类型，用于区分公钥所使用的曲线
+ *P256的公钥使用标准的SEC格式（首字节为0x02、0x03或0x04），不携带类型前缀，因此旧地址保持不变
+ *其他曲线的公钥在SEC格式前面加上一个字节的密钥类型前缀，以便验签时识别曲线
+ */
+const (
+	KEYTYPE_P256      = 0x00
+	KEYTYPE_SECP256K1 = 0x10
+	KEYTYPE_SCHNORR   = 0x11
+)
+
+/**
+ *曲线的接口标准，封装了不同曲线的密钥生成、公钥编解码以及签名和验签
+ */
+type Curve interface {
+	KeyType() byte
+	Name() string
+	GenerateKey() (*ecdsa.PrivateKey, error)
+	PrivKeyFromBytes(d []byte) (*ecdsa.PrivateKey, error)
+	MarshalPubKey(pub *ecdsa.PublicKey) []byte
+	ParsePubKey(data []byte) (*ecdsa.PublicKey, error)
+	Sign(priv *ecdsa.PrivateKey, hash []byte) ([]byte, error)
+	Verify(pubk []byte, hash []byte, sig []byte) error
+}
+
+/**
+ *根据密钥类型获取对应的曲线
+ */
+func GetCurve(keyType byte) (Curve, error) {
+	switch keyType {
+	case KEYTYPE_P256:
+		return P256Curve{}, nil
+	case KEYTYPE_SECP256K1:
+		return Secp256k1Curve{}, nil
+	case KEYTYPE_SCHNORR:
+		return SchnorrCurve{}, nil
+	}
+	return nil, errors.New("不支持的密钥类型")
+}
+
+/**
+ *根据曲线名称获取对应的曲线，供命令行参数使用
+ */
+func GetCurveByName(name string) (Curve, error) {
+	for _, curve := range []Curve{P256Curve{}, Secp256k1Curve{}, SchnorrCurve{}} {
+		if curve.Name() == name {
+			return curve, nil
+		}
+	}
+	return nil, errors.New("不支持的曲线：" + name)
+}
+
+/**
+ *根据公钥数据的首字节判断公钥所使用的曲线
+ */
+func GetCurveByPubKey(pubk []byte) (Curve, error) {
+	if len(pubk) == 0 {
+		return nil, errors.New("公钥数据为空")
+	}
+	switch pubk[0] {
+	case 0x02, 0x03, 0x04:
+		return P256Curve{}, nil
+	}
+	return GetCurve(pubk[0])
+}
+
+/**
+ *使用公钥所对应的曲线验证签名
+ */
+func VerifySignature(pubk []byte, hash []byte, sig []byte) error {
+	curve, err := GetCurveByPubKey(pubk)
+	if err != nil {
+		return err
+	}
+	return curve.Verify(pubk, hash, sig)
+}
+
+/**
+ *P256曲线，使用go标准库实现
+ */
+type P256Curve struct{}
+
+func (P256Curve) KeyType() byte {
+	return KEYTYPE_P256
+}
+
+func (P256Curve) Name() string {
+	return "p256"
+}
+
+func (P256Curve) GenerateKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func (P256Curve) PrivKeyFromBytes(d []byte) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() == 0 || k.Cmp(curve.Params().N) >= 0 {
+		return nil, errors.New("私钥数据不正确")
+	}
+	priv := &ecdsa.PrivateKey{D: k}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(k.FillBytes(make([]byte, 32)))
+	return priv, nil
+}
+
+func (P256Curve) MarshalPubKey(pub *ecdsa.PublicKey) []byte {
+	return elliptic.MarshalCompressed(elliptic.P256(), pub.X, pub.Y)
+}
+
+func (P256Curve) ParsePubKey(data []byte) (*ecdsa.PublicKey, error) {
+	return ParsePublicKey(elliptic.P256(), data)
+}
+
+func (P256Curve) Sign(priv *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, priv, hash)
+	if err != nil {
+		return nil, err
+	}
+	//统一使用low-S形式的签名，避免同一签名存在两种合法的编码
+	s = NormalizeLowS(priv.Curve, s)
+	return EncodeSignatureDER(r, s), nil
+}
+
+func (curve P256Curve) Verify(pubk []byte, hash []byte, sig []byte) error {
+	pub, err := curve.ParsePubKey(pubk)
+	if err != nil {
+		return err
+	}
+	r, s, err := ParseSignature(sig)
+	if err != nil {
+		return err
+	}
+	//拒绝high-S形式的签名，防止签名被重新编码
+	if !IsLowS(pub.Curve, s) {
+		return errors.New("签名不符合low-S规范")
+	}
+	if !ecdsa.Verify(pub, hash, r, s) {
+		return errors.New("验签失败")
+	}
+	return nil
+}
+
+/**
+ *secp256k1曲线，与比特币以及常见的硬件签名设备所使用的曲线一致
+ *公钥格式：KEYTYPE_SECP256K1 + 33字节的压缩公钥，签名只接受严格的DER格式
+ */
+type Secp256k1Curve struct{}
+
+func (Secp256k1Curve) KeyType() byte {
+	return KEYTYPE_SECP256K1
+}
+
+func (Secp256k1Curve) Name() string {
+	return "secp256k1"
+}
+
+func (curve Secp256k1Curve) GenerateKey() (*ecdsa.PrivateKey, error) {
+	priv, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return priv.ToECDSA(), nil
+}
+
+func (Secp256k1Curve) PrivKeyFromBytes(d []byte) (*ecdsa.PrivateKey, error) {
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() == 0 || k.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("私钥数据不正确")
+	}
+	priv, _ := btcec.PrivKeyFromBytes(k.FillBytes(make([]byte, 32)))
+	return priv.ToECDSA(), nil
+}
+
+func (curve Secp256k1Curve) MarshalPubKey(pub *ecdsa.PublicKey) []byte {
+	var x, y btcec.FieldVal
+	x.SetByteSlice(pub.X.FillBytes(make([]byte, 32)))
+	y.SetByteSlice(pub.Y.FillBytes(make([]byte, 32)))
+	compressed := btcec.NewPublicKey(&x, &y).SerializeCompressed()
+	return append([]byte{curve.KeyType()}, compressed...)
+}
+
+func (curve Secp256k1Curve) ParsePubKey(data []byte) (*ecdsa.PublicKey, error) {
+	pub, err := curve.parseBtcecPubKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return pub.ToECDSA(), nil
+}
+
+func (curve Secp256k1Curve) parseBtcecPubKey(data []byte) (*btcec.PublicKey, error) {
+	if len(data) != 1+btcec.PubKeyBytesLenCompressed || data[0] != curve.KeyType() {
+		return nil, errors.New("secp256k1公钥格式不正确")
+	}
+	return btcec.ParsePubKey(data[1:])
+}
+
+func (Secp256k1Curve) Sign(priv *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	key, _ := btcec.PrivKeyFromBytes(priv.D.FillBytes(make([]byte, 32)))
+	//btcec生成的签名为RFC6979确定性签名，并且已经是low-S形式的DER编码
+	return btcecdsa.Sign(key, hash).Serialize(), nil
+}
+
+func (curve Secp256k1Curve) Verify(pubk []byte, hash []byte, sig []byte) error {
+	pub, err := curve.parseBtcecPubKey(pubk)
+	if err != nil {
+		return err
+	}
+	r, s, err := ParseSignatureDER(sig)
+	if err != nil {
+		return err
+	}
+	if !IsLowS(btcec.S256(), s) {
+		return errors.New("签名不符合low-S规范")
+	}
+	var rScalar, sScalar btcec.ModNScalar
+	if rScalar.SetByteSlice(r.Bytes()) || sScalar.SetByteSlice(s.Bytes()) {
+		return errors.New("签名数据超出曲线的阶")
+	}
+	if !btcecdsa.NewSignature(&rScalar, &sScalar).Verify(hash, pub) {
+		return errors.New("验签失败")
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"github.com/boltdb/bolt"
+	"github.com/tyler-smith/go-bip39"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const HDCHAIN = "hd_chain"        //HD钱包种子记录的键名
+const HDPURPOSE = 44              //ECDSA秘钥派生路径的第一层，参考BIP44
+const HDPURPOSESCHNORR = 86       //schnorr秘钥派生路径的第一层，参考BIP86
+const HDCOINTYPE = 1              //派生路径的第二层：币种编号，本链没有注册SLIP-44编号，使用测试网络共用的编号1
+const HARDENEDOFFSET = 0x80000000 //强化派生的索引偏移
+const EXTERNALCHAIN = 0           //收款地址所在的链
+const INTERNALCHAIN = 1           //找零地址所在的链
+const HDGAPLIMIT = 20             //恢复钱包时，连续多少个未使用的地址之后停止派生
+const MNEMONICENTROPY = 128       //助记词的熵的位数，对应12个单词
+
+/**
+ *HD钱包的种子记录：所有HD地址的秘钥都由种子派生，备份助记词即可恢复全部地址
+ *钱包加密后助记词和种子只保存密文，锁定时明文为空
+ */
+type HDChain struct {
+	Mnemonic        string
+	Seed            []byte
+	CryptedMnemonic []byte
+	CryptedSeed     []byte
+}
+
+/**
+ *种子记录的编码：助记词 + 种子 + 助记词密文 + 种子密文，加密钱包不保存明文
+ */
+func (hdChain *HDChain) Serialize() []byte {
+	buff := new(bytes.Buffer)
+	if hdChain.CryptedSeed != nil {
+		utils.WriteVarBytes(buff, nil)
+		utils.WriteVarBytes(buff, nil)
+	} else {
+		utils.WriteVarBytes(buff, []byte(hdChain.Mnemonic))
+		utils.WriteVarBytes(buff, hdChain.Seed)
+	}
+	utils.WriteVarBytes(buff, hdChain.CryptedMnemonic)
+	utils.WriteVarBytes(buff, hdChain.CryptedSeed)
+	return buff.Bytes()
+}
+
+/**
+ *种子记录的解码
+ */
+func DeserializeHDChain(data []byte) (*HDChain, error) {
+	reader := bytes.NewReader(data)
+	fields := make([][]byte, 4)
+	for i := range fields {
+		field, err := utils.ReadVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(field) > 0 {
+			fields[i] = field
+		}
+	}
+	return &HDChain{
+		Mnemonic:        string(fields[0]),
+		Seed:            fields[1],
+		CryptedMnemonic: fields[2],
+		CryptedSeed:     fields[3],
+	}, nil
+}
+
+/**
+ *使用主密钥加密助记词和种子，并清除明文
+ */
+func (hdChain *HDChain) encrypt(key []byte) error {
+	var err error
+	if hdChain.CryptedMnemonic, err = sealAESGCM(key, []byte(hdChain.Mnemonic), []byte(HDCHAIN)); err != nil {
+		return err
+	}
+	if hdChain.CryptedSeed, err = sealAESGCM(key, hdChain.Seed, []byte(HDCHAIN)); err != nil {
+		return err
+	}
+	hdChain.Mnemonic = ""
+	hdChain.Seed = nil
+	return nil
+}
+
+/**
+ *使用主密钥解密助记词和种子
+ */
+func (hdChain *HDChain) decrypt(key []byte) error {
+	if hdChain.CryptedSeed == nil {
+		return nil
+	}
+	mnemonic, err := openAESGCM(key, hdChain.CryptedMnemonic, []byte(HDCHAIN))
+	if err != nil {
+		return errors.New("HD种子解密失败")
+	}
+	seed, err := openAESGCM(key, hdChain.CryptedSeed, []byte(HDCHAIN))
+	if err != nil {
+		return errors.New("HD种子解密失败")
+	}
+	hdChain.Mnemonic = string(mnemonic)
+	hdChain.Seed = seed
+	return nil
+}
+
+/**
+ *生成一组新的助记词
+ */
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(MNEMONICENTROPY)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+/**
+ *使用助记词设置钱包的HD种子，钱包已经设置了HD种子时返回错误
+ */
+func (wallet *Wallet) SetHDSeed(mnemonic string) error {
+	if wallet.HDChain != nil {
+		return errors.New("当前钱包已经设置了HD种子，可以恢复到新的钱包中，或者确认后替换当前的HD种子")
+	}
+	return wallet.setHDSeed(mnemonic)
+}
+
+/**
+ *使用助记词替换钱包的HD种子，原种子派生的地址及其私钥仍然保留在钱包中，之后的新地址由新种子派生
+ */
+func (wallet *Wallet) ReplaceHDSeed(mnemonic string) error {
+	if wallet.HDChain == nil {
+		return errors.New("当前钱包还没有HD种子")
+	}
+	return wallet.setHDSeed(mnemonic)
+}
+
+/**
+ *校验助记词的单词和校验位
+ */
+func IsMnemonicValid(mnemonic string) bool {
+	return bip39.IsMnemonicValid(strings.Join(strings.Fields(mnemonic), " "))
+}
+
+func (wallet *Wallet) setHDSeed(mnemonic string) error {
+	if err := wallet.CheckUnlocked(); err != nil {
+		return err
+	}
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return errors.New("助记词不正确，请检查后重试")
+	}
+	hdChain := &HDChain{Mnemonic: mnemonic, Seed: seed}
+	if wallet.IsEncrypted() {
+		if err = hdChain.encrypt(wallet.unlockedKey); err != nil {
+			return err
+		}
+		hdChain.Mnemonic, hdChain.Seed = mnemonic, seed
+	}
+	wallet.HDChain = hdChain
+	return wallet.saveHDChain()
+}
+
+/**
+ *导出钱包的助记词，用于备份钱包
+ */
+func (wallet *Wallet) DumpMnemonic() (string, error) {
+	if wallet.HDChain == nil {
+		return "", errors.New("当前钱包还没有HD种子，生成新地址时会自动创建")
+	}
+	if err := wallet.CheckUnlocked(); err != nil {
+		return "", err
+	}
+	return wallet.HDChain.Mnemonic, nil
+}
+
+/**
+ *使用HD种子派生一个新地址，account为账户编号，chain为EXTERNALCHAIN或INTERNALCHAIN
+ *钱包还没有HD种子时自动生成新的助记词
+ */
+func (wallet *Wallet) NewHDAddress(curve Curve, account uint32, chain uint32) (string, error) {
+	address, keyPair, err := wallet.deriveNextHDAddress(curve, account, chain)
+	if err != nil {
+		return "", err
+	}
+	return address, wallet.AddKeyPair(address, keyPair)
+}
+
+/**
+ *为交易预留一个新的找零地址：派生找零链上的下一个地址，但暂不加入钱包，
+ *交易确实需要找零时再使用KeepChangeAddress保存，避免未使用的找零地址超出恢复时的间隔限制
+ */
+func (wallet *Wallet) ReserveChangeAddress(curve Curve) (string, *KeyPair, error) {
+	return wallet.deriveNextHDAddress(curve, 0, INTERNALCHAIN)
+}
+
+/**
+ *保存通过ReserveChangeAddress预留的找零地址
+ */
+func (wallet *Wallet) KeepChangeAddress(address string, keyPair *KeyPair) error {
+	if _, ok := wallet.Address[address]; ok {
+		return nil
+	}
+	return wallet.AddKeyPair(address, keyPair)
+}
+
+/**
+ *派生某条链上下一个未使用的地址，钱包还没有HD种子时自动生成新的助记词
+ */
+func (wallet *Wallet) deriveNextHDAddress(curve Curve, account uint32, chain uint32) (string, *KeyPair, error) {
+	if err := wallet.CheckUnlocked(); err != nil {
+		return "", nil, err
+	}
+	if wallet.HDChain == nil {
+		mnemonic, err := NewMnemonic()
+		if err != nil {
+			return "", nil, err
+		}
+		if err = wallet.SetHDSeed(mnemonic); err != nil {
+			return "", nil, err
+		}
+	}
+	index := wallet.nextHDIndex(curve, account, chain)
+	for {
+		keyPair, err := wallet.DeriveKeyPair(curve, HDPath(curve, account, chain, index))
+		if err == nil {
+			return wallet.GetAddressByPubk(keyPair.Pub), keyPair, nil
+		}
+		//极小概率派生出无效的私钥，按BIP32的规定跳过该索引
+		if index >= HARDENEDOFFSET-1 {
+			return "", nil, err
+		}
+		index++
+	}
+}
+
+/**
+ *把秘钥对加入钱包并持久化，加密钱包中的私钥使用主密钥加密后保存，同时在地址簿中记录地址的用途
+ */
+func (wallet *Wallet) AddKeyPair(address string, keyPair *KeyPair) error {
+	if wallet.IsEncrypted() {
+		priv := keyPair.Priv
+		if err := keyPair.encrypt(wallet.unlockedKey); err != nil {
+			return err
+		}
+		keyPair.Priv = priv
+	}
+	wallet.Address[address] = keyPair
+	wallet.SaveAddAndKeyPairs2DB()
+	purpose := PURPOSERECEIVE
+	if wallet.IsInternalAddress(address) {
+		purpose = PURPOSECHANGE
+	}
+	return wallet.addAddressMeta(address, purpose)
+}
+
+/**
+ *从HD种子恢复地址：按曲线、链依次派生，遇到连续HDGAPLIMIT个未使用的地址后停止，
+ *同时扫描旧版本使用密钥类型作为币种编号的派生路径，isUsed判断地址是否在链上出现过，返回恢复的地址
+ */
+func (wallet *Wallet) RestoreHDAddresses(isUsed func(address string) bool) ([]string, error) {
+	if wallet.HDChain == nil {
+		return nil, errors.New("当前钱包还没有HD种子")
+	}
+	if err := wallet.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+	restored := make([]string, 0)
+	for _, hdPath := range []func(Curve, uint32, uint32, uint32) []uint32{HDPath, legacyHDPath} {
+		for _, curve := range []Curve{P256Curve{}, Secp256k1Curve{}, SchnorrCurve{}} {
+			for _, chain := range []uint32{EXTERNALCHAIN, INTERNALCHAIN} {
+				pending := make(map[string]*KeyPair)
+				pendingOrder := make([]string, 0)
+				for index, gap := uint32(0), 0; gap < HDGAPLIMIT; index++ {
+					keyPair, err := wallet.DeriveKeyPair(curve, hdPath(curve, 0, chain, index))
+					if err != nil {
+						continue
+					}
+					address := wallet.GetAddressByPubk(keyPair.Pub)
+					pending[address] = keyPair
+					pendingOrder = append(pendingOrder, address)
+					if !isUsed(address) {
+						gap++
+						continue
+					}
+					//地址被使用过，该地址之前派生的地址全部加入钱包
+					gap = 0
+					for _, addr := range pendingOrder {
+						if _, ok := wallet.Address[addr]; !ok {
+							if err = wallet.AddKeyPair(addr, pending[addr]); err != nil {
+								return nil, err
+							}
+							restored = append(restored, addr)
+						}
+					}
+					pending = make(map[string]*KeyPair)
+					pendingOrder = pendingOrder[:0]
+				}
+			}
+		}
+	}
+	return restored, nil
+}
+
+/**
+ *判断地址是否为找零地址：地址簿中记录为找零用途，或者是HD钱包找零链上的地址
+ */
+func (wallet *Wallet) IsInternalAddress(address string) bool {
+	if wallet.AddressBook[address].Purpose == PURPOSECHANGE {
+		return true
+	}
+	keyPair := wallet.Address[address]
+	if keyPair == nil || len(keyPair.HDPath) != len(HDPath(P256Curve{}, 0, 0, 0)) {
+		return false
+	}
+	return keyPair.HDPath[len(keyPair.HDPath)-2] == INTERNALCHAIN
+}
+
+/**
+ *使用HD种子按派生路径派生秘钥对
+ */
+func (wallet *Wallet) DeriveKeyPair(curve Curve, path []uint32) (*KeyPair, error) {
+	if wallet.HDChain == nil || wallet.HDChain.Seed == nil {
+		return nil, errors.New("钱包已锁定或者还没有HD种子")
+	}
+	key, err := newMasterExtendedKey(curve, wallet.HDChain.Seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range path {
+		if key, err = key.child(curve, index); err != nil {
+			return nil, err
+		}
+	}
+	return &KeyPair{
+		KeyType: curve.KeyType(),
+		Priv:    key.priv,
+		Pub:     curve.MarshalPubKey(&key.priv.PublicKey),
+		HDPath:  path,
+	}, nil
+}
+
+/**
+ *计算某条链上下一个未使用的地址索引：钱包中该链已有地址的最大索引加1
+ */
+func (wallet *Wallet) nextHDIndex(curve Curve, account uint32, chain uint32) uint32 {
+	prefix := HDPath(curve, account, chain, 0)
+	prefix = prefix[:len(prefix)-1]
+	var next uint32
+	for _, keyPair := range wallet.Address {
+		path := keyPair.HDPath
+		if len(path) != len(prefix)+1 || !equalPathPrefix(path, prefix) {
+			continue
+		}
+		if path[len(prefix)] >= next {
+			next = path[len(prefix)] + 1
+		}
+	}
+	return next
+}
+
+/**
+ *把HD种子记录保存到keystore桶中
+ */
+func (wallet *Wallet) saveHDChain() error {
+	return wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(HDCHAIN), wallet.HDChain.Serialize())
+	})
+}
+
+/**
+ *生成地址的派生路径：m/purpose'/coinType'/account'/chain/index
+ *secp256k1和P256秘钥的purpose为44，schnorr秘钥的purpose为86，使同一个种子在secp256k1和schnorr下派生出不同的秘钥，
+ *P256秘钥使用SLIP-10的主私钥，与secp256k1的秘钥互不相同
+ */
+func HDPath(curve Curve, account uint32, chain uint32, index uint32) []uint32 {
+	purpose := uint32(HDPURPOSE)
+	if curve.KeyType() == KEYTYPE_SCHNORR {
+		purpose = HDPURPOSESCHNORR
+	}
+	return []uint32{
+		purpose + HARDENEDOFFSET,
+		HDCOINTYPE + HARDENEDOFFSET,
+		account + HARDENEDOFFSET,
+		chain,
+		index,
+	}
+}
+
+/**
+ *旧版本的派生路径：m/44'/keyType'/account'/chain/index，第二层使用密钥类型代替币种编号，只在恢复钱包时扫描
+ */
+func legacyHDPath(curve Curve, account uint32, chain uint32, index uint32) []uint32 {
+	return []uint32{
+		HDPURPOSE + HARDENEDOFFSET,
+		uint32(curve.KeyType()) + HARDENEDOFFSET,
+		account + HARDENEDOFFSET,
+		chain,
+		index,
+	}
+}
+
+/**
+ *把派生路径格式化为m/44'/0'/0'/0/0的形式，随机生成的秘钥没有派生路径，返回空字符串
+ */
+func FormatHDPath(path []uint32) string {
+	if len(path) == 0 {
+		return ""
+	}
+	parts := []string{"m"}
+	for _, index := range path {
+		if index >= HARDENEDOFFSET {
+			parts = append(parts, strconv.FormatUint(uint64(index-HARDENEDOFFSET), 10)+"'")
+		} else {
+			parts = append(parts, strconv.FormatUint(uint64(index), 10))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func equalPathPrefix(path []uint32, prefix []uint32) bool {
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ *扩展私钥：私钥 + 链码
+ */
+type extendedKey struct {
+	priv      *ecdsa.PrivateKey
+	chainCode []byte
+}
+
+/**
+ *由种子生成主扩展私钥，secp256k1使用BIP32的"Bitcoin seed"，P256使用SLIP-10的"Nist256p1 seed"
+ */
+func newMasterExtendedKey(curve Curve, seed []byte) (*extendedKey, error) {
+	hmacKey := "Bitcoin seed"
+	if curve.KeyType() == KEYTYPE_P256 {
+		hmacKey = "Nist256p1 seed"
+	}
+	mac := hmac.New(sha512.New, []byte(hmacKey))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	priv, err := curve.PrivKeyFromBytes(sum[:32])
+	if err != nil {
+		return nil, errors.New("种子无法生成有效的主私钥")
+	}
+	return &extendedKey{priv: priv, chainCode: sum[32:]}, nil
+}
+
+/**
+ *派生子扩展私钥，index不小于HARDENEDOFFSET时为强化派生
+ */
+func (key *extendedKey) child(curve Curve, index uint32) (*extendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HARDENEDOFFSET {
+		data = append(data, 0x00)
+		data = append(data, key.priv.D.FillBytes(make([]byte, 32))...)
+	} else {
+		data = append(data, elliptic.MarshalCompressed(key.priv.Curve, key.priv.X, key.priv.Y)...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+	mac := hmac.New(sha512.New, key.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := key.priv.Curve.Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errors.New("派生的私钥无效")
+	}
+	d := il.Add(il, key.priv.D)
+	d.Mod(d, n)
+	if d.Sign() == 0 {
+		return nil, errors.New("派生的私钥无效")
+	}
+	priv, err := curve.PrivKeyFromBytes(d.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	return &extendedKey{priv: priv, chainCode: sum[32:]}, nil
+}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+/**
+ *使用种子按派生路径派生私钥，返回十六进制的私钥
+ */
+func deriveTestKey(t *testing.T, curve Curve, seedHex string, path []uint32) string {
+	t.Helper()
+	seed, _ := hex.DecodeString(seedHex)
+	wallet := &Wallet{HDChain: &HDChain{Seed: seed}}
+	keyPair, err := wallet.DeriveKeyPair(curve, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(keyPair.Priv.D.FillBytes(make([]byte, 32)))
+}
+
+/**
+ *BIP32测试向量1（secp256k1）和SLIP-10测试向量1（P256）
+ */
+func TestBIP32Vectors(t *testing.T) {
+	const seed = "000102030405060708090a0b0c0d0e0f"
+	h := uint32(HARDENEDOFFSET)
+	cases := []struct {
+		curve Curve
+		path  []uint32
+		priv  string
+	}{
+		{Secp256k1Curve{}, []uint32{}, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{Secp256k1Curve{}, []uint32{h}, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{Secp256k1Curve{}, []uint32{h, 1}, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{Secp256k1Curve{}, []uint32{h, 1, h + 2}, "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{Secp256k1Curve{}, []uint32{h, 1, h + 2, 2}, "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{Secp256k1Curve{}, []uint32{h, 1, h + 2, 2, 1000000000}, "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+		{P256Curve{}, []uint32{}, "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+		{P256Curve{}, []uint32{h, 1, h + 2, 2, 1000000000}, "21c4f269ef0a5fd1badf47eeacebeeaa3de22eb8e5b0adcd0f27dd99d34d0119"},
+	}
+	for _, c := range cases {
+		if got := deriveTestKey(t, c.curve, seed, c.path); got != c.priv {
+			t.Errorf("%s %s派生的私钥为%s，应为%s", c.curve.Name(), FormatHDPath(c.path), got, c.priv)
+		}
+	}
+}
+
+/**
+ *BIP39测试向量：助记词生成的种子，以及无效助记词的校验
+ */
+func TestBIP39Vectors(t *testing.T) {
+	seed := bip39.NewSeed(testMnemonic, "TREZOR")
+	if hex.EncodeToString(seed) != "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04" {
+		t.Fatal("助记词生成的种子不正确")
+	}
+
+	wallet := newTestWallet(t, "bip39")
+	if err := wallet.SetHDSeed("  " + testMnemonic + " "); err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(wallet.HDChain.Seed) != "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4" {
+		t.Fatal("钱包使用的种子不正确")
+	}
+	if !IsMnemonicValid(testMnemonic) || IsMnemonicValid(testMnemonic[:len(testMnemonic)-5]+"abandon") {
+		t.Fatal("助记词的校验位检查不正确")
+	}
+}
+
+func TestHDPath(t *testing.T) {
+	cases := map[string][]uint32{
+		"m/44'/1'/0'/1/7":  HDPath(P256Curve{}, 0, INTERNALCHAIN, 7),
+		"m/44'/1'/2'/0/0":  HDPath(Secp256k1Curve{}, 2, EXTERNALCHAIN, 0),
+		"m/86'/1'/0'/0/3":  HDPath(SchnorrCurve{}, 0, EXTERNALCHAIN, 3),
+		"m/44'/17'/0'/0/3": legacyHDPath(SchnorrCurve{}, 0, EXTERNALCHAIN, 3),
+	}
+	for want, path := range cases {
+		if got := FormatHDPath(path); got != want {
+			t.Errorf("派生路径为%s，应为%s", got, want)
+		}
+	}
+}
+
+/**
+ *已有HD种子的钱包不能直接恢复，确认替换后新地址由新种子派生，原来的地址仍然保留
+ */
+func TestReplaceHDSeed(t *testing.T) {
+	wallet := newTestWallet(t, "replace")
+	oldAddress, err := wallet.NewHDAddress(Secp256k1Curve{}, 0, EXTERNALCHAIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet.SetHDSeed(testMnemonic); err == nil {
+		t.Fatal("已有HD种子的钱包不应能直接设置新的种子")
+	}
+	if err = wallet.ReplaceHDSeed("abandon"); err == nil {
+		t.Fatal("无效的助记词不应替换HD种子")
+	}
+	if err = wallet.ReplaceHDSeed(testMnemonic); err != nil {
+		t.Fatal(err)
+	}
+	if wallet.HDChain.Mnemonic != testMnemonic || wallet.GetKeyPairByAddress(oldAddress) == nil {
+		t.Fatal("替换HD种子后助记词不正确或原来的地址丢失")
+	}
+	keyPair, err := wallet.DeriveKeyPair(Secp256k1Curve{}, HDPath(Secp256k1Curve{}, 0, EXTERNALCHAIN, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := wallet.NewHDAddress(Secp256k1Curve{}, 0, EXTERNALCHAIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address == oldAddress || wallet.GetKeyPairByAddress(address).Priv.D.Cmp(keyPair.Priv.D) == 0 {
+		t.Fatal("替换HD种子后的新地址应当由新种子派生")
+	}
+}
+
+/**
+ *恢复钱包时找到新旧两种派生路径上使用过的地址，以及它们之前派生的地址
+ */
+func TestRestoreHDAddresses(t *testing.T) {
+	source := &Wallet{HDChain: &HDChain{Seed: bip39.NewSeed(testMnemonic, "")}}
+	used := make(map[string]bool)
+	for _, key := range []struct {
+		curve Curve
+		path  []uint32
+	}{
+		{Secp256k1Curve{}, HDPath(Secp256k1Curve{}, 0, EXTERNALCHAIN, 2)},
+		{SchnorrCurve{}, HDPath(SchnorrCurve{}, 0, INTERNALCHAIN, 0)},
+		{P256Curve{}, legacyHDPath(P256Curve{}, 0, EXTERNALCHAIN, 0)},
+	} {
+		keyPair, err := source.DeriveKeyPair(key.curve, key.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used[source.GetAddressByPubk(keyPair.Pub)] = true
+	}
+
+	wallet := newTestWallet(t, "restore")
+	if err := wallet.SetHDSeed(testMnemonic); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := wallet.RestoreHDAddresses(func(address string) bool {
+		return used[address]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//secp256k1收款链上的前3个地址、schnorr找零链上的第1个地址和旧路径上P256的第1个地址
+	if len(restored) != 5 {
+		t.Fatalf("恢复了%d个地址，应为5个", len(restored))
+	}
+	for address := range used {
+		if wallet.GetKeyPairByAddress(address) == nil {
+			t.Errorf("使用过的地址%s没有被恢复", address)
+		}
+	}
+}
+
+func TestKeyPairPathGob(t *testing.T) {
+	keyPair, err := NewKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPair.HDPath = []uint32{1, 2, HARDENEDOFFSET + 3}
+	data, err := keyPair.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded KeyPair
+	if err = decoded.GobDecode(data); err != nil || len(decoded.HDPath) != 3 || decoded.HDPath[2] != HARDENEDOFFSET+3 || decoded.Priv.D.Cmp(keyPair.Priv.D) != 0 {
+		t.Fatal("派生路径编码前后不一致", err)
+	}
+	keyPair.HDPath = nil
+	data, _ = keyPair.GobEncode()
+	decoded = KeyPair{}
+	if err = decoded.GobDecode(data); err != nil || decoded.HDPath != nil {
+		t.Fatal("随机秘钥对解码后不应有派生路径", err)
+	}
+}
@@ -1,9 +1,12 @@
 package wallet
 
 import (
+	"XianfengChain04/utils"
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"math/big"
 )
 
@@ -11,38 +14,182 @@ import (
  *地址所对应的秘钥对（私钥 + 公钥），封装在一个自定义的结构体中
  */
 type KeyPair struct {
-	Priv *ecdsa.PrivateKey
+	KeyType byte //密钥类型，标识秘钥对所使用的曲线
+	Priv *ecdsa.PrivateKey //加密钱包被锁定时为空
 	Pub []byte
+	CryptedPriv []byte //加密钱包中使用主密钥加密后的私钥，未加密时为空
+	HDPath []uint32 //由HD种子派生的秘钥的派生路径，随机生成的秘钥为空
 }
 
 /**
- *生成一对秘钥对
+ *生成一对P256曲线的秘钥对，公钥使用33字节的压缩格式
  */
 func NewKeyPair() (*KeyPair, error) {
-	curve := elliptic.P256()
-	pri, err := ecdsa.GenerateKey(curve, rand.Reader)
+	return NewKeyPairWithCurve(P256Curve{})
+}
+
+/**
+ *使用指定的曲线生成一对秘钥对
+ */
+func NewKeyPairWithCurve(curve Curve) (*KeyPair, error) {
+	pri, err := curve.GenerateKey()
 	if err != nil {
 		return nil, err
 	}
-	pub := elliptic.Marshal(curve, pri.X, pri.Y)
 	keyPair := KeyPair{
+		KeyType: curve.KeyType(),
 		Priv: pri,
-		Pub:  pub,
+		Pub:  curve.MarshalPubKey(&pri.PublicKey),
+	}
+	return &keyPair, nil
+}
+
+/**
+ *使用指定曲线的私钥标量恢复秘钥对，用于导入外部私钥
+ */
+func NewKeyPairFromPrivKey(curve Curve, d []byte) (*KeyPair, error) {
+	priv, err := curve.PrivKeyFromBytes(d)
+	if err != nil {
+		return nil, err
+	}
+	keyPair := KeyPair{
+		KeyType: curve.KeyType(),
+		Priv: priv,
+		Pub:  curve.MarshalPubKey(&priv.PublicKey),
 	}
 	return &keyPair, nil
 }
 
 /**
- *恢复公钥信息
+ *使用秘钥对的私钥对数据的哈希值进行签名，返回DER编码的签名数据
+ */
+func (keyPair *KeyPair) Sign(hash []byte) ([]byte, error) {
+	if keyPair.Priv == nil {
+		return nil, errors.New("钱包已锁定，请通过XFWALLETPASSPHRASE环境变量或-stdinpassphrase参数提供钱包密码")
+	}
+	curve, err := GetCurve(keyPair.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	return curve.Sign(keyPair.Priv, hash)
+}
+
+/**
+ *秘钥对的持久化编码：keyType(1) + 私钥D + 公钥，私钥和公钥均带4字节长度前缀
+ *ecdsa.PrivateKey中的曲线对象无法直接使用gob编码，因此只保存私钥的标量值
+ *加密的秘钥对不保存明文私钥，私钥D为空，并在公钥之后追加加密后的私钥
+ *HD秘钥对在最后追加派生路径，每一层索引占4个字节
+ */
+func (keyPair *KeyPair) GobEncode() ([]byte, error) {
+	buff := new(bytes.Buffer)
+	buff.WriteByte(keyPair.KeyType)
+	if keyPair.CryptedPriv != nil {
+		utils.WriteVarBytes(buff, nil)
+		utils.WriteVarBytes(buff, keyPair.Pub)
+		utils.WriteVarBytes(buff, keyPair.CryptedPriv)
+	} else {
+		if keyPair.Priv == nil {
+			return nil, errors.New("秘钥对缺少私钥")
+		}
+		utils.WriteVarBytes(buff, keyPair.Priv.D.Bytes())
+		utils.WriteVarBytes(buff, keyPair.Pub)
+	}
+	if len(keyPair.HDPath) > 0 {
+		path := make([]byte, 0, 4*len(keyPair.HDPath))
+		for _, index := range keyPair.HDPath {
+			path = binary.BigEndian.AppendUint32(path, index)
+		}
+		utils.WriteVarBytes(buff, path)
+	}
+	return buff.Bytes(), nil
+}
+
+/**
+ *秘钥对的持久化解码，根据密钥类型恢复完整的私钥
+ */
+func (keyPair *KeyPair) GobDecode(data []byte) error {
+	reader := bytes.NewReader(data)
+	keyType, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	curve, err := GetCurve(keyType)
+	if err != nil {
+		return err
+	}
+	d, err := utils.ReadVarBytes(reader)
+	if err != nil {
+		return err
+	}
+	pub, err := utils.ReadVarBytes(reader)
+	if err != nil {
+		return err
+	}
+	keyPair.KeyType = keyType
+	keyPair.Pub = pub
+	if len(d) == 0 {
+		//私钥已加密，等待钱包解锁时再解密
+		keyPair.CryptedPriv, err = utils.ReadVarBytes(reader)
+		if err != nil {
+			return err
+		}
+	} else {
+		keyPair.Priv, err = curve.PrivKeyFromBytes(d)
+		if err != nil {
+			return err
+		}
+	}
+	//旧版本的秘钥对没有派生路径
+	if reader.Len() == 0 {
+		return nil
+	}
+	path, err := utils.ReadVarBytes(reader)
+	if err != nil {
+		return err
+	}
+	if len(path)%4 != 0 {
+		return errors.New("秘钥对的派生路径格式不正确")
+	}
+	keyPair.HDPath = make([]uint32, len(path)/4)
+	for i := range keyPair.HDPath {
+		keyPair.HDPath[i] = binary.BigEndian.Uint32(path[4*i:])
+	}
+	return nil
+}
+
+/**
+ *恢复公钥信息，同时支持33字节的压缩格式和65字节的非压缩格式
  */
 func RecoverPublicKey(curve elliptic.Curve, data []byte) ecdsa.PublicKey {
-	x, y := elliptic.Unmarshal(curve, data)
-	pub := ecdsa.PublicKey{curve, x, y}
-	return pub
+	pub, err := ParsePublicKey(curve, data)
+	if err != nil {
+		return ecdsa.PublicKey{Curve: curve}
+	}
+	return *pub
+}
+
+/**
+ *解析公钥数据，公钥格式不正确或者公钥不在曲线上时返回错误
+ */
+func ParsePublicKey(curve elliptic.Curve, data []byte) (*ecdsa.PublicKey, error) {
+	var x, y *big.Int
+	byteLen := (curve.Params().BitSize + 7) / 8
+	switch len(data) {
+	case 1 + byteLen://压缩格式：0x02或0x03 + x
+		x, y = elliptic.UnmarshalCompressed(curve, data)
+	case 1 + 2*byteLen://非压缩格式：0x04 + x + y，兼容旧版本生成的地址
+		x, y = elliptic.Unmarshal(curve, data)
+	}
+	if x == nil {
+		return nil, errors.New("公钥格式不正确")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
 }
 
 /**
  *将[]byte格式的签名数据转换为r和s的big.Int类型
+ *该格式为旧版本使用的r.Bytes()||s.Bytes()拼接格式，r或s存在前导零字节时无法正确拆分，
+ *新的签名请使用DER编码，参见EncodeSignatureDER和ParseSignature
  */
 func ConverSignature(sign []byte) (r, s *big.Int) {
 	rBig := new(big.Int)
@@ -51,4 +198,4 @@ func ConverSignature(sign []byte) (r, s *big.Int) {
 	sBig.SetBytes(sign[len(sign)/2:])
 
 	return rBig, sBig
-}
\ No newline at end of file
+}
@@ -0,0 +1,54 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math/big"
+)
+
+/**
+ *旧版本钱包直接使用gob编码的秘钥对：私钥为完整的ecdsa.PrivateKey，公钥为65字节的非压缩格式
+ */
+type legacyKeyPair struct {
+	Priv *legacyPrivateKey
+	Pub  []byte
+}
+
+/**
+ *旧版本私钥中的曲线对象以旧版本Go的类型名称（crypto/elliptic.p256Curve）编码，当前版本无法解码，
+ *gob会跳过本地结构体中不存在的字段，因此只读取私钥的标量值
+ */
+type legacyPrivateKey struct {
+	D *big.Int
+}
+
+/**
+ *解码旧版本的地址和秘钥对信息，旧版本只支持P256曲线，公钥保持非压缩格式以保证地址不变
+ */
+func decodeLegacyKeyPairs(data []byte) (map[string]*KeyPair, error) {
+	legacyPairs := make(map[string]*legacyKeyPair)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&legacyPairs); err != nil {
+		return nil, err
+	}
+	curve := P256Curve{}
+	address := make(map[string]*KeyPair)
+	for addr, legacyPair := range legacyPairs {
+		if legacyPair.Priv == nil || legacyPair.Priv.D == nil {
+			return nil, errors.New("旧版本钱包中地址" + addr + "的私钥缺失")
+		}
+		priv, err := curve.PrivKeyFromBytes(legacyPair.Priv.D.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		pub, err := curve.ParsePubKey(legacyPair.Pub)
+		if err != nil {
+			return nil, err
+		}
+		if pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0 {
+			return nil, errors.New("旧版本钱包中地址" + addr + "的私钥与公钥不匹配")
+		}
+		address[addr] = &KeyPair{KeyType: curve.KeyType(), Priv: priv, Pub: legacyPair.Pub}
+	}
+	return address, nil
+}
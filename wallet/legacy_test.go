@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+/**
+ *旧版本的钱包直接使用gob编码ecdsa私钥和非压缩公钥，解码后地址和公钥保持不变，并且可以签名
+ */
+func TestDecodeLegacyKeyPairs(t *testing.T) {
+	//当前版本的Go无法使用gob编码ecdsa.PrivateKey中的曲线对象，测试时使用字段名称相同的结构体代替
+	type oldPublicKey struct {
+		X, Y *big.Int
+	}
+	type oldPrivateKey struct {
+		PublicKey oldPublicKey
+		D         *big.Int
+	}
+	type oldKeyPair struct {
+		Priv *oldPrivateKey
+		Pub  []byte
+	}
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := elliptic.Marshal(elliptic.P256(), priv.X, priv.Y)
+	address := (&Wallet{}).GetAddressByPubk(pub)
+	data, err := utils.Encoder(map[string]*oldKeyPair{address: {Priv: &oldPrivateKey{oldPublicKey{priv.X, priv.Y}, priv.D}, Pub: pub}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyPairs, err := decodeLegacyKeyPairs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPair := keyPairs[address]
+	if keyPair == nil || keyPair.KeyType != KEYTYPE_P256 || string(keyPair.Pub) != string(pub) {
+		t.Fatalf("旧版本的秘钥对解码结果不正确：%+v", keyPair)
+	}
+	hash := utils.Hash256([]byte("legacy"))
+	sig, err := keyPair.Sign(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifySignature(keyPair.Pub, hash, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	//新格式的秘钥对重新编码后可以正常解码
+	encoded, err := keyPair.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &KeyPair{}
+	if err = decoded.GobDecode(encoded); err != nil || decoded.Priv.D.Cmp(priv.D) != 0 {
+		t.Fatalf("秘钥对重新编码后解码失败：%v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package wallet
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/utils"
+	"bytes"
+	"errors"
+)
+
+/**
+ *多重签名地址的版本号，与普通地址的VERSION区分
+ *多重签名地址的载荷为完整的多重签名锁定脚本，转账方无需知道参与方的公钥即可锁定资金
+ */
+const MULTISIGVERSION = 0x06
+
+/**
+ *使用M个签名、N个公钥生成一个M-of-N的多重签名地址
+ *公钥可以来自不同的钱包，生成地址时不要求本地持有任何一个私钥
+ */
+func (wallet *Wallet) NewMultiSigAddress(required int, pubks [][]byte) (string, error) {
+	multiSigScript, err := NewMultiSigScript(required, pubks)
+	if err != nil {
+		return "", err
+	}
+	return wallet.GetAddressByPubkHash(append([]byte{MULTISIGVERSION}, multiSigScript...)), nil
+}
+
+/**
+ *检查公钥的格式后生成M-of-N的多重签名锁定脚本
+ */
+func NewMultiSigScript(required int, pubks [][]byte) ([]byte, error) {
+	for index, pubk := range pubks {
+		curve, err := GetCurveByPubKey(pubk)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = curve.ParsePubKey(pubk); err != nil {
+			return nil, err
+		}
+		for _, other := range pubks[:index] {
+			if bytes.Equal(other, pubk) {
+				return nil, errors.New("多重签名的公钥不能重复")
+			}
+		}
+	}
+	return script.MultiSigScript(required, pubks)
+}
+
+/**
+ *从多重签名地址中解析出多重签名锁定脚本，不是多重签名地址时返回错误
+ */
+func GetMultiSigScriptByAddress(address string) ([]byte, error) {
+	reAddr := utils.Decode(address)
+	if len(reAddr) < 5 || reAddr[0] != MULTISIGVERSION {
+		return nil, errors.New("该地址不是多重签名地址")
+	}
+	multiSigScript := reAddr[1 : len(reAddr)-4]
+	if !script.IsMultiSig(multiSigScript) {
+		return nil, errors.New("多重签名地址中的锁定脚本格式不正确")
+	}
+	return multiSigScript, nil
+}
+
+/**
+ *判断地址是否是多重签名地址
+ */
+func IsMultiSigAddress(address string) bool {
+	_, err := GetMultiSigScriptByAddress(address)
+	return err == nil
+}
+
+/**
+ *获取本地钱包中持有的某个多重签名地址的参与方秘钥对，按照锁定脚本中公钥的顺序返回
+ *同时返回花费该地址的资金所需的签名数量
+ */
+func (wallet *Wallet) GetMultiSigKeyPairs(address string) ([]*KeyPair, int, error) {
+	multiSigScript, err := GetMultiSigScriptByAddress(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	required, pubks, _ := script.ExtractMultiSig(multiSigScript)
+	keyPairs := make([]*KeyPair, 0, len(pubks))
+	for _, pubk := range pubks {
+		keyPair := wallet.GetKeyPairByAddress(wallet.GetAddressByPubk(pubk))
+		if keyPair != nil {
+			keyPairs = append(keyPairs, keyPair)
+		}
+	}
+	return keyPairs, required, nil
+}
+
+/**
+ *根据地址获取对应的所有签名者：多重签名地址返回本地持有的所有参与方秘钥对，
+ *P2SH地址返回本地持有的能够满足赎回脚本的秘钥对，其他地址返回GetSignerByAddress所得到的单个签名者
+ */
+func (wallet *Wallet) GetSignersByAddress(address string) ([]Signer, error) {
+	var keyPairs []*KeyPair
+	var err error
+	if redeemScript, ok := wallet.GetRedeemScript(address); ok {
+		keyPairs, err = wallet.getRedeemScriptKeyPairs(redeemScript)
+	} else if IsMultiSigAddress(address) {
+		keyPairs, _, err = wallet.GetMultiSigKeyPairs(address)
+		if err == nil && len(keyPairs) == 0 {
+			err = errors.New("钱包中未持有多重签名地址" + address + "的任何参与方私钥")
+		}
+	} else {
+		signer, err := wallet.GetSignerByAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		return []Signer{signer}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]Signer, 0, len(keyPairs))
+	for _, keyPair := range keyPairs {
+		signers = append(signers, keyPair)
+	}
+	return signers, nil
+}
+
+/**
+ *获取花费地址的资金所需的签名数量：多重签名地址和多重签名赎回脚本为M，其他地址为1
+ */
+func (wallet *Wallet) requiredSignatures(address string) int {
+	if redeemScript, ok := wallet.GetRedeemScript(address); ok {
+		if required, _, ok := script.ExtractMultiSig(script.StripTimeLock(redeemScript)); ok {
+			return required
+		}
+		return 1
+	}
+	if _, required, err := wallet.GetMultiSigKeyPairs(address); err == nil {
+		return required
+	}
+	return 1
+}
@@ -0,0 +1,473 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+)
+
+const MUSIGNONCES = "musig_nonces"        //MuSig签名会话中尚未使用的秘密nonce的键名
+const MUSIGNONCELEN = musig2.PubNonceSize //MuSig公开nonce的字节数
+const MUSIGPARTIALSIGLEN = 32 + 33        //MuSig部分签名的字节数：s值 + 压缩格式的最终nonce
+
+/**
+ *签名者的接口标准，交易签名时只需要签名者提供公钥和签名功能
+ *单个秘钥对和多方共同持有的MuSig聚合公钥都实现了该接口
+ */
+type Signer interface {
+	PublicKey() []byte
+	Sign(hash []byte) ([]byte, error)
+}
+
+/**
+ *返回秘钥对的公钥
+ */
+func (keyPair *KeyPair) PublicKey() []byte {
+	return keyPair.Pub
+}
+
+/**
+ *使用MuSig2算法聚合多个schnorr公钥，公钥在聚合前排序，因此结果与公钥的顺序无关
+ *聚合后的公钥与普通的schnorr公钥格式一致，花费时只需要一个64字节的签名
+ */
+func AggregateSchnorrPubKeys(pubks [][]byte) ([]byte, error) {
+	keys, err := parseMuSigPubKeys(pubks)
+	if err != nil {
+		return nil, err
+	}
+	aggKey, _, _, err := musig2.AggregateKeys(keys, true)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{KEYTYPE_SCHNORR}, schnorr.SerializePubKey(aggKey.FinalKey)...), nil
+}
+
+func parseMuSigPubKeys(pubks [][]byte) ([]*btcec.PublicKey, error) {
+	if len(pubks) < 2 {
+		return nil, errors.New("MuSig聚合至少需要两个公钥")
+	}
+	keys := make([]*btcec.PublicKey, 0, len(pubks))
+	for _, pubk := range pubks {
+		key, err := SchnorrCurve{}.parseBtcecPubKey(pubk)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+/**
+ *MuSig聚合公钥的签名者，PubKeys为所有参与方的schnorr公钥，KeyPairs为本地持有的参与方的秘钥对
+ *本地持有所有参与方的秘钥对时，在本地依次完成nonce交换、部分签名和签名聚合；
+ *其他参与方的私钥不在本地时，需要通过部分签名交易交换nonce和部分签名
+ */
+type MuSigSigner struct {
+	PubKeys  [][]byte
+	KeyPairs []*KeyPair
+}
+
+/**
+ *返回所有参与方的聚合公钥
+ */
+func (signer *MuSigSigner) PublicKey() []byte {
+	aggPub, err := AggregateSchnorrPubKeys(signer.PubKeys)
+	if err != nil {
+		return nil
+	}
+	return aggPub
+}
+
+/**
+ *使用MuSig2协议生成聚合签名，得到的签名可以使用聚合公钥按照普通的schnorr签名进行验证
+ */
+func (signer *MuSigSigner) Sign(hash []byte) ([]byte, error) {
+	keys, err := parseMuSigPubKeys(signer.PubKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(signer.KeyPairs) != len(keys) {
+		return nil, errors.New("缺少MuSig参与方的私钥，无法签名")
+	}
+	if len(hash) != 32 {
+		return nil, errors.New("schnorr签名的消息必须为32字节")
+	}
+	var msg [32]byte
+	copy(msg[:], hash)
+
+	//第一轮：每个参与方生成自己的nonce
+	sessions := make([]*musig2.Session, 0, len(signer.KeyPairs))
+	for _, keyPair := range signer.KeyPairs {
+		priv, err := muSigPrivKey(keyPair)
+		if err != nil {
+			return nil, err
+		}
+		ctx, err := musig2.NewContext(priv, true, musig2.WithKnownSigners(keys))
+		if err != nil {
+			return nil, err
+		}
+		session, err := ctx.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	//交换nonce
+	for i, session := range sessions {
+		for j, other := range sessions {
+			if i == j {
+				continue
+			}
+			if _, err := session.RegisterPubNonce(other.PublicNonce()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	//第二轮：每个参与方生成部分签名，由第一个参与方聚合
+	partialSigs := make([]*musig2.PartialSignature, 0, len(sessions))
+	for _, session := range sessions {
+		partialSig, err := session.Sign(msg)
+		if err != nil {
+			return nil, err
+		}
+		partialSigs = append(partialSigs, partialSig)
+	}
+	for _, partialSig := range partialSigs[1:] {
+		if _, err := sessions[0].CombineSig(partialSig); err != nil {
+			return nil, err
+		}
+	}
+	finalSig := sessions[0].FinalSig()
+	if finalSig == nil {
+		return nil, errors.New("MuSig签名聚合失败")
+	}
+	return finalSig.Serialize(), nil
+}
+
+/**
+ *使用钱包中的schnorr地址和其他参与方的schnorr公钥生成一个MuSig聚合地址，并将参与方公钥持久化保存
+ *至少需要一个参与方的私钥在本地钱包中，其他参与方的私钥不在本地时通过部分签名交易共同签名
+ */
+func (wallet *Wallet) NewMuSigAddress(addresses []string, externalPubks [][]byte) (string, error) {
+	if len(addresses) == 0 {
+		return "", errors.New("MuSig聚合地址至少需要一个本地钱包中的schnorr地址")
+	}
+	pubks := make([][]byte, 0, len(addresses)+len(externalPubks))
+	for _, address := range addresses {
+		keyPair := wallet.GetKeyPairByAddress(address)
+		if keyPair == nil {
+			return "", errors.New("钱包中未找到地址" + address + "的秘钥对")
+		}
+		if keyPair.KeyType != KEYTYPE_SCHNORR {
+			return "", errors.New("地址" + address + "不是schnorr地址")
+		}
+		pubks = append(pubks, keyPair.Pub)
+	}
+	pubks = append(pubks, externalPubks...)
+	for i := range pubks {
+		for j := i + 1; j < len(pubks); j++ {
+			if bytes.Equal(pubks[i], pubks[j]) {
+				return "", fmt.Errorf("MuSig参与方的公钥%x重复", pubks[i])
+			}
+		}
+	}
+	aggPub, err := AggregateSchnorrPubKeys(pubks)
+	if err != nil {
+		return "", err
+	}
+	address := wallet.GetAddressByPubk(aggPub)
+	wallet.MuSigKeys[address] = pubks
+
+	var muSigKeysBytes []byte
+	muSigKeysBytes, err = utils.Encoder(wallet.MuSigKeys)
+	if err != nil {
+		return "", err
+	}
+	err = wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(MUSIGKEYS), muSigKeysBytes)
+	})
+	if err != nil {
+		return "", err
+	}
+	return address, wallet.addAddressMeta(address, PURPOSERECEIVE)
+}
+
+/**
+ *根据地址获取对应的签名者：普通地址返回其秘钥对，MuSig聚合地址返回由所有参与方秘钥对组成的签名者
+ */
+func (wallet *Wallet) GetSignerByAddress(address string) (Signer, error) {
+	keyPair := wallet.GetKeyPairByAddress(address)
+	if keyPair != nil {
+		return keyPair, nil
+	}
+	pubks, ok := wallet.MuSigKeys[address]
+	if !ok {
+		return nil, errors.New("钱包中未找到地址" + address + "的秘钥对")
+	}
+	keyPairs := make([]*KeyPair, 0, len(pubks))
+	for _, pubk := range pubks {
+		keyPair := wallet.GetKeyPairByAddress(wallet.GetAddressByPubk(pubk))
+		if keyPair == nil {
+			return nil, errors.New("缺少MuSig参与方的私钥，无法签名")
+		}
+		keyPairs = append(keyPairs, keyPair)
+	}
+	return &MuSigSigner{PubKeys: pubks, KeyPairs: keyPairs}, nil
+}
+
+/**
+ *把秘钥对的私钥转换为MuSig签名所使用的私钥
+ *schnorr公钥为x-only格式，对应y坐标为偶数的点，y坐标为奇数时需要对私钥取反
+ */
+func muSigPrivKey(keyPair *KeyPair) (*btcec.PrivateKey, error) {
+	if keyPair.KeyType != KEYTYPE_SCHNORR {
+		return nil, errors.New("MuSig参与方的秘钥对必须是schnorr类型")
+	}
+	if keyPair.Priv == nil {
+		return nil, errors.New("钱包已锁定，请通过XFWALLETPASSPHRASE环境变量或-stdinpassphrase参数提供钱包密码")
+	}
+	priv, _ := btcec.PrivKeyFromBytes(keyPair.Priv.D.FillBytes(make([]byte, 32)))
+	if priv.PubKey().SerializeCompressed()[0] == 0x03 {
+		priv.Key.Negate()
+	}
+	return priv, nil
+}
+
+/**
+ *MuSig多方签名的第一轮：为本地参与方keyPair生成对消息hash签名所使用的nonce，返回需要发送给其他参与方的公开nonce
+ *秘密nonce保存在钱包中，只能用于一次部分签名，加密钱包使用主密钥加密后保存
+ */
+func (wallet *Wallet) NewMuSigNonce(keyPair *KeyPair, pubks [][]byte, hash []byte) ([]byte, error) {
+	keys, err := parseMuSigPubKeys(pubks)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, errors.New("schnorr签名的消息必须为32字节")
+	}
+	if err = wallet.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+	priv, err := muSigPrivKey(keyPair)
+	if err != nil {
+		return nil, err
+	}
+	aggKey, _, _, err := musig2.AggregateKeys(keys, true)
+	if err != nil {
+		return nil, err
+	}
+	var msg [32]byte
+	copy(msg[:], hash)
+	nonces, err := musig2.GenNonces(
+		musig2.WithPublicKey(priv.PubKey()),
+		musig2.WithNonceSecretKeyAux(priv),
+		musig2.WithNonceCombinedKeyAux(aggKey.FinalKey),
+		musig2.WithNonceMessageAux(msg))
+	if err != nil {
+		return nil, err
+	}
+	if err = wallet.putMuSigSecNonce(muSigSessionKey(keyPair.Pub, hash), nonces.SecNonce[:]); err != nil {
+		return nil, err
+	}
+	return nonces.PubNonce[:], nil
+}
+
+/**
+ *MuSig多方签名的第二轮：使用本地参与方keyPair的秘密nonce和所有参与方的公开nonce生成部分签名
+ *pubNonces与pubks一一对应，秘密nonce在签名之前从钱包中删除，保证同一个nonce不会被用于两次签名
+ */
+func (wallet *Wallet) MuSigPartialSign(keyPair *KeyPair, pubks [][]byte, pubNonces [][]byte, hash []byte) ([]byte, error) {
+	keys, err := parseMuSigPubKeys(pubks)
+	if err != nil {
+		return nil, err
+	}
+	combinedNonce, err := aggregateMuSigNonces(pubks, pubNonces)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, errors.New("schnorr签名的消息必须为32字节")
+	}
+	if err = wallet.CheckUnlocked(); err != nil {
+		return nil, err
+	}
+	priv, err := muSigPrivKey(keyPair)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey := muSigSessionKey(keyPair.Pub, hash)
+	secNonceBytes, err := wallet.takeMuSigSecNonce(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	var secNonce [musig2.SecNonceSize]byte
+	copy(secNonce[:], secNonceBytes)
+	var msg [32]byte
+	copy(msg[:], hash)
+	partialSig, err := musig2.Sign(secNonce, priv, combinedNonce, keys, msg, musig2.WithSortedKeys())
+	if err != nil {
+		return nil, err
+	}
+	buff := new(bytes.Buffer)
+	if err = partialSig.Encode(buff); err != nil {
+		return nil, err
+	}
+	buff.Write(partialSig.R.SerializeCompressed())
+	return buff.Bytes(), nil
+}
+
+/**
+ *验证每个参与方的部分签名并聚合为最终的schnorr签名，pubNonces和partialSigs与pubks一一对应
+ *得到的签名可以使用聚合公钥按照普通的schnorr签名进行验证
+ */
+func CombineMuSigPartialSigs(pubks [][]byte, pubNonces [][]byte, partialSigs [][]byte, hash []byte) ([]byte, error) {
+	keys, err := parseMuSigPubKeys(pubks)
+	if err != nil {
+		return nil, err
+	}
+	combinedNonce, err := aggregateMuSigNonces(pubks, pubNonces)
+	if err != nil {
+		return nil, err
+	}
+	if len(partialSigs) != len(pubks) {
+		return nil, errors.New("MuSig部分签名的个数与参与方的个数不一致")
+	}
+	if len(hash) != 32 {
+		return nil, errors.New("schnorr签名的消息必须为32字节")
+	}
+	var msg [32]byte
+	copy(msg[:], hash)
+	//musig2在聚合公钥时会对传入的公钥切片原地排序，参与方公钥需要在验证之前单独取出
+	signingKeys := append([]*btcec.PublicKey(nil), keys...)
+	sigs := make([]*musig2.PartialSignature, 0, len(partialSigs))
+	for index, data := range partialSigs {
+		if len(data) != MUSIGPARTIALSIGLEN {
+			return nil, fmt.Errorf("参与方%x的部分签名长度不正确", pubks[index])
+		}
+		partialSig := new(musig2.PartialSignature)
+		if err = partialSig.Decode(bytes.NewReader(data[:32])); err != nil {
+			return nil, err
+		}
+		if partialSig.R, err = btcec.ParsePubKey(data[32:]); err != nil {
+			return nil, err
+		}
+		var pubNonce [musig2.PubNonceSize]byte
+		copy(pubNonce[:], pubNonces[index])
+		if !partialSig.Verify(pubNonce, combinedNonce, keys, signingKeys[index], msg, musig2.WithSortedKeys()) {
+			return nil, fmt.Errorf("参与方%x的部分签名无效", pubks[index])
+		}
+		if len(sigs) > 0 && !partialSig.R.IsEqual(sigs[0].R) {
+			return nil, errors.New("MuSig部分签名使用的最终nonce不一致")
+		}
+		sigs = append(sigs, partialSig)
+	}
+	return musig2.CombineSigs(sigs[0].R, sigs).Serialize(), nil
+}
+
+/**
+ *聚合所有参与方的公开nonce，pubNonces与pubks一一对应
+ */
+func aggregateMuSigNonces(pubks [][]byte, pubNonces [][]byte) ([musig2.PubNonceSize]byte, error) {
+	if len(pubNonces) != len(pubks) {
+		return [musig2.PubNonceSize]byte{}, errors.New("MuSig公开nonce的个数与参与方的个数不一致")
+	}
+	nonces := make([][musig2.PubNonceSize]byte, 0, len(pubNonces))
+	for index, data := range pubNonces {
+		if len(data) != MUSIGNONCELEN {
+			return [musig2.PubNonceSize]byte{}, fmt.Errorf("参与方%x的公开nonce长度不正确", pubks[index])
+		}
+		var nonce [musig2.PubNonceSize]byte
+		copy(nonce[:], data)
+		nonces = append(nonces, nonce)
+	}
+	return musig2.AggregateNonces(nonces)
+}
+
+/**
+ *签名会话的标识：参与方公钥 + 签名的消息
+ */
+func muSigSessionKey(pubk []byte, hash []byte) string {
+	return hex.EncodeToString(pubk) + hex.EncodeToString(hash)
+}
+
+/**
+ *把秘密nonce保存到keystore桶中
+ */
+func (wallet *Wallet) putMuSigSecNonce(sessionKey string, secNonce []byte) error {
+	if wallet.IsEncrypted() {
+		crypted, err := sealAESGCM(wallet.unlockedKey, secNonce, []byte(sessionKey))
+		if err != nil {
+			return err
+		}
+		secNonce = crypted
+	}
+	return wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		secNonces := make(map[string][]byte)
+		if data := bucket.Get([]byte(MUSIGNONCES)); len(data) != 0 {
+			if _, err = utils.Decodes(data, &secNonces); err != nil {
+				return err
+			}
+		}
+		secNonces[sessionKey] = secNonce
+		secNoncesBytes, err := utils.Encoder(secNonces)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(MUSIGNONCES), secNoncesBytes)
+	})
+}
+
+/**
+ *从keystore桶中取出并删除秘密nonce，未找到时返回错误
+ */
+func (wallet *Wallet) takeMuSigSecNonce(sessionKey string) ([]byte, error) {
+	var secNonce []byte
+	err := wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(wallet.keystore())
+		if bucket == nil {
+			return nil
+		}
+		secNonces := make(map[string][]byte)
+		if data := bucket.Get([]byte(MUSIGNONCES)); len(data) != 0 {
+			if _, err := utils.Decodes(data, &secNonces); err != nil {
+				return err
+			}
+		}
+		secNonce = secNonces[sessionKey]
+		delete(secNonces, sessionKey)
+		secNoncesBytes, err := utils.Encoder(secNonces)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(MUSIGNONCES), secNoncesBytes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secNonce == nil {
+		return nil, errors.New("钱包中没有该签名会话的nonce，nonce已被使用或尚未生成")
+	}
+	if wallet.IsEncrypted() {
+		if secNonce, err = openAESGCM(wallet.unlockedKey, secNonce, []byte(sessionKey)); err != nil {
+			return nil, errors.New("nonce解密失败")
+		}
+	}
+	if len(secNonce) != musig2.SecNonceSize {
+		return nil, errors.New("钱包中保存的nonce格式不正确")
+	}
+	return secNonce, nil
+}
@@ -0,0 +1,169 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"testing"
+)
+
+/**
+ *在临时目录中创建一个钱包，测试结束后关闭数据库
+ */
+func newTestWallet(t *testing.T, name string) *Wallet {
+	t.Helper()
+	wallet, err := CreateWallet(newTestEngine(t), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet
+}
+
+func newTestKeyPair(t *testing.T, curve Curve) *KeyPair {
+	t.Helper()
+	keyPair, err := NewKeyPairWithCurve(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyPair
+}
+
+/**
+ *本地持有所有参与方私钥时，MuSigSigner的签名可以使用聚合公钥按照普通schnorr签名验证
+ */
+func TestMuSigSignerRoundTrip(t *testing.T) {
+	keyPairs := []*KeyPair{newTestKeyPair(t, SchnorrCurve{}), newTestKeyPair(t, SchnorrCurve{}), newTestKeyPair(t, SchnorrCurve{})}
+	pubks := [][]byte{keyPairs[0].Pub, keyPairs[1].Pub, keyPairs[2].Pub}
+	signer := &MuSigSigner{PubKeys: pubks, KeyPairs: keyPairs}
+	hash := utils.Hash256([]byte("musig"))
+	sig, err := signer.Sign(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifySignature(signer.PublicKey(), hash, sig); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifySignature(signer.PublicKey(), utils.Hash256([]byte("other")), sig); err == nil {
+		t.Fatal("签名不应能验证其他消息")
+	}
+
+	//聚合公钥与参与方公钥的顺序无关
+	reversed, err := AggregateSchnorrPubKeys([][]byte{pubks[2], pubks[1], pubks[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reversed) != string(signer.PublicKey()) {
+		t.Fatal("聚合公钥不应受参与方公钥顺序的影响")
+	}
+
+	//缺少参与方私钥时不能签名
+	partial := &MuSigSigner{PubKeys: pubks, KeyPairs: keyPairs[:2]}
+	if _, err = partial.Sign(hash); err == nil {
+		t.Fatal("缺少参与方私钥时应当返回错误")
+	}
+}
+
+/**
+ *参与方分别在自己的钱包中生成nonce和部分签名，聚合后的签名可以使用聚合公钥验证，nonce只能使用一次
+ */
+func TestMuSigMultiParty(t *testing.T) {
+	alice, bob := newTestWallet(t, "alice"), newTestWallet(t, "bob")
+	aliceAddress, err := alice.NewAddressWithCurve(SchnorrCurve{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobAddress, err := bob.NewAddressWithCurve(SchnorrCurve{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceKey, bobKey := alice.GetKeyPairByAddress(aliceAddress), bob.GetKeyPairByAddress(bobAddress)
+
+	//双方各自使用本地地址和对方的公钥生成相同的聚合地址，公钥的顺序不同
+	muSigAddress, err := alice.NewMuSigAddress([]string{aliceAddress}, [][]byte{bobKey.Pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobMuSigAddress, err := bob.NewMuSigAddress([]string{bobAddress}, [][]byte{aliceKey.Pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if muSigAddress != bobMuSigAddress {
+		t.Fatalf("双方生成的聚合地址不一致：%s %s", muSigAddress, bobMuSigAddress)
+	}
+	if _, err = alice.GetSignerByAddress(muSigAddress); err == nil {
+		t.Fatal("缺少对方私钥时不应返回本地签名者")
+	}
+
+	pubks := alice.MuSigKeys[muSigAddress]
+	hash := utils.Hash256([]byte("musig multi party"))
+	aliceNonce, err := alice.NewMuSigNonce(aliceKey, pubks, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobNonce, err := bob.NewMuSigNonce(bobKey, pubks, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonces := [][]byte{aliceNonce, bobNonce}
+	aliceSig, err := alice.MuSigPartialSign(aliceKey, pubks, nonces, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobSig, err := bob.MuSigPartialSign(bobKey, pubks, nonces, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := CombineMuSigPartialSigs(pubks, nonces, [][]byte{aliceSig, bobSig}, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggPub, err := AggregateSchnorrPubKeys(pubks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifySignature(aggPub, hash, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	//nonce在签名后被删除，不能再次用于签名
+	if _, err = alice.MuSigPartialSign(aliceKey, pubks, nonces, hash); err == nil {
+		t.Fatal("同一个nonce不应能用于两次签名")
+	}
+	//部分签名与参与方不对应时聚合失败
+	if _, err = CombineMuSigPartialSigs(pubks, nonces, [][]byte{bobSig, aliceSig}, hash); err == nil {
+		t.Fatal("无效的部分签名应当被拒绝")
+	}
+}
+
+/**
+ *批量验证器中只要有一个签名无效，整批验证失败
+ */
+func TestSchnorrBatchRejectsBadSignature(t *testing.T) {
+	batch := NewSchnorrBatch()
+	badBatch := NewSchnorrBatch()
+	for i := 0; i < 4; i++ {
+		keyPair := newTestKeyPair(t, SchnorrCurve{})
+		hash := utils.Hash256([]byte{byte(i)})
+		sig, err := keyPair.Sign(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = batch.Add(keyPair.Pub, hash, sig); err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			//签名另一条消息，签名格式正确但验证不通过
+			hash = utils.Hash256([]byte("tampered"))
+		}
+		if err = badBatch.Add(keyPair.Pub, hash, sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if batch.Len() != 4 || badBatch.Len() != 4 {
+		t.Fatal("批量验证器中的签名个数不正确")
+	}
+	if err := batch.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := badBatch.Verify(); err == nil {
+		t.Fatal("包含无效签名的批次应当验证失败")
+	}
+}
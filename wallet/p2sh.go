@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"XianfengChain04/script"
+	"XianfengChain04/utils"
+	"errors"
+	"github.com/boltdb/bolt"
+)
+
+/**
+ *P2SH地址的版本号，地址载荷为赎回脚本的hash160
+ *转账方只需要知道脚本哈希即可锁定资金，花费时由接收方提供完整的赎回脚本
+ */
+const P2SHVERSION = 0x05
+
+/**
+ *根据赎回脚本计算对应的P2SH地址
+ */
+func (wallet *Wallet) GetAddressByRedeemScript(redeemScript []byte) string {
+	return wallet.GetAddressByPubkHash(append([]byte{P2SHVERSION}, script.Hash160(redeemScript)...))
+}
+
+/**
+ *把赎回脚本保存到钱包中，并返回对应的P2SH地址，钱包只有保存了赎回脚本才能花费该地址的资金
+ */
+func (wallet *Wallet) AddRedeemScript(redeemScript []byte) (string, error) {
+	if len(redeemScript) == 0 || len(redeemScript) > script.MAXELEMENTSIZE {
+		return "", errors.New("赎回脚本的长度不正确")
+	}
+	address := wallet.GetAddressByRedeemScript(redeemScript)
+	wallet.RedeemScripts[address] = redeemScript
+
+	redeemScriptsBytes, err := utils.Encoder(wallet.RedeemScripts)
+	if err != nil {
+		return "", err
+	}
+	err = wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(REDEEMSCRIPTS), redeemScriptsBytes)
+	})
+	if err != nil {
+		return "", err
+	}
+	return address, wallet.addAddressMeta(address, PURPOSERECEIVE)
+}
+
+/**
+ *使用多个公钥生成一个P2SH包装的M-of-N多重签名地址，赎回脚本保存到钱包中
+ */
+func (wallet *Wallet) NewP2SHMultiSigAddress(required int, pubks [][]byte) (string, error) {
+	redeemScript, err := NewMultiSigScript(required, pubks)
+	if err != nil {
+		return "", err
+	}
+	return wallet.AddRedeemScript(redeemScript)
+}
+
+/**
+ *获取P2SH地址对应的赎回脚本，钱包中未保存该地址的赎回脚本时ok为false
+ */
+func (wallet *Wallet) GetRedeemScript(address string) ([]byte, bool) {
+	redeemScript, ok := wallet.RedeemScripts[address]
+	return redeemScript, ok
+}
+
+/**
+ *获取本地钱包中能够满足赎回脚本的秘钥对：多重签名赎回脚本返回本地持有的参与方秘钥对，
+ *P2PKH赎回脚本返回公钥哈希所对应的秘钥对
+ */
+func (wallet *Wallet) getRedeemScriptKeyPairs(redeemScript []byte) ([]*KeyPair, error) {
+	keyPairs := make([]*KeyPair, 0)
+	//带时间锁的赎回脚本按照内部的花费条件查找秘钥对
+	redeemScript = script.StripTimeLock(redeemScript)
+	if _, pubks, ok := script.ExtractMultiSig(redeemScript); ok {
+		for _, pubk := range pubks {
+			keyPair := wallet.GetKeyPairByAddress(wallet.GetAddressByPubk(pubk))
+			if keyPair != nil {
+				keyPairs = append(keyPairs, keyPair)
+			}
+		}
+	} else if pubkHash := script.ExtractPubKeyHash(redeemScript); pubkHash != nil {
+		address := wallet.GetAddressByPubkHash(append([]byte{VERSION}, pubkHash...))
+		if keyPair := wallet.GetKeyPairByAddress(address); keyPair != nil {
+			keyPairs = append(keyPairs, keyPair)
+		}
+	} else {
+		return nil, errors.New("钱包暂不支持对该类型的赎回脚本签名")
+	}
+	if len(keyPairs) == 0 {
+		return nil, errors.New("钱包中未持有赎回脚本所需的任何私钥")
+	}
+	return keyPairs, nil
+}
+
+/**
+ *生成一个带时间锁的P2SH地址：时间锁到期后，address的私钥才能花费该地址的资金
+ *relative为false时lockTime为区块高度或unix时间戳，为true时lockTime为sequence编码的相对时间锁
+ */
+func (wallet *Wallet) NewTimeLockAddress(address string, lockTime int64, relative bool) (string, error) {
+	reAddr := utils.Decode(address)
+	if len(reAddr) != 1+script.PUBKHASHLEN+4 || reAddr[0] != VERSION {
+		return "", errors.New("时间锁地址只支持普通地址作为接收方")
+	}
+	p2pkh, err := script.PayToPubKeyHashScript(reAddr[1 : 1+script.PUBKHASHLEN])
+	if err != nil {
+		return "", err
+	}
+	redeemScript, err := script.TimeLockScript(lockTime, relative, p2pkh)
+	if err != nil {
+		return "", err
+	}
+	return wallet.AddRedeemScript(redeemScript)
+}
@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"math/big"
+)
+
+/**
+ *BIP340规范的Schnorr签名，基于secp256k1曲线
+ *公钥格式：KEYTYPE_SCHNORR + 32字节的x-only公钥，签名固定为64字节：R.x(32) + s(32)
+ */
+type SchnorrCurve struct{}
+
+func (SchnorrCurve) KeyType() byte {
+	return KEYTYPE_SCHNORR
+}
+
+func (SchnorrCurve) Name() string {
+	return "schnorr"
+}
+
+func (SchnorrCurve) GenerateKey() (*ecdsa.PrivateKey, error) {
+	return Secp256k1Curve{}.GenerateKey()
+}
+
+func (SchnorrCurve) PrivKeyFromBytes(d []byte) (*ecdsa.PrivateKey, error) {
+	return Secp256k1Curve{}.PrivKeyFromBytes(d)
+}
+
+func (curve SchnorrCurve) MarshalPubKey(pub *ecdsa.PublicKey) []byte {
+	var x, y btcec.FieldVal
+	x.SetByteSlice(pub.X.FillBytes(make([]byte, 32)))
+	y.SetByteSlice(pub.Y.FillBytes(make([]byte, 32)))
+	xOnly := schnorr.SerializePubKey(btcec.NewPublicKey(&x, &y))
+	return append([]byte{curve.KeyType()}, xOnly...)
+}
+
+func (curve SchnorrCurve) ParsePubKey(data []byte) (*ecdsa.PublicKey, error) {
+	pub, err := curve.parseBtcecPubKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return pub.ToECDSA(), nil
+}
+
+func (curve SchnorrCurve) parseBtcecPubKey(data []byte) (*btcec.PublicKey, error) {
+	if len(data) != 1+schnorr.PubKeyBytesLen || data[0] != curve.KeyType() {
+		return nil, errors.New("schnorr公钥格式不正确")
+	}
+	return schnorr.ParsePubKey(data[1:])
+}
+
+func (SchnorrCurve) Sign(priv *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	key, _ := btcec.PrivKeyFromBytes(priv.D.FillBytes(make([]byte, 32)))
+	sig, err := schnorr.Sign(key, hash)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+func (curve SchnorrCurve) Verify(pubk []byte, hash []byte, sig []byte) error {
+	pub, err := curve.parseBtcecPubKey(pubk)
+	if err != nil {
+		return err
+	}
+	signature, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(hash, pub) {
+		return errors.New("验签失败")
+	}
+	return nil
+}
+
+/**
+ *判断公钥是否是schnorr公钥，schnorr公钥的签名可以加入批量验证
+ */
+func IsSchnorrPubKey(pubk []byte) bool {
+	return len(pubk) == 1+schnorr.PubKeyBytesLen && pubk[0] == KEYTYPE_SCHNORR
+}
+
+/**
+ *schnorr签名的批量验证器
+ *逐个验证n个签名需要计算2n次点乘，批量验证将所有的验证等式使用随机系数a_i线性组合为一个等式：
+ *(Σa_i*s_i)*G == Σa_i*R_i + Σ(a_i*e_i)*P_i
+ *任何一个签名无效时，等式成立的概率可以忽略不计
+ */
+type SchnorrBatch struct {
+	items []schnorrBatchItem
+}
+
+type schnorrBatchItem struct {
+	pub  *btcec.PublicKey
+	pubX []byte
+	hash []byte
+	r    btcec.FieldVal
+	s    btcec.ModNScalar
+}
+
+/**
+ *构建一个空的批量验证器
+ */
+func NewSchnorrBatch() *SchnorrBatch {
+	return &SchnorrBatch{items: make([]schnorrBatchItem, 0)}
+}
+
+/**
+ *向批量验证器中添加一个待验证的签名，签名和公钥的格式错误会立即返回
+ */
+func (batch *SchnorrBatch) Add(pubk []byte, hash []byte, sig []byte) error {
+	pub, err := SchnorrCurve{}.parseBtcecPubKey(pubk)
+	if err != nil {
+		return err
+	}
+	if len(sig) != schnorr.SignatureSize {
+		return errors.New("schnorr签名长度不正确")
+	}
+	if len(hash) != 32 {
+		return errors.New("schnorr签名的消息必须为32字节")
+	}
+	item := schnorrBatchItem{
+		pub:  pub,
+		pubX: pubk[1:],
+		hash: hash,
+	}
+	if item.r.SetByteSlice(sig[:32]) {
+		return errors.New("schnorr签名的r超出范围")
+	}
+	if item.s.SetByteSlice(sig[32:]) {
+		return errors.New("schnorr签名的s超出范围")
+	}
+	batch.items = append(batch.items, item)
+	return nil
+}
+
+/**
+ *返回批量验证器中待验证的签名个数
+ */
+func (batch *SchnorrBatch) Len() int {
+	return len(batch.items)
+}
+
+/**
+ *执行批量验证，所有签名都有效时返回nil
+ */
+func (batch *SchnorrBatch) Verify() error {
+	if len(batch.items) == 0 {
+		return nil
+	}
+	var sumS btcec.ModNScalar
+	var rhs btcec.JacobianPoint
+	for index, item := range batch.items {
+		//第一个签名的系数固定为1，其余签名的系数为随机数
+		var a btcec.ModNScalar
+		a.SetInt(1)
+		if index > 0 {
+			var err error
+			a, err = randomScalar()
+			if err != nil {
+				return err
+			}
+		}
+
+		//R_i：x坐标为r、y坐标为偶数的点
+		var rY btcec.FieldVal
+		if !btcec.DecompressY(&item.r, false, &rY) {
+			return errors.New("schnorr签名的r不在曲线上")
+		}
+		rJ := btcec.MakeJacobianPoint(&item.r, &rY, new(btcec.FieldVal).SetInt(1))
+
+		//e_i = tagged_hash("BIP0340/challenge", r || P.x || m) mod n
+		rBytes := item.r.Bytes()
+		challenge := taggedHash("BIP0340/challenge", rBytes[:], item.pubX, item.hash)
+		var e btcec.ModNScalar
+		e.SetByteSlice(challenge)
+
+		var pJ btcec.JacobianPoint
+		item.pub.AsJacobian(&pJ)
+
+		//累加a_i*s_i
+		var aS btcec.ModNScalar
+		aS.Mul2(&a, &item.s)
+		sumS.Add(&aS)
+
+		//累加a_i*R_i + (a_i*e_i)*P_i
+		var aR, aeP btcec.JacobianPoint
+		btcec.ScalarMultNonConst(&a, &rJ, &aR)
+		var ae btcec.ModNScalar
+		ae.Mul2(&a, &e)
+		btcec.ScalarMultNonConst(&ae, &pJ, &aeP)
+		btcec.AddNonConst(&rhs, &aR, &rhs)
+		btcec.AddNonConst(&rhs, &aeP, &rhs)
+	}
+
+	var lhs btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&sumS, &lhs)
+	lhs.ToAffine()
+	rhs.ToAffine()
+	if !lhs.X.Equals(&rhs.X) || !lhs.Y.Equals(&rhs.Y) {
+		return errors.New("schnorr签名批量验证失败")
+	}
+	return nil
+}
+
+/**
+ *BIP340规范的带标签哈希：sha256(sha256(tag) || sha256(tag) || data)
+ */
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	hash := sha256.New()
+	hash.Write(tagHash[:])
+	hash.Write(tagHash[:])
+	for _, item := range data {
+		hash.Write(item)
+	}
+	return hash.Sum(nil)
+}
+
+/**
+ *生成一个[1, N-1]范围内的随机标量
+ */
+func randomScalar() (btcec.ModNScalar, error) {
+	var scalar btcec.ModNScalar
+	for {
+		k, err := rand.Int(rand.Reader, btcec.S256().N)
+		if err != nil {
+			return scalar, err
+		}
+		if k.Cmp(big.NewInt(0)) == 0 {
+			continue
+		}
+		scalar.SetByteSlice(k.FillBytes(make([]byte, 32)))
+		return scalar, nil
+	}
+}
@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+)
+
+const LEGACYSIGLEN = 64 //旧版本r||s拼接格式的签名长度，r和s各占32字节
+
+/**
+ *判断签名的s值是否为low-S形式，即s <= N/2
+ *对于任意合法签名(r, s)，(r, N-s)同样是合法签名，只接受low-S形式可以消除这种延展性
+ */
+func IsLowS(curve elliptic.Curve, s *big.Int) bool {
+	halfOrder := new(big.Int).Rsh(curve.Params().N, 1)
+	return s.Sign() > 0 && s.Cmp(halfOrder) <= 0
+}
+
+/**
+ *将签名的s值转换为low-S形式，如果s > N/2，则返回N-s
+ */
+func NormalizeLowS(curve elliptic.Curve, s *big.Int) *big.Int {
+	if IsLowS(curve, s) {
+		return s
+	}
+	return new(big.Int).Sub(curve.Params().N, s)
+}
+
+/**
+ *将签名的r和s编码为DER格式：
+ *0x30 + 总长度 + 0x02 + r的长度 + r + 0x02 + s的长度 + s
+ *r和s均使用最短的大端序编码，最高位为1时在前面补一个0x00字节
+ */
+func EncodeSignatureDER(r, s *big.Int) []byte {
+	rBytes := encodeDERInteger(r)
+	sBytes := encodeDERInteger(s)
+	sig := make([]byte, 0, 6+len(rBytes)+len(sBytes))
+	sig = append(sig, 0x30, byte(4+len(rBytes)+len(sBytes)))
+	sig = append(sig, 0x02, byte(len(rBytes)))
+	sig = append(sig, rBytes...)
+	sig = append(sig, 0x02, byte(len(sBytes)))
+	sig = append(sig, sBytes...)
+	return sig
+}
+
+func encodeDERInteger(num *big.Int) []byte {
+	data := num.Bytes()
+	if len(data) == 0 || data[0]&0x80 != 0 {
+		data = append([]byte{0x00}, data...)
+	}
+	return data
+}
+
+/**
+ *严格解析DER格式的签名，任何不符合最短编码规则的签名都会被拒绝
+ */
+func ParseSignatureDER(sig []byte) (r, s *big.Int, err error) {
+	//最短的DER签名：0x30 0x06 0x02 0x01 r 0x02 0x01 s
+	if len(sig) < 8 || len(sig) > 72 {
+		return nil, nil, errors.New("DER签名长度不正确")
+	}
+	if sig[0] != 0x30 || int(sig[1]) != len(sig)-2 {
+		return nil, nil, errors.New("DER签名头部不正确")
+	}
+	r, rest, err := parseDERInteger(sig[2:])
+	if err != nil {
+		return nil, nil, err
+	}
+	s, rest, err = parseDERInteger(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("DER签名末尾存在多余的字节")
+	}
+	return r, s, nil
+}
+
+func parseDERInteger(data []byte) (*big.Int, []byte, error) {
+	if len(data) < 2 || data[0] != 0x02 {
+		return nil, nil, errors.New("DER签名整数标记不正确")
+	}
+	length := int(data[1])
+	if length == 0 || length > len(data)-2 {
+		return nil, nil, errors.New("DER签名整数长度不正确")
+	}
+	num := data[2 : 2+length]
+	if num[0]&0x80 != 0 {
+		return nil, nil, errors.New("DER签名整数不能为负数")
+	}
+	if length > 1 && num[0] == 0x00 && num[1]&0x80 == 0 {
+		return nil, nil, errors.New("DER签名整数存在多余的前导零")
+	}
+	value := new(big.Int).SetBytes(num)
+	if value.Sign() == 0 {
+		return nil, nil, errors.New("DER签名整数不能为零")
+	}
+	return value, data[2+length:], nil
+}
+
+/**
+ *解析交易中的签名数据：优先按照DER格式严格解析，
+ *解析失败且长度为64字节时，按照旧版本的r||s拼接格式解析，以兼容已经上链的交易
+ */
+func ParseSignature(sig []byte) (r, s *big.Int, err error) {
+	r, s, err = ParseSignatureDER(sig)
+	if err == nil {
+		return r, s, nil
+	}
+	if len(sig) == LEGACYSIGLEN {
+		r, s = ConverSignature(sig)
+		return r, s, nil
+	}
+	return nil, nil, err
+}
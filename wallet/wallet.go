@@ -1,10 +1,11 @@
 package wallet
 
 import (
+	"XianfengChain04/script"
 	"XianfengChain04/utils"
 	"bytes"
-	"crypto/elliptic"
 	"encoding/gob"
+	"errors"
 	"github.com/boltdb/bolt"
 )
 
@@ -12,27 +13,37 @@ const KEYSTORE = "keystores"
 const ADDANDPAIR = "addrs_keypairs"
 const VERSION = 0x00
 const COINBASE = "coinbase"//键名
+const MUSIGKEYS = "musig_keys"//MuSig聚合地址及其参与方公钥的键名
+const REDEEMSCRIPTS = "redeem_scripts"//P2SH地址及其赎回脚本的键名
 
 /**
  *定义wallet结构体，用于管理地址和对应的秘钥对信息
  */
 type Wallet struct {
+	Name string//钱包名称，默认钱包的名称为空
 	Address map[string]*KeyPair
+	MuSigKeys map[string][][]byte//MuSig聚合地址 -> 所有参与方的schnorr公钥
+	RedeemScripts map[string][]byte//P2SH地址 -> 赎回脚本
+	MasterKey *MasterKey//加密钱包的主密钥记录，未加密时为空
+	HDChain *HDChain//HD钱包的种子记录，生成第一个HD地址之前为空
+	WatchOnly map[string]bool//没有私钥、只查询余额的观察地址
+	AddressBook map[string]AddressMeta//地址簿：地址的标签、用途和创建时间
+	unlockedKey []byte//解锁后的主密钥，仅保存在内存中
 	Engine  *bolt.DB
 }
 
+/**
+ *生成一个P256曲线的新地址
+ */
 func (wallet *Wallet) NewAddress() (string, error) {
-	keyPair, err := NewKeyPair()
-	if err != nil {
-		return "", err
-	}
+	return wallet.NewAddressWithCurve(P256Curve{})
+}
 
-	address := wallet.GetAddressByPubk(keyPair.Pub)
-	//把新生成的地址和对应的秘钥对存入到wallet的map结构中管理起来
-	wallet.Address[address] = keyPair//仅仅是内存
-	//把更新了地址信息和对应秘钥对的map结构中的数据持久化存储到
-    wallet.SaveAddAndKeyPairs2DB()
-	return  address, nil
+/**
+ *使用指定的曲线生成一个新地址，地址的秘钥由HD种子派生，位于默认账户的收款链上
+ */
+func (wallet *Wallet) NewAddressWithCurve(curve Curve) (string, error) {
+	return wallet.NewHDAddress(curve, 0, EXTERNALCHAIN)
 }
 
 /**
@@ -54,7 +65,29 @@ func (wallet *Wallet)CheckAddress(addr string) bool {
 	reSecondHash := utils.Hash256(reFirstHash)
 	//5，对双哈希以后的内容进行前四个字节的截取
 	check := reSecondHash[:4]
-	return bytes.Compare(reCheck, check) == 0
+	if bytes.Compare(reCheck, check) != 0 {
+		return false
+	}
+	//6，根据版本号检查地址载荷的格式
+	return checkAddressPayload(reVersionPubHash)
+}
+
+/**
+ *根据地址的版本号检查地址载荷：普通地址和P2SH地址为20字节的哈希，多重签名地址为多重签名锁定脚本
+ */
+func checkAddressPayload(versionPayload []byte) bool {
+	if len(versionPayload) < 1 {
+		return false
+	}
+	switch versionPayload[0] {
+	case VERSION:
+		return len(versionPayload) == 1+script.PUBKHASHLEN
+	case P2SHVERSION:
+		return len(versionPayload) == 1+script.SCRIPTHASHLEN
+	case MULTISIGVERSION:
+		return script.IsMultiSig(versionPayload[1:])
+	}
+	return false
 }
 
 /**
@@ -63,16 +96,15 @@ func (wallet *Wallet)CheckAddress(addr string) bool {
 func (wallet *Wallet) SaveAddAndKeyPairs2DB() {
 	var err error
 	wallet.Engine.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(KEYSTORE))
+		bucket := tx.Bucket(wallet.keystore())
 		if bucket == nil {
-			bucket, err = tx.CreateBucket([]byte(KEYSTORE))
+			bucket, err = tx.CreateBucket(wallet.keystore())
 			if err != nil {
 				return err
 			}
 		}
 		//桶keystores已经存在，可以向桶中存放map的数据了
 		//map[key]keypair
-		gob.Register(elliptic.P256())
 		buff := new(bytes.Buffer)
 		encoder := gob.NewEncoder(buff)
 		err := encoder.Encode(wallet.Address)
@@ -88,24 +120,92 @@ func (wallet *Wallet) SaveAddAndKeyPairs2DB() {
  *从文件中读取已经存在的地址和秘钥对信息
  */
 func LoadAddrAndKeyPairsFromDB(engine *bolt.DB) (*Wallet, error) {
+	return loadWalletFromDB(engine, DEFAULTWALLET)
+}
+
+/**
+ *从指定名称的钱包所在的keystore桶中读取地址和秘钥对信息
+ */
+func loadWalletFromDB(engine *bolt.DB, name string) (*Wallet, error) {
 	address := make(map[string]*KeyPair)
+	muSigKeys := make(map[string][][]byte)
+	redeemScripts := make(map[string][]byte)
+	watchOnly := make(map[string]bool)
+	addressBook := make(map[string]AddressMeta)
+	var masterKey *MasterKey
+	var hdChain *HDChain
+	var isLegacy bool
 	var err error
 	engine.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(KEYSTORE))
+		bucket := tx.Bucket([]byte(KeystoreBucket(name)))
 		if bucket == nil {
 			return nil
 		}
 
 		//如果有keystore存在，从keystore桶中读取
 		addsAndKeyPairsBytes := bucket.Get([]byte(ADDANDPAIR))
+		if len(addsAndKeyPairsBytes) != 0 {
+			decoder := gob.NewDecoder(bytes.NewReader(addsAndKeyPairsBytes))
+			if decoder.Decode(&address) != nil {
+				//旧版本的钱包使用gob直接编码ecdsa私钥，按照旧格式解码后再以新格式保存
+				address, err = decodeLegacyKeyPairs(addsAndKeyPairsBytes)
+				if err != nil {
+					return errors.New("无法读取钱包中的秘钥对：" + err.Error())
+				}
+				isLegacy = true
+			}
+		}
 
-		if len(addsAndKeyPairsBytes) == 0 {
-			return nil
+		//读取MuSig聚合地址的参与方公钥
+		muSigKeysBytes := bucket.Get([]byte(MUSIGKEYS))
+		if len(muSigKeysBytes) != 0 {
+			_, err = utils.Decodes(muSigKeysBytes, &muSigKeys)
+			if err != nil {
+				return err
+			}
 		}
 
-		gob.Register(elliptic.P256())
-		decoder := gob.NewDecoder(bytes.NewReader(addsAndKeyPairsBytes))
-		err = decoder.Decode(&address)
+		//读取P2SH地址的赎回脚本
+		redeemScriptsBytes := bucket.Get([]byte(REDEEMSCRIPTS))
+		if len(redeemScriptsBytes) != 0 {
+			_, err = utils.Decodes(redeemScriptsBytes, &redeemScripts)
+			if err != nil {
+				return err
+			}
+		}
+
+		//读取加密钱包的主密钥记录
+		masterKeyBytes := bucket.Get([]byte(MASTERKEY))
+		if len(masterKeyBytes) != 0 {
+			masterKey, err = DeserializeMasterKey(masterKeyBytes)
+			if err != nil {
+				return err
+			}
+		}
+
+		//读取HD钱包的种子记录
+		hdChainBytes := bucket.Get([]byte(HDCHAIN))
+		if len(hdChainBytes) != 0 {
+			hdChain, err = DeserializeHDChain(hdChainBytes)
+			if err != nil {
+				return err
+			}
+		}
+
+		//读取观察地址
+		watchOnlyBytes := bucket.Get([]byte(WATCHONLY))
+		if len(watchOnlyBytes) != 0 {
+			_, err = utils.Decodes(watchOnlyBytes, &watchOnly)
+			if err != nil {
+				return err
+			}
+		}
+
+		//读取地址簿
+		addressBookBytes := bucket.Get([]byte(ADDRESSBOOK))
+		if len(addressBookBytes) != 0 {
+			_, err = utils.Decodes(addressBookBytes, &addressBook)
+		}
 		return err
 	})
 	if err != nil {
@@ -113,9 +213,19 @@ func LoadAddrAndKeyPairsFromDB(engine *bolt.DB) (*Wallet, error) {
 	}
 
 	wallet := &Wallet{
+		Name: name,
 		Address: address,
+		MuSigKeys: muSigKeys,
+		RedeemScripts: redeemScripts,
+		MasterKey: masterKey,
+		HDChain: hdChain,
+		WatchOnly: watchOnly,
+		AddressBook: addressBook,
 		Engine:  engine,
 	}
+	if isLegacy {
+		wallet.SaveAddAndKeyPairs2DB()
+	}
 	return wallet, nil
 }
 
@@ -132,16 +242,16 @@ func (wallet *Wallet) GetKeyPairByAddress(address string) (*KeyPair) {
 func (wallet *Wallet) SetCoinbase(address string) error {
 	var err error
 	wallet.Engine.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(KEYSTORE))
-		if bucket != nil {
-			bucket, err = bucket.CreateBucket([]byte(KEYSTORE))
+		bucket := tx.Bucket(wallet.keystore())
+		if bucket == nil {
+			bucket, err = tx.CreateBucket(wallet.keystore())
 			if err != nil {
 				return err
 			}
-			//bucket已经存在，把用户设置的address持久化存储起来
-			bucket.Put([]byte(COINBASE), []byte(address))
 		}
-		return nil
+		//bucket已经存在，把用户设置的address持久化存储起来
+		err = bucket.Put([]byte(COINBASE), []byte(address))
+		return err
 	})
 	return err
 }
@@ -152,7 +262,7 @@ func (wallet *Wallet) SetCoinbase(address string) error {
 func (wallet *Wallet) GetCoinbase() string {
 	var coinbase []byte
 	wallet.Engine.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(KEYSTORE))
+		bucket := tx.Bucket(wallet.keystore())
 		if bucket == nil {
 			return nil
 		}
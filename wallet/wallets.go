@@ -0,0 +1,190 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/boltdb/bolt"
+	"sort"
+)
+
+const WALLETS = "wallets"   //存放钱包列表的桶名：钱包名称 -> 是否已加载
+const DEFAULTWALLET = ""    //默认钱包的名称，默认钱包始终处于加载状态
+const MAXWALLETNAMELEN = 32 //钱包名称的最大长度
+
+const (
+	WALLETUNLOADED byte = 0x00
+	WALLETLOADED   byte = 0x01
+)
+
+/**
+ *钱包所使用的keystore桶名，默认钱包沿用keystores桶，其他钱包各自使用独立的桶
+ */
+func KeystoreBucket(name string) string {
+	if name == DEFAULTWALLET {
+		return KEYSTORE
+	}
+	return KEYSTORE + "_" + name
+}
+
+/**
+ *当前钱包的keystore桶名
+ */
+func (wallet *Wallet) keystore() []byte {
+	return []byte(KeystoreBucket(wallet.Name))
+}
+
+/**
+ *检查钱包名称：只能由字母、数字、下划线和中划线组成，长度不超过MAXWALLETNAMELEN
+ */
+func CheckWalletName(name string) error {
+	if len(name) == 0 {
+		return errors.New("钱包名称不能为空")
+	}
+	if len(name) > MAXWALLETNAMELEN {
+		return errors.New("钱包名称过长")
+	}
+	for _, char := range name {
+		isLetter := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+		isDigit := char >= '0' && char <= '9'
+		if !isLetter && !isDigit && char != '_' && char != '-' {
+			return errors.New("钱包名称只能包含字母、数字、下划线和中划线")
+		}
+	}
+	return nil
+}
+
+/**
+ *创建一个新的钱包，新钱包创建后处于加载状态
+ */
+func CreateWallet(engine *bolt.DB, name string) (*Wallet, error) {
+	if err := CheckWalletName(name); err != nil {
+		return nil, err
+	}
+	err := engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(WALLETS))
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(name)) != nil || tx.Bucket([]byte(KeystoreBucket(name))) != nil {
+			return errors.New("钱包已经存在：" + name)
+		}
+		if _, err = tx.CreateBucket([]byte(KeystoreBucket(name))); err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), []byte{WALLETLOADED})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loadWalletFromDB(engine, name)
+}
+
+/**
+ *加载一个已经存在的钱包
+ */
+func LoadWallet(engine *bolt.DB, name string) (*Wallet, error) {
+	if name == DEFAULTWALLET {
+		return nil, errors.New("默认钱包始终处于加载状态")
+	}
+	exists, loaded, err := walletState(engine, name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("钱包不存在：" + name)
+	}
+	if loaded {
+		return nil, errors.New("钱包已经加载：" + name)
+	}
+	if err = setWalletState(engine, name, WALLETLOADED); err != nil {
+		return nil, err
+	}
+	return loadWalletFromDB(engine, name)
+}
+
+/**
+ *卸载一个已经加载的钱包
+ */
+func UnloadWallet(engine *bolt.DB, name string) error {
+	if name == DEFAULTWALLET {
+		return errors.New("默认钱包不能卸载")
+	}
+	if _, err := OpenWallet(engine, name); err != nil {
+		return err
+	}
+	return setWalletState(engine, name, WALLETUNLOADED)
+}
+
+/**
+ *打开一个已经加载的钱包，名称为空时打开默认钱包
+ */
+func OpenWallet(engine *bolt.DB, name string) (*Wallet, error) {
+	if name == DEFAULTWALLET {
+		return loadWalletFromDB(engine, name)
+	}
+	exists, loaded, err := walletState(engine, name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("钱包不存在：" + name)
+	}
+	if !loaded {
+		return nil, errors.New("钱包尚未加载，请先使用loadwallet命令加载：" + name)
+	}
+	return loadWalletFromDB(engine, name)
+}
+
+/**
+ *列出所有已经加载的钱包名称，默认钱包排在最前面，其余按名称排序
+ */
+func ListWallets(engine *bolt.DB) ([]string, error) {
+	names := make([]string, 0)
+	err := engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(WALLETS))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if len(v) == 1 && v[0] == WALLETLOADED {
+				names = append(names, string(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return append([]string{DEFAULTWALLET}, names...), nil
+}
+
+/**
+ *查询钱包是否存在以及是否已经加载
+ */
+func walletState(engine *bolt.DB, name string) (bool, bool, error) {
+	var exists, loaded bool
+	err := engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(WALLETS))
+		if bucket == nil {
+			return nil
+		}
+		state := bucket.Get([]byte(name))
+		exists = len(state) == 1
+		loaded = exists && state[0] == WALLETLOADED
+		return nil
+	})
+	return exists, loaded, err
+}
+
+/**
+ *更新钱包的加载状态
+ */
+func setWalletState(engine *bolt.DB, name string, state byte) error {
+	return engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(WALLETS))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), []byte{state})
+	})
+}
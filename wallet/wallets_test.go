@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"github.com/boltdb/bolt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+/**
+ *在临时目录中打开一个数据库，测试结束后关闭
+ */
+func newTestEngine(t *testing.T) *bolt.DB {
+	t.Helper()
+	engine, err := bolt.Open(filepath.Join(t.TempDir(), "wallet.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func TestCheckWalletName(t *testing.T) {
+	for _, name := range []string{"a", "hot_wallet", "cold-1", "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"} {
+		if err := CheckWalletName(name); err != nil {
+			t.Errorf("%s：%v", name, err)
+		}
+	}
+	for _, name := range []string{"", "a b", "钱包", "../x", "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456"} {
+		if err := CheckWalletName(name); err == nil {
+			t.Errorf("%s应当被拒绝", name)
+		}
+	}
+}
+
+/**
+ *创建、卸载、加载和列出钱包，每个钱包的密钥保存在独立的keystore桶中
+ */
+func TestWalletLifecycle(t *testing.T) {
+	engine := newTestEngine(t)
+	if names, err := ListWallets(engine); err != nil || !reflect.DeepEqual(names, []string{DEFAULTWALLET}) {
+		t.Fatalf("默认钱包应当始终被列出：%v %v", names, err)
+	}
+	hot, err := CreateWallet(engine, "hot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := hot.NewAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CreateWallet(engine, "cold"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CreateWallet(engine, "hot"); err == nil {
+		t.Fatal("重复创建钱包应当返回错误")
+	}
+	if names, _ := ListWallets(engine); !reflect.DeepEqual(names, []string{DEFAULTWALLET, "cold", "hot"}) {
+		t.Fatalf("已加载的钱包列表不正确：%v", names)
+	}
+
+	//其他钱包和默认钱包看不到该钱包的地址
+	opened, err := OpenWallet(engine, "hot")
+	if err != nil || opened.GetKeyPairByAddress(address) == nil {
+		t.Fatal("重新打开的钱包中缺少已生成的地址", err)
+	}
+	for _, name := range []string{"cold", DEFAULTWALLET} {
+		other, err := OpenWallet(engine, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other.GetKeyPairByAddress(address) != nil {
+			t.Fatalf("钱包%q中不应包含其他钱包的地址", name)
+		}
+	}
+
+	if err = UnloadWallet(engine, "hot"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenWallet(engine, "hot"); err == nil {
+		t.Fatal("卸载的钱包不应能被打开")
+	}
+	if err = UnloadWallet(engine, "hot"); err == nil {
+		t.Fatal("重复卸载钱包应当返回错误")
+	}
+	if names, _ := ListWallets(engine); !reflect.DeepEqual(names, []string{DEFAULTWALLET, "cold"}) {
+		t.Fatalf("卸载的钱包不应被列出：%v", names)
+	}
+	loaded, err := LoadWallet(engine, "hot")
+	if err != nil || loaded.GetKeyPairByAddress(address) == nil {
+		t.Fatal("重新加载的钱包中缺少已生成的地址", err)
+	}
+	if _, err = LoadWallet(engine, "hot"); err == nil {
+		t.Fatal("重复加载钱包应当返回错误")
+	}
+
+	if _, err = LoadWallet(engine, "missing"); err == nil {
+		t.Fatal("加载不存在的钱包应当返回错误")
+	}
+	if _, err = OpenWallet(engine, "missing"); err == nil {
+		t.Fatal("打开不存在的钱包应当返回错误")
+	}
+	if err = UnloadWallet(engine, DEFAULTWALLET); err == nil {
+		t.Fatal("默认钱包不应能被卸载")
+	}
+	if _, err = LoadWallet(engine, DEFAULTWALLET); err == nil {
+		t.Fatal("默认钱包始终处于加载状态")
+	}
+}
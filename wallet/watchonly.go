@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"errors"
+	"github.com/boltdb/bolt"
+	"sort"
+)
+
+const WATCHONLY = "watch_only" //观察地址的键名
+
+/**
+ *导入观察地址：钱包只记录地址，不持有私钥，可以查询余额但无法花费
+ */
+func (wallet *Wallet) ImportAddress(address string) error {
+	if !wallet.CheckAddress(address) {
+		return errors.New("地址不符合规范，请检查后重试")
+	}
+	if _, ok := wallet.Address[address]; ok {
+		return errors.New("钱包中已经持有该地址的私钥")
+	}
+	if wallet.WatchOnly[address] {
+		return errors.New("钱包中已经存在该观察地址")
+	}
+	wallet.WatchOnly[address] = true
+	if err := wallet.saveWatchOnly(); err != nil {
+		return err
+	}
+	return wallet.addAddressMeta(address, PURPOSERECEIVE)
+}
+
+/**
+ *判断地址是否为观察地址：导入的观察地址，以及钱包只持有部分参与方私钥、无法独立花费的多重签名、P2SH和MuSig地址
+ */
+func (wallet *Wallet) IsWatchOnly(address string) bool {
+	if wallet.WatchOnly[address] {
+		return true
+	}
+	if wallet.IsMine(address) {
+		return false
+	}
+	if _, err := wallet.GetSignersByAddress(address); err == nil {
+		return true
+	}
+	_, ok := wallet.MuSigKeys[address]
+	return ok
+}
+
+/**
+ *判断钱包是否可以独立花费地址的资金：持有普通地址的私钥，或者持有多重签名、P2SH地址所需的M个参与方私钥，
+ *或者持有MuSig地址所有参与方的私钥
+ */
+func (wallet *Wallet) IsMine(address string) bool {
+	signers, err := wallet.GetSignersByAddress(address)
+	return err == nil && len(signers) >= wallet.requiredSignatures(address)
+}
+
+/**
+ *获取所有的观察地址，按地址排序
+ */
+func (wallet *Wallet) GetWatchOnlyAddresses() []string {
+	addresses := make([]string, 0, len(wallet.WatchOnly))
+	for address := range wallet.WatchOnly {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+/**
+ *把观察地址保存到keystore桶中
+ */
+func (wallet *Wallet) saveWatchOnly() error {
+	watchOnlyBytes, err := utils.Encoder(wallet.WatchOnly)
+	if err != nil {
+		return err
+	}
+	return wallet.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(wallet.keystore())
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(WATCHONLY), watchOnlyBytes)
+	})
+}
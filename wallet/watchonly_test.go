@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"testing"
+)
+
+/**
+ *钱包只有在能够独立完成花费所需的全部签名时才认为地址属于自己，只持有部分私钥的地址按观察地址处理
+ */
+func TestIsMineRequiresAllSignatures(t *testing.T) {
+	wallet := newTestWallet(t, "mine")
+	address, err := wallet.NewAddressWithCurve(Secp256k1Curve{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAddress, err := wallet.NewAddressWithCurve(Secp256k1Curve{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := wallet.GetKeyPairByAddress(address).Pub
+	otherLocal := wallet.GetKeyPairByAddress(otherAddress).Pub
+	external := newTestKeyPair(t, Secp256k1Curve{}).Pub
+
+	cases := []struct {
+		name      string
+		required  int
+		pubks     [][]byte
+		mine      bool
+		watchOnly bool
+	}{
+		{"1-of-2持有一个私钥", 1, [][]byte{local, external}, true, false},
+		{"2-of-2持有一个私钥", 2, [][]byte{local, external}, false, true},
+		{"2-of-3持有两个私钥", 2, [][]byte{local, external, otherLocal}, true, false},
+		{"2-of-2不持有私钥", 2, [][]byte{external, newTestKeyPair(t, Secp256k1Curve{}).Pub}, false, false},
+	}
+	for _, c := range cases {
+		multiSigAddress, err := wallet.NewMultiSigAddress(c.required, c.pubks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wallet.IsMine(multiSigAddress) != c.mine || wallet.IsWatchOnly(multiSigAddress) != c.watchOnly {
+			t.Errorf("%s：IsMine=%v IsWatchOnly=%v", c.name, wallet.IsMine(multiSigAddress), wallet.IsWatchOnly(multiSigAddress))
+		}
+	}
+
+	//只持有部分参与方私钥的MuSig地址按观察地址处理
+	schnorrAddress, err := wallet.NewAddressWithCurve(SchnorrCurve{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	muSigAddress, err := wallet.NewMuSigAddress([]string{schnorrAddress}, [][]byte{newTestKeyPair(t, SchnorrCurve{}).Pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.IsMine(muSigAddress) || !wallet.IsWatchOnly(muSigAddress) {
+		t.Error("只持有部分私钥的MuSig地址应当是观察地址")
+	}
+	if !wallet.IsMine(address) || wallet.IsWatchOnly(address) {
+		t.Error("普通地址应当属于钱包")
+	}
+}
@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"errors"
+)
+
+const WIFVERSION = 0x80    //WIF私钥的网络前缀
+const WIFCOMPRESSED = 0x01 //公钥使用压缩格式的标志
+const WIFKEYLEN = 32       //私钥标量的字节数
+
+/**
+ *把秘钥对的私钥编码为WIF格式：base58(0x80 + 32字节私钥 + 0x01 + 校验位)
+ *不携带密钥类型的标准格式表示secp256k1私钥，其他曲线在压缩标志之后追加一个字节的密钥类型
+ */
+func EncodeWIF(keyPair *KeyPair) (string, error) {
+	if keyPair.Priv == nil {
+		return "", errors.New("钱包已锁定，请通过XFWALLETPASSPHRASE环境变量或-stdinpassphrase参数提供钱包密码")
+	}
+	payload := []byte{WIFVERSION}
+	payload = append(payload, keyPair.Priv.D.FillBytes(make([]byte, WIFKEYLEN))...)
+	payload = append(payload, WIFCOMPRESSED)
+	if keyPair.KeyType != KEYTYPE_SECP256K1 {
+		payload = append(payload, keyPair.KeyType)
+	}
+	check := utils.Hash256(utils.Hash256(payload))[:4]
+	return utils.Encode(append(payload, check...)), nil
+}
+
+/**
+ *解析WIF格式的私钥，检查网络前缀和校验位，并根据密钥类型恢复秘钥对，没有密钥类型时按照secp256k1私钥解析
+ */
+func DecodeWIF(wif string) (*KeyPair, error) {
+	data := utils.Decode(wif)
+	if len(data) < 4 {
+		return nil, errors.New("WIF私钥格式不正确")
+	}
+	payload, check := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(utils.Hash256(utils.Hash256(payload))[:4], check) {
+		return nil, errors.New("WIF私钥的校验位不正确")
+	}
+	if len(payload) < 2+WIFKEYLEN || payload[0] != WIFVERSION || payload[1+WIFKEYLEN] != WIFCOMPRESSED {
+		return nil, errors.New("WIF私钥格式不正确")
+	}
+	var keyType byte = KEYTYPE_SECP256K1
+	switch len(payload) {
+	case 2 + WIFKEYLEN:
+	case 3 + WIFKEYLEN:
+		keyType = payload[2+WIFKEYLEN]
+	default:
+		return nil, errors.New("WIF私钥格式不正确")
+	}
+	curve, err := GetCurve(keyType)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyPairFromPrivKey(curve, payload[1:1+WIFKEYLEN])
+}
+
+/**
+ *导入WIF格式的私钥，返回私钥对应的地址，该地址之前是观察地址时转为可花费的地址
+ */
+func (wallet *Wallet) ImportPrivKey(wif string) (string, error) {
+	if err := wallet.CheckUnlocked(); err != nil {
+		return "", err
+	}
+	keyPair, err := DecodeWIF(wif)
+	if err != nil {
+		return "", err
+	}
+	address := wallet.GetAddressByPubk(keyPair.Pub)
+	if _, ok := wallet.Address[address]; ok {
+		return "", errors.New("钱包中已经存在该私钥")
+	}
+	if err = wallet.AddKeyPair(address, keyPair); err != nil {
+		return "", err
+	}
+	if wallet.WatchOnly[address] {
+		delete(wallet.WatchOnly, address)
+		if err = wallet.saveWatchOnly(); err != nil {
+			return "", err
+		}
+	}
+	return address, nil
+}
@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+/**
+ *不携带密钥类型的标准WIF格式按照secp256k1私钥编码和解析，与比特币的压缩私钥一致
+ */
+func TestWIFVector(t *testing.T) {
+	d, _ := hex.DecodeString("0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d")
+	keyPair, err := NewKeyPairFromPrivKey(Secp256k1Curve{}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wif = "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617"
+	encoded, err := EncodeWIF(keyPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded != wif {
+		t.Fatalf("WIF编码结果为%s，应为%s", encoded, wif)
+	}
+	decoded, err := DecodeWIF(wif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := "02d0de0aaeaefad02b8bdc8a01a1b8b11c696bd3d66a2c5f10780d95b7df42645c"
+	if decoded.KeyType != KEYTYPE_SECP256K1 || hex.EncodeToString(decoded.Pub[1:]) != pub {
+		t.Fatalf("WIF解析得到的公钥不正确：%x", decoded.Pub)
+	}
+}
+
+func TestWIFRoundTrip(t *testing.T) {
+	for _, curve := range []Curve{P256Curve{}, Secp256k1Curve{}, SchnorrCurve{}} {
+		keyPair, err := NewKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wif, err := EncodeWIF(keyPair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeWIF(wif)
+		if err != nil {
+			t.Fatal(curve.Name(), err)
+		}
+		if decoded.KeyType != keyPair.KeyType || decoded.Priv.D.Cmp(keyPair.Priv.D) != 0 || string(decoded.Pub) != string(keyPair.Pub) {
+			t.Fatalf("%s私钥的WIF编码前后不一致", curve.Name())
+		}
+		//修改最后一个字符破坏校验位
+		last := "1"
+		if strings.HasSuffix(wif, last) {
+			last = "2"
+		}
+		if _, err = DecodeWIF(wif[:len(wif)-1] + last); err == nil {
+			t.Fatalf("%s私钥校验位错误的WIF应当被拒绝", curve.Name())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package wallettx
+
+import (
+	"XianfengChain04/utils"
+	"bytes"
+	"github.com/boltdb/bolt"
+	"sort"
+)
+
+const WALLETTXS = "wallet_txs"   //存放钱包交易的桶名：txid -> 钱包交易
+const SYNCHEIGHT = "sync_height" //钱包交易记录已经同步到的区块高度的键名
+
+/**
+ *钱包交易记录，随着区块被添加到区块链上记录与钱包地址相关的交易
+ */
+type TxStore struct {
+	Engine *bolt.DB //bolt.db对象
+	Bucket string   //存放钱包交易的桶名，每个钱包使用独立的桶
+}
+
+/**
+ *构建一个钱包交易记录结构体实例并返回，walletName为钱包名称，默认钱包为空
+ */
+func NewTxStore(db *bolt.DB, walletName string) TxStore {
+	bucket := WALLETTXS
+	if walletName != "" {
+		bucket = WALLETTXS + "_" + walletName
+	}
+	return TxStore{
+		Engine: db,
+		Bucket: bucket,
+	}
+}
+
+/**
+ *保存一个区块中与钱包相关的交易，并把同步高度更新为该区块的高度
+ */
+func (store *TxStore) AddBlockTxs(walletTxs []WalletTx, height int64) error {
+	return store.Engine.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(store.Bucket))
+		if err != nil {
+			return err
+		}
+		for _, walletTx := range walletTxs {
+			if err = bucket.Put(walletTx.TxHash[:], walletTx.Serialize()); err != nil {
+				return err
+			}
+		}
+		buff := new(bytes.Buffer)
+		utils.WriteInt64(buff, height)
+		return bucket.Put([]byte(SYNCHEIGHT), buff.Bytes())
+	})
+}
+
+/**
+ *查询钱包交易记录已经同步到的区块高度，还未同步过任何区块时返回-1
+ */
+func (store *TxStore) GetSyncHeight() (int64, error) {
+	height := int64(-1)
+	err := store.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(store.Bucket))
+		if bucket == nil {
+			return nil
+		}
+		heightBytes := bucket.Get([]byte(SYNCHEIGHT))
+		if len(heightBytes) == 0 {
+			return nil
+		}
+		var err error
+		height, err = utils.ReadInt64(bytes.NewReader(heightBytes))
+		return err
+	})
+	return height, err
+}
+
+/**
+ *根据交易哈希查询钱包交易，未找到时返回nil
+ */
+func (store *TxStore) GetTx(txId [32]byte) (*WalletTx, error) {
+	var walletTx *WalletTx
+	err := store.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(store.Bucket))
+		if bucket == nil {
+			return nil
+		}
+		txBytes := bucket.Get(txId[:])
+		if len(txBytes) == 0 {
+			return nil
+		}
+		found, err := DeserializeWalletTx(txBytes)
+		if err != nil {
+			return err
+		}
+		walletTx = &found
+		return nil
+	})
+	return walletTx, err
+}
+
+/**
+ *获取所有的钱包交易，按区块高度从低到高排列
+ */
+func (store *TxStore) GetTxs() ([]WalletTx, error) {
+	walletTxs := make([]WalletTx, 0)
+	err := store.Engine.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(store.Bucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			//同步高度与交易记录存放在同一个桶中，交易记录的键为32字节的交易哈希
+			if len(k) != 32 {
+				return nil
+			}
+			walletTx, err := DeserializeWalletTx(v)
+			if err != nil {
+				return err
+			}
+			walletTxs = append(walletTxs, walletTx)
+			return nil
+		})
+	})
+	SortWalletTxs(walletTxs)
+	return walletTxs, err
+}
+
+/**
+ *清空钱包交易记录，用于重新扫描区块链
+ */
+func (store *TxStore) Clear() error {
+	return store.Engine.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(store.Bucket)) == nil {
+			return nil
+		}
+		return tx.DeleteBucket([]byte(store.Bucket))
+	})
+}
+
+/**
+ *把钱包交易按区块高度从低到高排列，未确认的交易排在最后，同一高度按时间排列
+ */
+func SortWalletTxs(walletTxs []WalletTx) {
+	sort.SliceStable(walletTxs, func(i, j int) bool {
+		hi, hj := walletTxs[i].Height, walletTxs[j].Height
+		if (hi < 0) != (hj < 0) {
+			return hj < 0
+		}
+		if hi != hj {
+			return hi < hj
+		}
+		return walletTxs[i].Time < walletTxs[j].Time
+	})
+}
@@ -0,0 +1,246 @@
+package wallettx
+
+import (
+	"XianfengChain04/transaction"
+	"XianfengChain04/utils"
+	"XianfengChain04/wallet"
+	"bytes"
+	"errors"
+)
+
+const COINBASEMATURITY = 100 //coinbase交易的输出经过多少个区块确认后才计入已确认余额
+
+const (
+	CATEGORYRECEIVE  = "receive"  //收到其他地址的转账
+	CATEGORYSEND     = "send"     //向其他地址转账
+	CATEGORYGENERATE = "generate" //挖矿获得的coinbase奖励
+)
+
+/**
+ *钱包交易中的一条收支明细，找零输出不计入明细
+ */
+type TxDetail struct {
+	Address   string
+	Category  string
+	Amount    float64 //收入为正，支出为负
+	Vout      int     //对应的交易输出下标，无法确定支出去向时为-1
+	WatchOnly bool    //该明细是否属于观察地址
+}
+
+/**
+ *与钱包地址相关的一笔交易：记录钱包在交易中的支出、收入、找零和手续费
+ */
+type WalletTx struct {
+	TxHash   [32]byte
+	Height   int64 //交易所在区块的高度，未确认的交易为-1
+	Time     int64 //交易所在区块的时间戳，未确认的交易为进入交易池的时间
+	Coinbase bool
+	Debit    float64 //交易输入中属于钱包的金额
+	Credit   float64 //交易输出中属于钱包的金额，包含找零
+	Change   float64 //找零金额
+	Fee      float64 //交易的全部输入都属于钱包时为交易的手续费，否则为0
+	Details  []TxDetail
+}
+
+/**
+ *根据钱包的地址分析交易的收支情况，spent为交易所花费的utxo，与交易输入一一对应
+ *交易与钱包的地址无关时第二个返回值为false
+ *找零的判定：钱包支付的交易中，转给付款地址或HD钱包找零链地址的输出视为找零
+ *钱包支付的交易中，找零以外的每个输出都记为一条支出，转给钱包自己的输出同时再记为一条收入
+ */
+func NewWalletTx(w *wallet.Wallet, tx transaction.Transaction, spent []transaction.UTXO, height int64, time int64) (WalletTx, bool) {
+	walletTx := WalletTx{
+		TxHash:   tx.TxHash,
+		Height:   height,
+		Time:     time,
+		Coinbase: tx.IsCoinbase(),
+		Details:  make([]TxDetail, 0),
+	}
+	fromMe := len(spent) > 0
+	inputAddresses := make(map[string]bool)
+	var totalIn, totalOut float64
+	for _, utxo := range spent {
+		totalIn += utxo.Value
+		address := w.GetAddressByPubkHash(utxo.PubkHash)
+		if !isWalletAddress(w, address) {
+			fromMe = false
+			continue
+		}
+		walletTx.Debit += utxo.Value
+		inputAddresses[address] = true
+	}
+
+	for vout, output := range tx.Outputs {
+		totalOut += output.Value
+		//携带数据的输出不属于任何地址
+		if len(output.PubkHash) == 0 {
+			continue
+		}
+		address := w.GetAddressByPubkHash(output.PubkHash)
+		mine := isWalletAddress(w, address)
+		if mine && walletTx.Debit > 0 && (inputAddresses[address] || w.IsInternalAddress(address)) {
+			walletTx.Credit += output.Value
+			walletTx.Change += output.Value
+			continue
+		}
+		if fromMe {
+			walletTx.Details = append(walletTx.Details, TxDetail{Address: address, Category: CATEGORYSEND, Amount: -output.Value, Vout: vout})
+		}
+		if !mine {
+			continue
+		}
+		walletTx.Credit += output.Value
+		category := CATEGORYRECEIVE
+		if walletTx.Coinbase {
+			category = CATEGORYGENERATE
+		}
+		walletTx.Details = append(walletTx.Details, TxDetail{
+			Address:   address,
+			Category:  category,
+			Amount:    output.Value,
+			Vout:      vout,
+			WatchOnly: w.IsWatchOnly(address),
+		})
+	}
+	if walletTx.Debit == 0 && walletTx.Credit == 0 {
+		return walletTx, false
+	}
+	if fromMe {
+		walletTx.Fee = totalIn - totalOut
+	} else if walletTx.Debit > 0 {
+		//多方共同出资的交易无法确定钱包的资金去向，只记录钱包的净支出
+		walletTx.Details = append(walletTx.Details, TxDetail{Category: CATEGORYSEND, Amount: walletTx.Change - walletTx.Debit, Vout: -1})
+	}
+	return walletTx, true
+}
+
+/**
+ *钱包可以花费或者正在观察的地址
+ */
+func isWalletAddress(w *wallet.Wallet, address string) bool {
+	return w.IsMine(address) || w.IsWatchOnly(address)
+}
+
+/**
+ *交易对钱包余额的影响：收入减去支出
+ */
+func (walletTx WalletTx) Net() float64 {
+	return walletTx.Credit - walletTx.Debit
+}
+
+/**
+ *交易的确认数，tipHeight为当前最新区块的高度，未确认的交易为0
+ */
+func (walletTx WalletTx) Confirmations(tipHeight int64) int64 {
+	if walletTx.Height < 0 {
+		return 0
+	}
+	return tipHeight - walletTx.Height + 1
+}
+
+/**
+ *coinbase交易在达到COINBASEMATURITY个确认之前为未成熟状态
+ */
+func (walletTx WalletTx) IsImmature(tipHeight int64) bool {
+	return walletTx.Coinbase && walletTx.Confirmations(tipHeight) < COINBASEMATURITY
+}
+
+/**
+ *序列化钱包交易：交易哈希 + 高度 + 时间 + coinbase标志 + 支出 + 收入 + 找零 + 手续费 + 明细
+ */
+func (walletTx WalletTx) Serialize() []byte {
+	buff := new(bytes.Buffer)
+	buff.Write(walletTx.TxHash[:])
+	utils.WriteInt64(buff, walletTx.Height)
+	utils.WriteInt64(buff, walletTx.Time)
+	writeBool(buff, walletTx.Coinbase)
+	utils.WriteFloat64(buff, walletTx.Debit)
+	utils.WriteFloat64(buff, walletTx.Credit)
+	utils.WriteFloat64(buff, walletTx.Change)
+	utils.WriteFloat64(buff, walletTx.Fee)
+	utils.WriteUint32(buff, uint32(len(walletTx.Details)))
+	for _, detail := range walletTx.Details {
+		utils.WriteVarBytes(buff, []byte(detail.Address))
+		utils.WriteVarBytes(buff, []byte(detail.Category))
+		utils.WriteFloat64(buff, detail.Amount)
+		utils.WriteInt64(buff, int64(detail.Vout))
+		writeBool(buff, detail.WatchOnly)
+	}
+	return buff.Bytes()
+}
+
+/**
+ *反序列化钱包交易
+ */
+func DeserializeWalletTx(data []byte) (WalletTx, error) {
+	var walletTx WalletTx
+	var err error
+	reader := bytes.NewReader(data)
+	if walletTx.TxHash, err = utils.ReadHash(reader); err != nil {
+		return walletTx, err
+	}
+	if walletTx.Height, err = utils.ReadInt64(reader); err != nil {
+		return walletTx, err
+	}
+	if walletTx.Time, err = utils.ReadInt64(reader); err != nil {
+		return walletTx, err
+	}
+	if walletTx.Coinbase, err = readBool(reader); err != nil {
+		return walletTx, err
+	}
+	amounts := []*float64{&walletTx.Debit, &walletTx.Credit, &walletTx.Change, &walletTx.Fee}
+	for _, amount := range amounts {
+		if *amount, err = utils.ReadFloat64(reader); err != nil {
+			return walletTx, err
+		}
+	}
+	//每条明细至少包含两个长度前缀、金额、下标和观察标志
+	count, err := utils.ReadCount(reader, 25)
+	if err != nil {
+		return walletTx, err
+	}
+	walletTx.Details = make([]TxDetail, count)
+	for i := range walletTx.Details {
+		detail := &walletTx.Details[i]
+		address, err := utils.ReadVarBytes(reader)
+		if err != nil {
+			return walletTx, err
+		}
+		category, err := utils.ReadVarBytes(reader)
+		if err != nil {
+			return walletTx, err
+		}
+		detail.Address, detail.Category = string(address), string(category)
+		if detail.Amount, err = utils.ReadFloat64(reader); err != nil {
+			return walletTx, err
+		}
+		vout, err := utils.ReadInt64(reader)
+		if err != nil {
+			return walletTx, err
+		}
+		detail.Vout = int(vout)
+		if detail.WatchOnly, err = readBool(reader); err != nil {
+			return walletTx, err
+		}
+	}
+	if reader.Len() != 0 {
+		return walletTx, errors.New("钱包交易数据中存在多余的字节")
+	}
+	return walletTx, nil
+}
+
+func writeBool(buff *bytes.Buffer, value bool) {
+	if value {
+		buff.WriteByte(1)
+	} else {
+		buff.WriteByte(0)
+	}
+}
+
+func readBool(reader *bytes.Reader) (bool, error) {
+	value, err := reader.ReadByte()
+	if err != nil {
+		return false, errors.New("反序列化失败，数据长度不足")
+	}
+	return value == 1, nil
+}